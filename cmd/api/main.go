@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -15,9 +16,11 @@ import (
 	"github.com/untea/bottom_babruysk/internal/configuration"
 	"github.com/untea/bottom_babruysk/internal/logger"
 	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
 	"github.com/untea/bottom_babruysk/internal/web/handlers"
 	"github.com/untea/bottom_babruysk/internal/web/router"
 	"github.com/untea/bottom_babruysk/internal/web/server"
+	"github.com/untea/bottom_babruysk/internal/worker"
 )
 
 func main() {
@@ -43,7 +46,26 @@ func main() {
 
 	defer dbClient.Close()
 
-	container, err := application.BuildContainer(cfg, l, dbClient)
+	storageCfg := storage.Configuration{
+		Driver: cfg.StorageDriver,
+		Path:   cfg.StoragePath,
+		S3: storage.S3Configuration{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UsePathStyle:    cfg.S3UsePathStyle,
+			Timeout:         10 * time.Minute,
+		},
+	}
+
+	blobStore, err := storage.New(storageCfg)
+	if err != nil {
+		l.Fatal("failed to initialize blobStore", zap.Error(err))
+	}
+
+	container, err := application.BuildContainer(cfg, l, dbClient, blobStore)
 	if err != nil {
 		panic(err)
 	}
@@ -60,6 +82,42 @@ func main() {
 
 	srv := server.New(cfg, l, dependencies)
 
+	services := container.Services
+
+	// Каждый воркер берёт работу, брошенную дольше указанного срока, повторно.
+	pollers := []worker.Poller{
+		{Name: "uploads", Interval: 2 * time.Second, Process: func(ctx context.Context) (bool, error) {
+			return services.UploadsService.ProcessNextUpload(ctx, 15*time.Minute)
+		}},
+		{Name: "waveforms", Interval: 10 * time.Second, Process: func(ctx context.Context) (bool, error) {
+			return services.WaveformsService.ProcessNextWaveform(ctx, 30*time.Minute)
+		}},
+		{Name: "loudness", Interval: 10 * time.Second, Process: func(ctx context.Context) (bool, error) {
+			return services.LoudnessService.ProcessNextLoudness(ctx, 30*time.Minute)
+		}},
+		{Name: "transcodes", Interval: 10 * time.Second, Process: func(ctx context.Context) (bool, error) {
+			return services.TranscodesService.ProcessNextTranscode(ctx, time.Hour)
+		}},
+		{Name: "hls", Interval: 10 * time.Second, Process: func(ctx context.Context) (bool, error) {
+			return services.HLSService.ProcessNextHLSIndex(ctx, 30*time.Minute)
+		}},
+		{Name: "fingerprints", Interval: 10 * time.Second, Process: func(ctx context.Context) (bool, error) {
+			return services.FingerprintsService.ProcessNextFingerprint(ctx, 30*time.Minute)
+		}},
+		// Объект без ссылок удаляется через час после последнего обращения к нему.
+		{Name: "blobs", Interval: time.Minute, Process: func(ctx context.Context) (bool, error) {
+			return services.BlobsService.ProcessNextUnreferencedBlob(ctx, time.Hour)
+		}},
+	}
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+	for _, poller := range pollers {
+		workers.Go(func() { poller.Run(workersCtx, l) })
+	}
+
 	go func() {
 		err := srv.Start()
 		if err != nil && !errors.Is(err, http.ErrServerClosed) {
@@ -72,6 +130,8 @@ func main() {
 
 	<-stop
 
+	stopWorkers()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -79,4 +139,7 @@ func main() {
 	if err != nil {
 		l.Info("failed to stop HTTP server", zap.Error(err))
 	}
+
+	// Пул соединений закрывается отложенным вызовом, только когда воркеры закончат начатую работу.
+	workers.Wait()
 }
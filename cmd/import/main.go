@@ -0,0 +1,304 @@
+// Команда import переносит существующую музыкальную библиотеку в сервис: рекурсивно сканирует каталог, читает теги
+// файлов и создаёт треки, альбомы, исполнителей и жанры. Уже импортированные файлы (по SHA-256 содержимого)
+// пропускаются, поэтому команду можно безопасно запускать повторно. Образ диска в FLAC или WAV, размеченный cue
+// (файлом .cue рядом или встроенным CUESHEET), импортируется как альбом: по треку на каждый трек разметки.
+//
+//	import [-dry-run] [-workers N] [-owner UUID] [-visibility private|unlisted|public] DIR
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/untea/bottom_babruysk/internal/application"
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/configuration"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/logger"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/service"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type options struct {
+	dryRun     bool
+	workers    int
+	ownerID    *uuid.UUID
+	visibility domain.Visibility
+}
+
+type summary struct {
+	imported, skipped, failed int
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would be imported")
+	workers := flag.Int("workers", 0, "number of files parsed concurrently (default GOMAXPROCS)")
+	owner := flag.String("owner", "", "id of the user that will own imported tracks and albums")
+	visibility := flag.String("visibility", string(domain.VisibilityPrivate), "visibility of imported tracks")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] DIR\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	l, err := logger.New()
+	if err != nil {
+		panic(err)
+	}
+
+	opts := options{
+		dryRun:     *dryRun,
+		workers:    *workers,
+		visibility: domain.Visibility(*visibility),
+	}
+
+	if *owner != "" {
+		ownerID, err := uuid.Parse(*owner)
+		if err != nil {
+			l.Fatal("invalid owner id", zap.Error(err))
+		}
+
+		opts.ownerID = &ownerID
+	}
+
+	cfg, err := configuration.Load()
+	if err != nil {
+		l.Fatal("failed to load cfg", zap.Error(err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbCfg := postgres.Configuration{
+		ConnectionString: cfg.DatabaseConnectionURL,
+		Timeout:          30 * time.Second,
+	}
+
+	dbClient, err := postgres.New(ctx, dbCfg)
+	if err != nil {
+		l.Fatal("failed to initialize dbClient", zap.Error(err))
+	}
+
+	defer dbClient.Close()
+
+	storageCfg := storage.Configuration{
+		Driver: cfg.StorageDriver,
+		Path:   cfg.StoragePath,
+		S3: storage.S3Configuration{
+			Endpoint:        cfg.S3Endpoint,
+			Region:          cfg.S3Region,
+			Bucket:          cfg.S3Bucket,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			UsePathStyle:    cfg.S3UsePathStyle,
+			Timeout:         10 * time.Minute,
+		},
+	}
+
+	blobStore, err := storage.New(storageCfg)
+	if err != nil {
+		l.Fatal("failed to initialize blobStore", zap.Error(err))
+	}
+
+	container, err := application.BuildContainer(cfg, l, dbClient, blobStore)
+	if err != nil {
+		panic(err)
+	}
+
+	result, progress, err := run(ctx, container.Services.LibraryService, l, flag.Arg(0), opts)
+
+	imported := "imported"
+	if opts.dryRun {
+		imported = "to import"
+	}
+
+	fmt.Printf("scanned %d files (%d bytes): %d %s, %d skipped, %d failed\n",
+		progress.Done, progress.Bytes, result.imported, imported, result.skipped, result.failed+int(progress.Failed))
+
+	if err != nil {
+		l.Fatal("import interrupted", zap.Error(err))
+	}
+}
+
+// run сканирует root и импортирует найденные файлы по одному: параллельно идёт только разбор, запись в базу и
+// хранилище последовательна, поэтому поиск исполнителей и альбомов по имени не гоняется сам с собой.
+func run(ctx context.Context, library *service.LibraryService, l *zap.Logger, root string, opts options) (summary, audio.ScanProgress, error) {
+	var result summary
+
+	// seen отсекает одинаковые файлы внутри одного прогона, в том числе в режиме dry-run, когда в базу ничего не пишется.
+	seen := make(map[string]bool)
+
+	progress, err := audio.Scan(ctx, root, audio.ScanOptions{Workers: opts.workers}, func(scanned audio.ScanResult) error {
+		if scanned.Err != nil {
+			l.Warn("failed to parse file", zap.String("path", scanned.Path), zap.Error(scanned.Err))
+			return nil
+		}
+
+		status, details, err := importFile(ctx, library, scanned.Meta, opts, seen)
+
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			result.failed++
+			l.Warn("failed to import file", zap.String("path", scanned.Path), zap.Error(err))
+
+			return nil
+		case status == "skip":
+			result.skipped++
+		default:
+			result.imported++
+		}
+
+		fmt.Printf("[%d/%d] %-6s %s%s\n", scanned.Progress.Done, scanned.Progress.Found, status, scanned.Path, details)
+
+		return nil
+	})
+
+	return result, progress, err
+}
+
+// importFile импортирует один разобранный файл и возвращает, что с ним сделано: "import", "skip" или, в режиме
+// dry-run, "new" вместе с исполнителями, альбомом и названием, которые получит трек.
+func importFile(ctx context.Context, library *service.LibraryService, meta *audio.TrackFileMetadata, opts options, seen map[string]bool) (string, string, error) {
+	file, err := os.Open(meta.Path)
+	if err != nil {
+		return "", "", err
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", "", fmt.Errorf("hash file: %w", err)
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if seen[checksum] {
+		return "skip", "", nil
+	}
+
+	seen[checksum] = true
+
+	imported, err := library.IsImported(ctx, domain.FindTrackFileByChecksumRequest{Checksum: utils.Ptr(checksum)})
+	if err != nil {
+		return "", "", err
+	}
+
+	if imported {
+		return "skip", "", nil
+	}
+
+	cueSheet, err := findCueSheet(meta)
+	if err != nil {
+		return "", "", err
+	}
+
+	if cueSheet != nil {
+		return importCueSheet(ctx, library, meta, cueSheet, file, checksum, opts)
+	}
+
+	request := service.ImportTrackRequestFromMetadata(meta, checksum)
+	request.OwnerID = opts.ownerID
+	request.Visibility = utils.Ptr(opts.visibility)
+	request.Body = file
+
+	if opts.dryRun {
+		if err = request.Validate(); err != nil {
+			return "", "", err
+		}
+
+		details := fmt.Sprintf(" (%s — %s — %s)", strings.Join(request.Artists, ", "), utils.ValueOrZero(request.Album), *request.Title)
+
+		return "new", details, nil
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	_, err = library.ImportTrack(ctx, request)
+	if errors.Is(err, domain.ErrAlreadyImported) {
+		return "skip", "", nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return "import", "", nil
+}
+
+// findCueSheet возвращает разметку образа диска или nil, если файл — обычный трек. Разметка из одного трека
+// образом не считается: такой файл импортируется как есть.
+func findCueSheet(meta *audio.TrackFileMetadata) (*audio.CueSheet, error) {
+	if meta.Format != domain.FormatFLAC && meta.Format != domain.FormatWAV {
+		return nil, nil
+	}
+
+	cueSheet, err := audio.FindCueSheet(meta)
+	if err != nil {
+		return nil, fmt.Errorf("read cue sheet: %w", err)
+	}
+
+	if cueSheet == nil || len(cueSheet.Spans(meta.TotalSamples)) < 2 {
+		return nil, nil
+	}
+
+	return cueSheet, nil
+}
+
+// importCueSheet импортирует образ диска целиком как альбом.
+func importCueSheet(ctx context.Context, library *service.LibraryService, meta *audio.TrackFileMetadata, cueSheet *audio.CueSheet, file *os.File, checksum string, opts options) (string, string, error) {
+	request := service.ImportCueSheetRequestFromMetadata(meta, cueSheet, checksum)
+	request.OwnerID = opts.ownerID
+	request.Visibility = utils.Ptr(opts.visibility)
+	request.Body = file
+
+	if opts.dryRun {
+		if err := request.Validate(); err != nil {
+			return "", "", err
+		}
+
+		details := fmt.Sprintf(" (%s — %s — %d tracks)", utils.ValueOrZero(request.AlbumArtist), *request.Album, len(request.Tracks))
+
+		return "new", details, nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", "", err
+	}
+
+	response, err := library.ImportCueSheet(ctx, request)
+	if errors.Is(err, domain.ErrAlreadyImported) {
+		return "skip", "", nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return "import", fmt.Sprintf(" (%d tracks)", len(response.Tracks)), nil
+}
@@ -1,54 +1,97 @@
 package application
 
 import (
+	"fmt"
+
 	"go.uber.org/zap"
 
+	"github.com/untea/bottom_babruysk/internal/auth"
 	"github.com/untea/bottom_babruysk/internal/configuration"
+	"github.com/untea/bottom_babruysk/internal/domain"
 	"github.com/untea/bottom_babruysk/internal/repository"
 	"github.com/untea/bottom_babruysk/internal/repository/postgres"
 	"github.com/untea/bottom_babruysk/internal/service"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/internal/transcode"
 )
 
 type Services struct {
-	UsersServices     *service.UsersService
-	AlbumServices     *service.AlbumsService
-	TacksServices     *service.TracksService
-	PlaylistsService  *service.PlaylistsService
-	ArtistsService    *service.ArtistsService
-	TrackFilesService *service.TrackFilesService
+	UsersServices       *service.UsersService
+	AlbumServices       *service.AlbumsService
+	TacksServices       *service.TracksService
+	PlaylistsService    *service.PlaylistsService
+	ArtistsService      *service.ArtistsService
+	TrackFilesService   *service.TrackFilesService
+	UploadsService      *service.UploadsService
+	LibraryService      *service.LibraryService
+	WaveformsService    *service.WaveformsService
+	LoudnessService     *service.LoudnessService
+	TranscodesService   *service.TranscodesService
+	HLSService          *service.HLSService
+	FingerprintsService *service.FingerprintsService
+	BlobsService        *service.BlobsService
+	AuthService         *service.AuthService
 }
 
 type Repositories struct {
-	UsersRepository      service.Users
-	AlbumsRepository     service.Albums
-	TracksRepository     service.Tracks
-	PlaylistsRepository  service.Playlists
-	ArtistsRepository    service.Artists
-	TrackFilesRepository service.TrackFiles
+	UsersRepository        service.Users
+	AlbumsRepository       service.Albums
+	TracksRepository       service.Tracks
+	PlaylistsRepository    service.Playlists
+	ArtistsRepository      service.Artists
+	TrackFilesRepository   service.TrackFiles
+	UploadsRepository      service.Uploads
+	LibraryRepository      service.Library
+	WaveformsRepository    service.Waveforms
+	LoudnessRepository     service.Loudness
+	TranscodesRepository   service.Transcodes
+	HLSRepository          service.HLS
+	FingerprintsRepository service.Fingerprints
+	BlobsRepository        service.Blobs
+	SessionsRepository     service.Sessions
 }
 
 type Container struct {
 	Logger        *zap.Logger
 	Configuration *configuration.Configuration
+	BlobStore     storage.BlobStore
 	Repositories  Repositories
 	Services      Services
 }
 
-func BuildContainer(configuration *configuration.Configuration, logger *zap.Logger, dbClient *postgres.Client) (*Container, error) {
+func BuildContainer(configuration *configuration.Configuration, logger *zap.Logger, dbClient *postgres.Client, blobStore storage.BlobStore) (*Container, error) {
 	usersRepository := repository.NewUsersRepository(dbClient)
 	albumsRepository := repository.NewAlbumsRepository(dbClient)
 	tracksRepository := repository.NewTracksRepository(dbClient)
 	playlistsRepository := repository.NewPlaylistsRepository(dbClient)
 	artistsRepository := repository.NewArtistsRepository(dbClient)
 	trackFilesRepository := repository.NewTrackFilesRepository(dbClient)
+	uploadsRepository := repository.NewUploadsRepository(dbClient)
+	libraryRepository := repository.NewLibraryRepository(dbClient)
+	waveformsRepository := repository.NewWaveformsRepository(dbClient)
+	loudnessRepository := repository.NewLoudnessRepository(dbClient)
+	transcodesRepository := repository.NewTranscodesRepository(dbClient)
+	hlsRepository := repository.NewHLSRepository(dbClient)
+	fingerprintsRepository := repository.NewFingerprintsRepository(dbClient)
+	blobsRepository := repository.NewBlobsRepository(dbClient)
+	sessionsRepository := repository.NewSessionsRepository(dbClient)
 
 	repositories := Repositories{
-		UsersRepository:      usersRepository,
-		AlbumsRepository:     albumsRepository,
-		TracksRepository:     tracksRepository,
-		PlaylistsRepository:  playlistsRepository,
-		ArtistsRepository:    artistsRepository,
-		TrackFilesRepository: trackFilesRepository,
+		UsersRepository:        usersRepository,
+		AlbumsRepository:       albumsRepository,
+		TracksRepository:       tracksRepository,
+		PlaylistsRepository:    playlistsRepository,
+		ArtistsRepository:      artistsRepository,
+		TrackFilesRepository:   trackFilesRepository,
+		UploadsRepository:      uploadsRepository,
+		LibraryRepository:      libraryRepository,
+		WaveformsRepository:    waveformsRepository,
+		LoudnessRepository:     loudnessRepository,
+		TranscodesRepository:   transcodesRepository,
+		HLSRepository:          hlsRepository,
+		FingerprintsRepository: fingerprintsRepository,
+		BlobsRepository:        blobsRepository,
+		SessionsRepository:     sessionsRepository,
 	}
 
 	usersServices := service.NewUsersService(usersRepository)
@@ -56,23 +99,76 @@ func BuildContainer(configuration *configuration.Configuration, logger *zap.Logg
 	tracksServices := service.NewTracksService(tracksRepository)
 	playlistsServices := service.NewPlaylistsService(playlistsRepository)
 	artistsServices := service.NewArtistsService(artistsRepository)
-	trackFilesService := service.NewTrackFilesService(trackFilesRepository)
+	trackFilesService := service.NewTrackFilesService(trackFilesRepository, tracksRepository, blobsRepository, blobStore)
+	uploadsService := service.NewUploadsService(uploadsRepository, tracksRepository, trackFilesRepository, blobsRepository, blobStore)
+	libraryService := service.NewLibraryService(libraryRepository, blobsRepository, blobStore)
+	waveformsService := service.NewWaveformsService(waveformsRepository, tracksRepository, blobStore)
+	loudnessService := service.NewLoudnessService(loudnessRepository, blobStore)
+
+	transcoder, renditions, err := buildTranscoder(configuration, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	transcodesService := service.NewTranscodesService(transcodesRepository, blobsRepository, blobStore, transcoder, renditions)
+	hlsService := service.NewHLSService(hlsRepository, tracksRepository, blobStore)
+	fingerprintsService := service.NewFingerprintsService(fingerprintsRepository, tracksRepository, blobStore)
+	blobsService := service.NewBlobsService(blobsRepository, blobStore)
+	tokenSigner := auth.NewTokenSigner([]byte(configuration.AuthTokenSecret), configuration.AuthAccessTokenTTL)
+	authService := service.NewAuthService(usersRepository, sessionsRepository, tokenSigner, configuration.AuthRefreshTokenTTL)
 
 	services := Services{
-		UsersServices:     usersServices,
-		AlbumServices:     albumsServices,
-		TacksServices:     tracksServices,
-		PlaylistsService:  playlistsServices,
-		ArtistsService:    artistsServices,
-		TrackFilesService: trackFilesService,
+		UsersServices:       usersServices,
+		AlbumServices:       albumsServices,
+		TacksServices:       tracksServices,
+		PlaylistsService:    playlistsServices,
+		ArtistsService:      artistsServices,
+		TrackFilesService:   trackFilesService,
+		UploadsService:      uploadsService,
+		LibraryService:      libraryService,
+		WaveformsService:    waveformsService,
+		LoudnessService:     loudnessService,
+		TranscodesService:   transcodesService,
+		HLSService:          hlsService,
+		FingerprintsService: fingerprintsService,
+		BlobsService:        blobsService,
+		AuthService:         authService,
 	}
 
 	container := &Container{
 		Logger:        logger,
 		Configuration: configuration,
+		BlobStore:     blobStore,
 		Repositories:  repositories,
 		Services:      services,
 	}
 
 	return container, nil
 }
+
+// buildTranscoder собирает цепочку транскодеров и оставляет из настроенных копий только те, которые она умеет
+// получать: без внешнего кодировщика lossy-копии пропускаются с предупреждением.
+func buildTranscoder(configuration *configuration.Configuration, logger *zap.Logger) (transcode.Transcoder, []domain.Rendition, error) {
+	configured, err := transcode.ParseRenditions(configuration.TranscodeRenditions)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse TRANSCODE_RENDITIONS: %w", err)
+	}
+
+	chain := transcode.Chain{transcode.NewPCMTranscoder()}
+	if configuration.TranscodeEncoder != "" {
+		chain = append(chain, transcode.NewExternalTranscoder(configuration.TranscodeEncoder))
+	}
+
+	renditions := make([]domain.Rendition, 0, len(configured))
+
+	for _, rendition := range configured {
+		if !chain.Supports(rendition) {
+			logger.Warn("rendition is not supported without external encoder", zap.Stringer("rendition", rendition))
+			continue
+		}
+
+		renditions = append(renditions, rendition)
+	}
+
+	return chain, renditions, nil
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+var (
+	errAiffInvalid     = errors.New("invalid aiff file")
+	errAiffUnsupported = errors.New("unsupported aiff compression")
+
+	// aiffPCMCompressions типы сжатия AIFC, которые на самом деле несжатый PCM (целый в любом порядке байт или float).
+	aiffPCMCompressions = map[string]bool{
+		"NONE": true,
+		"sowt": true,
+		"twos": true,
+		"raw ": true,
+		"in24": true,
+		"in32": true,
+		"fl32": true,
+		"FL32": true,
+		"fl64": true,
+		"FL64": true,
+	}
+
+	// aiffTextTags текстовые chunk AIFF и соответствующие ключи TrackFileMetadata.Tags.
+	aiffTextTags = map[string]string{
+		"NAME": "title",
+		"AUTH": "artist",
+		"(c) ": "copyright",
+		"ANNO": "comment",
+	}
+)
+
+// ParseAiffFile разбирает AIFF и AIFC с несжатым PCM. В отличие от RIFF, все числа в AIFF big-endian, а частота
+// дискретизации записана 80-битным extended float.
+func ParseAiffFile(filePath string) (*TrackFileMetadata, error) {
+	return parseFile(filePath, "aiff", parseAiff)
+}
+
+func parseAiff(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	var header [12]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("reading aiff header: %w", err)
+	}
+
+	form := string(header[8:12])
+	if string(header[:4]) != "FORM" || (form != "AIFF" && form != "AIFC") {
+		return nil, fmt.Errorf("%w: signature %q", errAiffInvalid, header[:])
+	}
+
+	meta := &TrackFileMetadata{Format: domain.FormatAIFF, Codec: domain.CodecPCM, Mime: MimeAIFF, Size: size}
+
+	var hasCommon bool
+
+	for offset := int64(12); offset+8 <= size; {
+		var chunk [8]byte
+		if _, err := r.ReadAt(chunk[:], offset); err != nil {
+			return nil, fmt.Errorf("reading aiff chunk header: %w", err)
+		}
+
+		id := string(chunk[:4])
+		chunkSize := int64(binary.BigEndian.Uint32(chunk[4:8]))
+		body := offset + 8
+
+		switch {
+		case id == "COMM":
+			b, err := readAtMost(r, make([]byte, min(chunkSize, 64)), body)
+			if err != nil {
+				return nil, fmt.Errorf("reading aiff COMM chunk: %w", err)
+			}
+
+			if err = parseAiffCommon(b, form == "AIFC", meta); err != nil {
+				return nil, err
+			}
+
+			hasCommon = true
+		case aiffTextTags[id] != "":
+			b, err := readAtMost(r, make([]byte, min(chunkSize, size-body, textChunkLimit)), body)
+			if err != nil {
+				return nil, fmt.Errorf("reading aiff %s chunk: %w", id, err)
+			}
+
+			if value := strings.TrimSpace(string(bytes.TrimRight(b, "\x00"))); value != "" {
+				setTag(meta, aiffTextTags[id], value)
+			}
+		}
+
+		offset = body + chunkSize + chunkSize&1
+	}
+
+	if !hasCommon {
+		return nil, fmt.Errorf("%w: missing COMM chunk", errAiffInvalid)
+	}
+
+	meta.Duration = time.Duration(float64(meta.TotalSamples) / float64(meta.SampleRate) * float64(time.Second))
+	meta.Bitrate = meta.SampleRate * meta.BitsPerSample * meta.Channels / 1000
+
+	return meta, nil
+}
+
+// parseAiffCommon разбирает COMM: каналы (2), число сэмплов на канал (4), разрядность (2), частота (10), а в AIFC
+// ещё тип сжатия (4).
+func parseAiffCommon(b []byte, aifc bool, meta *TrackFileMetadata) error {
+	if len(b) < 18 || (aifc && len(b) < 22) {
+		return fmt.Errorf("%w: COMM chunk too short", errAiffInvalid)
+	}
+
+	meta.Channels = int(binary.BigEndian.Uint16(b[0:2]))
+	meta.TotalSamples = uint64(binary.BigEndian.Uint32(b[2:6]))
+	meta.BitsPerSample = int(binary.BigEndian.Uint16(b[6:8]))
+	meta.SampleRate = int(math.Round(extendedToFloat64(b[8:18])))
+
+	if aifc {
+		if compression := string(b[18:22]); !aiffPCMCompressions[compression] {
+			return fmt.Errorf("%w: %q", errAiffUnsupported, compression)
+		}
+	}
+
+	if meta.Channels == 0 || meta.SampleRate <= 0 {
+		return fmt.Errorf("%w: zero channels or sample rate", errAiffInvalid)
+	}
+
+	return nil
+}
+
+// extendedToFloat64 переводит 80-битный IEEE 754 extended (знак, 15 бит порядка, 64 бита мантиссы с явной
+// единицей) в float64.
+func extendedToFloat64(b []byte) float64 {
+	exponent := int(binary.BigEndian.Uint16(b[0:2]) & 0x7FFF)
+	mantissa := binary.BigEndian.Uint64(b[2:10])
+
+	if exponent == 0 && mantissa == 0 {
+		return 0
+	}
+
+	value := math.Ldexp(float64(mantissa), exponent-16383-63)
+	if b[0]&0x80 != 0 {
+		value = -value
+	}
+
+	return value
+}
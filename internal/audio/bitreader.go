@@ -0,0 +1,158 @@
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// bitReader читает поток по битам, старшим битом вперёд, и попутно считает CRC-8 и CRC-16 FLAC по всем
+// прочитанным байтам. В кэше никогда не остаётся целого непрочитанного байта, поэтому CRC всегда покрывает ровно то,
+// что уже разобрано.
+type bitReader struct {
+	r     *bufio.Reader
+	cache uint64
+	n     uint
+
+	crc8  uint8
+	crc16 uint16
+}
+
+func newBitReader(r io.Reader) *bitReader {
+	return &bitReader{r: bufio.NewReaderSize(r, 64<<10)}
+}
+
+func (br *bitReader) readByte() (byte, error) {
+	b, err := br.r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	br.crc8 = crc8Table[br.crc8^b]
+	br.crc16 = br.crc16<<8 ^ crc16Table[byte(br.crc16>>8)^b]
+
+	return b, nil
+}
+
+// readBits читает n бит (n <= 56) как беззнаковое число.
+func (br *bitReader) readBits(n uint) (uint64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+
+	for br.n < n {
+		b, err := br.readByte()
+		if err != nil {
+			return 0, unexpectedEOF(err)
+		}
+
+		br.cache = br.cache<<8 | uint64(b)
+		br.n += 8
+	}
+
+	br.n -= n
+	v := br.cache >> br.n & (1<<n - 1)
+	br.cache &= 1<<br.n - 1
+
+	return v, nil
+}
+
+// readSigned читает n бит как число в дополнительном коде.
+func (br *bitReader) readSigned(n uint) (int64, error) {
+	v, err := br.readBits(n)
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	return int64(v<<(64-n)) >> (64 - n), nil
+}
+
+// readUnary считает нулевые биты до первой единицы (единица тоже съедается).
+func (br *bitReader) readUnary() (uint64, error) {
+	var q uint64
+
+	for {
+		if br.n == 0 {
+			b, err := br.readByte()
+			if err != nil {
+				return 0, unexpectedEOF(err)
+			}
+
+			br.cache, br.n = uint64(b), 8
+		}
+
+		if br.cache == 0 {
+			q += uint64(br.n)
+			br.n = 0
+
+			continue
+		}
+
+		zeros := br.n - uint(bits.Len64(br.cache))
+		q += uint64(zeros)
+		br.n -= zeros + 1
+		br.cache &= 1<<br.n - 1
+
+		return q, nil
+	}
+}
+
+// align отбрасывает биты до границы байта.
+func (br *bitReader) align() {
+	br.cache, br.n = 0, 0
+}
+
+// resetCRC начинает подсчёт CRC заново; вызывается на границе байта в начале фрейма.
+func (br *bitReader) resetCRC() {
+	br.crc8, br.crc16 = 0, 0
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+var (
+	// crc8Table CRC-8 заголовка фрейма FLAC, полином x^8 + x^2 + x + 1.
+	crc8Table = makeCRC8Table(0x07)
+	// crc16Table CRC-16 фрейма FLAC, полином x^16 + x^15 + x^2 + 1.
+	crc16Table = makeCRC16Table(0x8005)
+)
+
+func makeCRC8Table(poly uint8) (table [256]uint8) {
+	for i := range table {
+		crc := uint8(i)
+		for range 8 {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}
+
+func makeCRC16Table(poly uint16) (table [256]uint16) {
+	for i := range table {
+		crc := uint16(i) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ poly
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}
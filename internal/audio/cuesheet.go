@@ -0,0 +1,412 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// cueFramesPerSecond в cue-файле время записывается как mm:ss:ff, где ff — кадры CD по 1/75 секунды.
+const cueFramesPerSecond = 75
+
+var errCueSheetInvalid = errors.New("invalid cue sheet")
+
+// CueSheet разметка образа диска на треки. Все смещения в сэмплах от начала аудиопотока.
+type CueSheet struct {
+	// CatalogNumber номер каталога носителя (для CD это 13 цифр UPC/EAN), пустой, если не задан.
+	CatalogNumber string
+	// LeadInSamples число сэмплов lead-in CD. Для остальных носителей 0.
+	LeadInSamples uint64
+	// IsCD признак того, что разметка соответствует Red Book CD.
+	IsCD bool
+	// Tracks треки в порядке следования, без завершающего lead-out.
+	Tracks []CueTrack
+	// LeadOut смещение lead-out, то есть конец последнего трека. В текстовом cue-файле его нет: 0, и концом
+	// последнего трека считается конец файла.
+	LeadOut uint64
+
+	// Поля ниже есть только в текстовом cue-файле (в том числе встроенном в тег CUESHEET); блок CUESHEET FLAC их
+	// не хранит.
+
+	// File имя файла образа из команды FILE.
+	File      string
+	Title     string
+	Performer string
+	// Genre и Date из комментариев REM GENRE и REM DATE, которые пишут EAC и foobar2000.
+	Genre string
+	Date  string
+	// DiscNumber номер диска из REM DISCNUMBER; 0, если не задан.
+	DiscNumber int
+}
+
+// CueTrack один трек разметки.
+type CueTrack struct {
+	// Number номер трека (1-99 для CD).
+	Number int
+	// Offset начало трека в сэмплах.
+	Offset uint64
+	// ISRC международный код записи, пустой, если не задан.
+	ISRC string
+	// Audio false для дорожек с данными.
+	Audio bool
+	// PreEmphasis признак записи с предыскажением.
+	PreEmphasis bool
+	// Indexes точки индекса; смещения относительно Offset трека. Индекс 0 — пауза перед треком, 1 — его начало.
+	Indexes []CueIndex
+	// Title и Performer трека из текстового cue-файла.
+	Title     string
+	Performer string
+}
+
+// CueIndex точка индекса внутри трека.
+type CueIndex struct {
+	Number int
+	Offset uint64
+}
+
+// CueSpan звучащая часть трека разметки: сэмплы [Start, End).
+type CueSpan struct {
+	Track CueTrack
+	Start uint64
+	End   uint64
+}
+
+// Spans делит образ на треки. Трек начинается с точки INDEX 01 (без неё — с первой точки) и длится до INDEX 01
+// следующего, то есть пауза перед треком достаётся предыдущему, как при разрезке с "gaps appended to previous".
+// Звук до INDEX 01 первого трека (скрытый трек) не попадает никуда. Последний трек заканчивается на lead-out, а
+// если его нет — на totalSamples. Дорожки с данными пропускаются.
+func (c *CueSheet) Spans(totalSamples uint64) []CueSpan {
+	end := totalSamples
+	if c.LeadOut > 0 && (totalSamples == 0 || c.LeadOut < totalSamples) {
+		end = c.LeadOut
+	}
+
+	starts := make([]uint64, len(c.Tracks))
+	for i, track := range c.Tracks {
+		starts[i] = track.Offset
+
+		for _, index := range track.Indexes {
+			if index.Number == 1 {
+				starts[i] = track.Offset + index.Offset
+				break
+			}
+		}
+	}
+
+	var spans []CueSpan
+
+	for i, track := range c.Tracks {
+		if !track.Audio {
+			continue
+		}
+
+		span := CueSpan{Track: track, Start: starts[i], End: end}
+		if i+1 < len(c.Tracks) {
+			span.End = min(starts[i+1], end)
+		}
+
+		if span.End > span.Start {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// ParseCueSheet разбирает текстовый cue-файл образа. Время mm:ss:ff переводится в сэмплы по sampleRate образа.
+// Поддерживается только разметка одного файла: несколько команд FILE — ошибка. Кодировка — UTF-8 (с BOM или без),
+// а если текст в UTF-8 не укладывается, он читается как Windows-1251: в ней EAC на русской Windows сохраняет cue.
+func ParseCueSheet(r io.Reader, sampleRate int) (*CueSheet, error) {
+	if sampleRate <= 0 {
+		return nil, fmt.Errorf("%w: unknown sample rate", errCueSheetInvalid)
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading cue sheet: %w", err)
+	}
+
+	data = bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF"))
+	if !utf8.Valid(data) {
+		if data, err = charmap.Windows1251.NewDecoder().Bytes(data); err != nil {
+			return nil, fmt.Errorf("%w: %w", errCueSheetInvalid, err)
+		}
+	}
+
+	cueSheet := &CueSheet{}
+
+	var track *CueTrack
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		fields := cueFields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		command, args := strings.ToUpper(fields[0]), fields[1:]
+
+		switch command {
+		case "REM":
+			if len(args) >= 2 {
+				parseCueRemark(cueSheet, strings.ToUpper(args[0]), strings.Join(args[1:], " "))
+			}
+		case "CATALOG":
+			cueSheet.CatalogNumber = cueArgument(args)
+		case "FILE":
+			if cueSheet.File != "" {
+				return nil, fmt.Errorf("%w: line %d: more than one FILE", errCueSheetInvalid, line)
+			}
+
+			// Тип файла (WAVE, MP3, ...) идёт последним словом.
+			if len(args) > 1 {
+				args = args[:len(args)-1]
+			}
+
+			cueSheet.File = cueArgument(args)
+		case "TITLE", "PERFORMER":
+			value := cueArgument(args)
+
+			switch {
+			case track != nil && command == "TITLE":
+				track.Title = value
+			case track != nil:
+				track.Performer = value
+			case command == "TITLE":
+				cueSheet.Title = value
+			default:
+				cueSheet.Performer = value
+			}
+		case "TRACK":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%w: line %d: TRACK without number or type", errCueSheetInvalid, line)
+			}
+
+			number, err := strconv.Atoi(args[0])
+			if err != nil || number <= 0 {
+				return nil, fmt.Errorf("%w: line %d: track number %q", errCueSheetInvalid, line, args[0])
+			}
+
+			cueSheet.Tracks = append(cueSheet.Tracks, CueTrack{
+				Number: number,
+				Audio:  strings.EqualFold(args[1], "AUDIO"),
+			})
+			track = &cueSheet.Tracks[len(cueSheet.Tracks)-1]
+		case "ISRC":
+			if track != nil {
+				track.ISRC = cueArgument(args)
+			}
+		case "FLAGS":
+			if track != nil {
+				for _, flag := range args {
+					track.PreEmphasis = track.PreEmphasis || strings.EqualFold(flag, "PRE")
+				}
+			}
+		case "INDEX":
+			if track == nil || len(args) < 2 {
+				return nil, fmt.Errorf("%w: line %d: INDEX outside of TRACK", errCueSheetInvalid, line)
+			}
+
+			number, err := strconv.Atoi(args[0])
+			if err != nil || number < 0 {
+				return nil, fmt.Errorf("%w: line %d: index number %q", errCueSheetInvalid, line, args[0])
+			}
+
+			frames, err := parseCueTime(args[1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", errCueSheetInvalid, line, err)
+			}
+
+			offset := frames * uint64(sampleRate) / cueFramesPerSecond
+
+			// Смещения точек хранятся относительно начала трека, как в блоке CUESHEET FLAC; началом трека
+			// считается его первая точка.
+			if len(track.Indexes) == 0 {
+				track.Offset = offset
+			}
+
+			if offset < track.Offset {
+				return nil, fmt.Errorf("%w: line %d: index goes backwards", errCueSheetInvalid, line)
+			}
+
+			track.Indexes = append(track.Indexes, CueIndex{Number: number, Offset: offset - track.Offset})
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading cue sheet: %w", err)
+	}
+
+	if len(cueSheet.Tracks) == 0 {
+		return nil, fmt.Errorf("%w: no tracks", errCueSheetInvalid)
+	}
+
+	for i, track := range cueSheet.Tracks {
+		if len(track.Indexes) == 0 {
+			return nil, fmt.Errorf("%w: track %d has no INDEX", errCueSheetInvalid, track.Number)
+		}
+
+		if i > 0 && track.Offset < cueSheet.Tracks[i-1].Offset {
+			return nil, fmt.Errorf("%w: track %d starts before track %d", errCueSheetInvalid, track.Number, cueSheet.Tracks[i-1].Number)
+		}
+	}
+
+	return cueSheet, nil
+}
+
+// ParseCueSheetFile читает cue-файл по пути, см. ParseCueSheet.
+func ParseCueSheetFile(filePath string, sampleRate int) (*CueSheet, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open cue sheet: %w", err)
+	}
+
+	defer f.Close()
+
+	return ParseCueSheet(f, sampleRate)
+}
+
+// FindCueSheet ищет разметку для разобранного файла образа: сначала cue-файл рядом с ним, затем текст в теге
+// CUESHEET и, наконец, блок CUESHEET FLAC. Отдельный и встроенный текстовый cue предпочтительнее блока: только в них
+// есть названия треков. Рядом лежащий cue подходит, если размечает единственный файл с тем же именем (расширение
+// может отличаться: образ часто пережимают из WAV во FLAC, не правя cue) или сам называется так же, как образ.
+// Если разметки нет, возвращается nil без ошибки.
+func FindCueSheet(meta *TrackFileMetadata) (*CueSheet, error) {
+	if meta.Path != "" {
+		cueSheet, err := findSidecarCueSheet(meta)
+		if err != nil || cueSheet != nil {
+			return cueSheet, err
+		}
+	}
+
+	if text := strings.TrimSpace(meta.Tags["cuesheet"]); text != "" {
+		return ParseCueSheet(strings.NewReader(text), meta.SampleRate)
+	}
+
+	return meta.CueSheet, nil
+}
+
+func findSidecarCueSheet(meta *TrackFileMetadata) (*CueSheet, error) {
+	dir := filepath.Dir(meta.Path)
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	stem := func(name string) string {
+		// В FILE бывает путь с обратными слэшами Windows.
+		name = path.Base(strings.ReplaceAll(name, `\`, "/"))
+		return strings.ToLower(strings.TrimSuffix(name, path.Ext(name)))
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".cue") {
+			continue
+		}
+
+		cueSheet, err := ParseCueSheetFile(filepath.Join(dir, entry.Name()), meta.SampleRate)
+		if err != nil {
+			// Чужой или битый cue рядом не должен мешать: разметка может найтись в тегах.
+			if stem(entry.Name()) == stem(meta.Filename) {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if stem(cueSheet.File) == stem(meta.Filename) || stem(entry.Name()) == stem(meta.Filename) {
+			return cueSheet, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func parseCueRemark(cueSheet *CueSheet, key, value string) {
+	value = strings.Trim(value, `"`)
+
+	switch key {
+	case "GENRE":
+		cueSheet.Genre = value
+	case "DATE":
+		cueSheet.Date = value
+	case "DISCNUMBER":
+		if number, err := strconv.Atoi(value); err == nil && number > 0 {
+			cueSheet.DiscNumber = number
+		}
+	}
+}
+
+// parseCueTime переводит mm:ss:ff в кадры CD.
+func parseCueTime(value string) (uint64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("time %q is not mm:ss:ff", value)
+	}
+
+	var numbers [3]uint64
+
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("time %q is not mm:ss:ff", value)
+		}
+
+		numbers[i] = n
+	}
+
+	if numbers[1] >= 60 || numbers[2] >= cueFramesPerSecond {
+		return 0, fmt.Errorf("time %q is out of range", value)
+	}
+
+	return (numbers[0]*60+numbers[1])*cueFramesPerSecond + numbers[2], nil
+}
+
+// cueFields делит строку cue-файла на слова; слова в двойных кавычках могут содержать пробелы.
+func cueFields(line string) []string {
+	var (
+		fields []string
+		field  strings.Builder
+		quoted bool
+		inWord bool
+	)
+
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			inWord = true
+		case !quoted && (r == ' ' || r == '\t'):
+			if inWord {
+				fields = append(fields, field.String())
+				field.Reset()
+				inWord = false
+			}
+		default:
+			field.WriteRune(r)
+			inWord = true
+		}
+	}
+
+	if inWord {
+		fields = append(fields, field.String())
+	}
+
+	return fields
+}
+
+// cueArgument значение команды: обычно одно слово в кавычках, но встречаются и названия без кавычек.
+func cueArgument(args []string) string {
+	return strings.TrimSpace(strings.Join(args, " "))
+}
@@ -0,0 +1,315 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"math/cmplx"
+	"slices"
+)
+
+const (
+	// Как в Chromaprint: звук сводится в моно 11025 Гц и режется на окна по 4096 сэмплов с шагом в треть окна,
+	// то есть один субфингерпринт на ~124 мс.
+	fingerprintSampleRate = 11025
+	fingerprintFrameSize  = 4096
+	fingerprintFrameStep  = fingerprintFrameSize / 3
+	// fingerprintMaxDuration дальше начала трека не декодируется: двух минут хватает, чтобы узнать запись.
+	fingerprintMaxDuration = 120
+
+	// Биты субфингерпринта получаются по схеме Haitsma–Kalker из энергии fingerprintBands полос, логарифмически
+	// разбивающих диапазон fingerprintMinFrequency–fingerprintMaxFrequency: 33 полосы дают 32 бита.
+	fingerprintBands        = 33
+	fingerprintMinFrequency = 300.0
+	fingerprintMaxFrequency = 2000.0
+
+	// fingerprintMaxOffset на сколько субфингерпринтов (~10 с) сдвигаются записи друг относительно друга при
+	// сравнении: у копий одной записи бывает разная тишина в начале.
+	fingerprintMaxOffset = 80
+	// fingerprintMinOverlap меньше стольких общих субфингерпринтов (~5 с) записи не сравниваются.
+	fingerprintMinOverlap = 40
+	// fingerprintKeyShift столько младших бит субфингерпринта отбрасывается в ключах поиска.
+	fingerprintKeyShift = 4
+
+	fingerprintMagic   = "BBFP"
+	fingerprintVersion = 1
+)
+
+var (
+	errFingerprintInvalid = errors.New("invalid fingerprint data")
+	errFingerprintShort   = errors.New("audio is too short to fingerprint")
+)
+
+// Fingerprint акустический отпечаток начала записи в духе Chromaprint: по 32-битному субфингерпринту на окно.
+// Отпечатки копий одной записи (в другом формате, с другой громкостью или частотой дискретизации) отличаются
+// немногими битами, а разных записей — примерно половиной.
+type Fingerprint struct {
+	Values []uint32
+}
+
+// ComputeFingerprint декодирует первые fingerprintMaxDuration секунд потока и считает его отпечаток.
+func ComputeFingerprint(decoder PCMDecoder) (*Fingerprint, error) {
+	f := newFingerprinter(decoder.SampleRate(), decoder.BitsPerSample())
+	limit := uint64(fingerprintMaxDuration * decoder.SampleRate())
+
+	var samples uint64
+
+	for samples < limit {
+		frame, err := decoder.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(frame) == 0 {
+			continue
+		}
+
+		f.write(frame)
+		samples += uint64(len(frame[0]))
+	}
+
+	if len(f.values) < fingerprintMinOverlap {
+		return nil, errFingerprintShort
+	}
+
+	return &Fingerprint{Values: f.values}, nil
+}
+
+// Similarity доля совпавших бит отпечатков при лучшем сдвиге записей друг относительно друга: около 0.5 у разных
+// записей и близко к 1 у копий одной. Пары субфингерпринтов цифровой тишины не учитываются. Если общих
+// субфингерпринтов при любом сдвиге меньше fingerprintMinOverlap, сходство 0.
+func (f *Fingerprint) Similarity(other *Fingerprint) float64 {
+	best := 0.0
+
+	for offset := -fingerprintMaxOffset; offset <= fingerprintMaxOffset; offset++ {
+		var compared, mismatched int
+
+		for i := max(0, -offset); i < len(f.Values) && i+offset < len(other.Values); i++ {
+			a, b := f.Values[i], other.Values[i+offset]
+			if a == 0 && b == 0 {
+				continue
+			}
+
+			compared++
+			mismatched += bits.OnesCount32(a ^ b)
+		}
+
+		if compared < fingerprintMinOverlap {
+			continue
+		}
+
+		best = max(best, 1-float64(mismatched)/float64(32*compared))
+	}
+
+	return best
+}
+
+// LookupKeys ключи поиска похожих отпечатков: различные старшие 28 бит субфингерпринтов, кроме цифровой тишины, по
+// возрастанию. У копий одной записи часть субфингерпринтов совпадает целиком, так что общий ключ у них почти
+// наверняка есть, а у разных записей он редкость. Ключи помещаются в int32, чтобы храниться в integer[].
+func (f *Fingerprint) LookupKeys() []int32 {
+	keys := make([]int32, 0, len(f.Values))
+
+	for _, value := range f.Values {
+		if key := int32(value >> fingerprintKeyShift); key != 0 {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return slices.Compact(keys)
+}
+
+// MarshalBinary кодирует отпечаток: сигнатура "BBFP", версия, число субфингерпринтов (uint32) и сами они,
+// все числа little-endian.
+func (f *Fingerprint) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 9+4*len(f.Values))
+	b = append(b, fingerprintMagic...)
+	b = append(b, fingerprintVersion)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(f.Values)))
+
+	for _, value := range f.Values {
+		b = binary.LittleEndian.AppendUint32(b, value)
+	}
+
+	return b, nil
+}
+
+func (f *Fingerprint) UnmarshalBinary(b []byte) error {
+	if len(b) < 9 || string(b[:4]) != fingerprintMagic {
+		return errFingerprintInvalid
+	}
+
+	if b[4] != fingerprintVersion {
+		return fmt.Errorf("%w: version %d", errFingerprintInvalid, b[4])
+	}
+
+	count := int(binary.LittleEndian.Uint32(b[5:]))
+	b = b[9:]
+
+	if len(b)/4 != count || len(b)%4 != 0 {
+		return errFingerprintInvalid
+	}
+
+	f.Values = make([]uint32, count)
+	for i := range f.Values {
+		f.Values[i] = binary.LittleEndian.Uint32(b[4*i:])
+	}
+
+	return nil
+}
+
+// fingerprinter потоковый расчёт отпечатка: сведение в моно, передискретизация, окна и полосы.
+type fingerprinter struct {
+	scale float64
+
+	// Передискретизация усреднением: каждый выходной сэмпл — среднее входных, попавших в его интервал длиной step
+	// входных сэмплов. Это заодно грубый фильтр нижних частот перед прореживанием.
+	step     float64
+	position float64
+	next     float64
+	sum      float64
+	count    int
+
+	buffer []float64
+	window []float64
+	fft    []complex128
+	// bandBins границы полос в номерах бинов спектра: полоса i — бины [bandBins[i], bandBins[i+1]).
+	bandBins [fingerprintBands + 1]int
+
+	previous [fingerprintBands]float64
+	started  bool
+	values   []uint32
+}
+
+func newFingerprinter(sampleRate, bitsPerSample int) *fingerprinter {
+	f := &fingerprinter{
+		scale:  1 / float64(int64(1)<<(bitsPerSample-1)),
+		step:   float64(sampleRate) / fingerprintSampleRate,
+		buffer: make([]float64, 0, fingerprintFrameSize),
+		window: make([]float64, fingerprintFrameSize),
+		fft:    make([]complex128, fingerprintFrameSize),
+	}
+
+	f.next = f.step
+
+	for i := range f.window {
+		f.window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(fingerprintFrameSize-1))
+	}
+
+	ratio := fingerprintMaxFrequency / fingerprintMinFrequency
+	for i := range f.bandBins {
+		frequency := fingerprintMinFrequency * math.Pow(ratio, float64(i)/fingerprintBands)
+		f.bandBins[i] = int(math.Round(frequency * fingerprintFrameSize / fingerprintSampleRate))
+	}
+
+	return f
+}
+
+func (f *fingerprinter) write(frame [][]int32) {
+	for i := range frame[0] {
+		var mono float64
+		for _, channel := range frame {
+			mono += float64(channel[i])
+		}
+
+		f.sum += mono * f.scale / float64(len(frame))
+		f.count++
+		f.position++
+
+		// При повышении частоты (step < 1) один входной сэмпл закрывает несколько выходных.
+		for f.position >= f.next {
+			f.push(f.sum / float64(max(f.count, 1)))
+			f.next += f.step
+
+			if f.position < f.next {
+				f.sum, f.count = 0, 0
+			}
+		}
+	}
+}
+
+func (f *fingerprinter) push(sample float64) {
+	f.buffer = append(f.buffer, sample)
+	if len(f.buffer) < fingerprintFrameSize {
+		return
+	}
+
+	f.processFrame()
+
+	copy(f.buffer, f.buffer[fingerprintFrameStep:])
+	f.buffer = f.buffer[:fingerprintFrameSize-fingerprintFrameStep]
+}
+
+// processFrame считает энергию полос окна и из её разностей по частоте и по времени — биты субфингерпринта.
+func (f *fingerprinter) processFrame() {
+	for i, sample := range f.buffer {
+		f.fft[i] = complex(sample*f.window[i], 0)
+	}
+
+	fft(f.fft)
+
+	var energy [fingerprintBands]float64
+	for band := range energy {
+		for bin := f.bandBins[band]; bin < f.bandBins[band+1]; bin++ {
+			magnitude := cmplx.Abs(f.fft[bin])
+			energy[band] += magnitude * magnitude
+		}
+	}
+
+	if f.started {
+		var value uint32
+
+		for band := range fingerprintBands - 1 {
+			diff := energy[band] - energy[band+1] - (f.previous[band] - f.previous[band+1])
+			if diff > 0 {
+				value |= 1 << band
+			}
+		}
+
+		f.values = append(f.values, value)
+	}
+
+	f.previous = energy
+	f.started = true
+}
+
+// fft преобразование Фурье на месте по основанию 2; длина x — степень двойки.
+func fft(x []complex128) {
+	n := len(x)
+
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+
+		j ^= bit
+
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+
+			for k := range size / 2 {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k], x[start+k+size/2] = even+odd, even-odd
+				w *= step
+			}
+		}
+	}
+}
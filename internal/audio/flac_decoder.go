@@ -0,0 +1,686 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrFlacCorrupt поток FLAC повреждён или обрезан: нарушена синхронизация, не сошлась CRC фрейма, встретилось
+	// недопустимое значение или файл закончился раньше, чем обещал STREAMINFO.
+	ErrFlacCorrupt = errors.New("corrupt flac stream")
+	// ErrFlacMD5Mismatch поток декодируется, но MD5 полученного PCM не совпадает с подписью из STREAMINFO.
+	ErrFlacMD5Mismatch = errors.New("flac md5 signature mismatch")
+)
+
+// Каналы фрейма FLAC: до 8 независимых каналов или одна из стерео-схем с разностным каналом.
+const (
+	flacChannelsLeftSide  = 8
+	flacChannelsSideRight = 9
+	flacChannelsMidSide   = 10
+)
+
+// flacSampleSizes разрядность по 3-битному коду заголовка фрейма; 0 — «как в STREAMINFO», -1 — зарезервировано.
+var flacSampleSizes = [8]int{0, 8, 12, -1, 16, 20, 24, 32}
+
+// FlacDecoder последовательно декодирует фреймы FLAC в PCM. Поддерживаются все типы подфреймов (constant,
+// verbatim, fixed, LPC) с остатками Rice, все стерео-схемы и «потраченные» биты; CRC-8 заголовка и CRC-16 каждого
+// фрейма проверяются.
+type FlacDecoder struct {
+	br      *bitReader
+	info    flacStreamInfo
+	decoded uint64
+
+	work [][]int64
+	out  [][]int32
+}
+
+// NewFlacDecoder читает сигнатуру и метаблоки (ID3v2 перед ними пропускается) и готовит декодер к чтению
+// фреймов. Источник читается строго последовательно, поэтому подходит и тело объекта из хранилища.
+func NewFlacDecoder(r io.Reader) (*FlacDecoder, error) {
+	br := newBitReader(r)
+
+	info, err := readFlacStreamHeader(br.r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FlacDecoder{br: br, info: info}, nil
+}
+
+func (d *FlacDecoder) SampleRate() int {
+	return d.info.sampleRate
+}
+
+func (d *FlacDecoder) Channels() int {
+	return d.info.channels
+}
+
+func (d *FlacDecoder) BitsPerSample() int {
+	return d.info.bitsPerSample
+}
+
+// TotalSamples число сэмплов на канал по STREAMINFO; 0, если кодер его не знал.
+func (d *FlacDecoder) TotalSamples() uint64 {
+	return d.info.totalSamples
+}
+
+// ReadFrame декодирует следующий фрейм и возвращает его сэмплы по каналам. Срезы переиспользуются и действительны
+// только до следующего вызова. В конце потока возвращает io.EOF.
+func (d *FlacDecoder) ReadFrame() ([][]int32, error) {
+	if d.info.totalSamples > 0 && d.decoded >= d.info.totalSamples {
+		return nil, io.EOF
+	}
+
+	frame, err := d.readFrame()
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("%w: truncated at sample %d", ErrFlacCorrupt, d.decoded)
+	}
+
+	return frame, err
+}
+
+func (d *FlacDecoder) readFrame() ([][]int32, error) {
+	br := d.br
+
+	br.align()
+	br.resetCRC()
+
+	sync, err := br.readByte()
+	if errors.Is(err, io.EOF) {
+		if d.info.totalSamples > 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+
+		return nil, io.EOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := br.readBits(8)
+	if err != nil {
+		return nil, err
+	}
+
+	// 14 бит синхрослова, зарезервированный бит и бит стратегии размера блока.
+	if sync != 0xFF || header&0xFE != 0xF8 {
+		return nil, fmt.Errorf("%w: lost frame sync at sample %d", ErrFlacCorrupt, d.decoded)
+	}
+
+	codes, err := br.readBits(16)
+	if err != nil {
+		return nil, err
+	}
+
+	blockSizeCode, rateCode := codes>>12, codes>>8&0xF
+	channelCode, sizeCode := codes>>4&0xF, codes>>1&0x7
+
+	if codes&1 != 0 || rateCode == 15 || flacSampleSizes[sizeCode] < 0 || channelCode > flacChannelsMidSide {
+		return nil, fmt.Errorf("%w: reserved value in frame header", ErrFlacCorrupt)
+	}
+
+	// Номер фрейма или первого сэмпла в кодировке UTF-8 (до 7 байт); он нам не нужен, но входит в CRC.
+	if err = d.skipCodedNumber(); err != nil {
+		return nil, err
+	}
+
+	blockSize, err := d.readBlockSize(blockSizeCode)
+	if err != nil {
+		return nil, err
+	}
+
+	switch rateCode {
+	case 12:
+		_, err = br.readBits(8)
+	case 13, 14:
+		_, err = br.readBits(16)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	expectedCRC8 := br.crc8
+
+	crc8, err := br.readBits(8)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint8(crc8) != expectedCRC8 {
+		return nil, fmt.Errorf("%w: frame header crc mismatch at sample %d", ErrFlacCorrupt, d.decoded)
+	}
+
+	bitsPerSample := flacSampleSizes[sizeCode]
+	if bitsPerSample == 0 {
+		bitsPerSample = d.info.bitsPerSample
+	}
+
+	channels := int(channelCode) + 1
+	if channelCode >= flacChannelsLeftSide {
+		channels = 2
+	}
+
+	if channels != d.info.channels {
+		return nil, fmt.Errorf("%w: frame has %d channels, stream has %d", ErrFlacCorrupt, channels, d.info.channels)
+	}
+
+	d.grow(channels, blockSize)
+
+	for ch := range channels {
+		// Разностный канал на бит шире остальных.
+		sideBits := 0
+		if (channelCode == flacChannelsLeftSide && ch == 1) || (channelCode == flacChannelsSideRight && ch == 0) ||
+			(channelCode == flacChannelsMidSide && ch == 1) {
+			sideBits = 1
+		}
+
+		if err = d.decodeSubframe(d.work[ch][:blockSize], bitsPerSample+sideBits); err != nil {
+			return nil, err
+		}
+	}
+
+	br.align()
+
+	expectedCRC16 := br.crc16
+
+	crc16, err := br.readBits(16)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint16(crc16) != expectedCRC16 {
+		return nil, fmt.Errorf("%w: frame crc mismatch at sample %d", ErrFlacCorrupt, d.decoded)
+	}
+
+	d.decorrelate(channelCode, channels, blockSize)
+	d.decoded += uint64(blockSize)
+
+	frame := d.out[:channels]
+	for ch := range frame {
+		frame[ch] = frame[ch][:blockSize]
+	}
+
+	return frame, nil
+}
+
+func (d *FlacDecoder) skipCodedNumber() error {
+	first, err := d.br.readBits(8)
+	if err != nil {
+		return err
+	}
+
+	var extra int
+	for mask := uint64(0x80); first&mask != 0 && mask > 1; mask >>= 1 {
+		extra++
+	}
+
+	// 0xxxxxxx — один байт, 110xxxxx — два, ..., 11111110 — семь; 10xxxxxx и 11111111 в начале недопустимы.
+	if extra == 1 || first == 0xFF {
+		return fmt.Errorf("%w: invalid coded frame number", ErrFlacCorrupt)
+	}
+
+	for range max(extra-1, 0) {
+		b, err := d.br.readBits(8)
+		if err != nil {
+			return err
+		}
+
+		if b&0xC0 != 0x80 {
+			return fmt.Errorf("%w: invalid coded frame number", ErrFlacCorrupt)
+		}
+	}
+
+	return nil
+}
+
+func (d *FlacDecoder) readBlockSize(code uint64) (int, error) {
+	var blockSize int
+
+	switch {
+	case code == 1:
+		blockSize = 192
+	case code >= 2 && code <= 5:
+		blockSize = 576 << (code - 2)
+	case code == 6 || code == 7:
+		v, err := d.br.readBits(8 << (code - 6))
+		if err != nil {
+			return 0, err
+		}
+
+		blockSize = int(v) + 1
+	case code >= 8:
+		blockSize = 256 << (code - 8)
+	default:
+		return 0, fmt.Errorf("%w: reserved block size", ErrFlacCorrupt)
+	}
+
+	if d.info.maxBlockSize > 0 && blockSize > d.info.maxBlockSize {
+		return 0, fmt.Errorf("%w: block size %d exceeds maximum %d", ErrFlacCorrupt, blockSize, d.info.maxBlockSize)
+	}
+
+	return blockSize, nil
+}
+
+func (d *FlacDecoder) grow(channels, blockSize int) {
+	if len(d.work) < channels {
+		d.work = make([][]int64, channels)
+		d.out = make([][]int32, channels)
+	}
+
+	for ch := range channels {
+		if cap(d.work[ch]) < blockSize {
+			d.work[ch] = make([]int64, blockSize)
+			d.out[ch] = make([]int32, blockSize)
+		}
+
+		d.work[ch] = d.work[ch][:blockSize]
+		d.out[ch] = d.out[ch][:blockSize]
+	}
+}
+
+// decodeSubframe декодирует подфрейм одного канала: бит-заполнитель, 6 бит типа, флаг потраченных бит (их число
+// записано унарно), затем тело.
+func (d *FlacDecoder) decodeSubframe(dst []int64, bitsPerSample int) error {
+	br := d.br
+
+	header, err := br.readBits(8)
+	if err != nil {
+		return err
+	}
+
+	if header&0x80 != 0 {
+		return fmt.Errorf("%w: subframe padding bit set", ErrFlacCorrupt)
+	}
+
+	kind := header >> 1 & 0x3F
+
+	var wasted int
+
+	if header&1 != 0 {
+		k, err := br.readUnary()
+		if err != nil {
+			return err
+		}
+
+		wasted = int(k) + 1
+		bitsPerSample -= wasted
+
+		if bitsPerSample <= 0 {
+			return fmt.Errorf("%w: too many wasted bits", ErrFlacCorrupt)
+		}
+	}
+
+	switch {
+	case kind == 0: // CONSTANT
+		v, err := br.readSigned(uint(bitsPerSample))
+		if err != nil {
+			return err
+		}
+
+		for i := range dst {
+			dst[i] = v
+		}
+	case kind == 1: // VERBATIM
+		for i := range dst {
+			if dst[i], err = br.readSigned(uint(bitsPerSample)); err != nil {
+				return err
+			}
+		}
+	case kind >= 8 && kind <= 12: // FIXED порядка 0-4
+		err = d.decodeFixed(dst, int(kind-8), bitsPerSample)
+	case kind >= 32: // LPC порядка 1-32
+		err = d.decodeLPC(dst, int(kind-31), bitsPerSample)
+	default:
+		return fmt.Errorf("%w: reserved subframe type %d", ErrFlacCorrupt, kind)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if wasted > 0 {
+		for i := range dst {
+			dst[i] <<= wasted
+		}
+	}
+
+	return nil
+}
+
+func (d *FlacDecoder) readWarmup(dst []int64, order, bitsPerSample int) error {
+	if order > len(dst) {
+		return fmt.Errorf("%w: predictor order %d exceeds block size %d", ErrFlacCorrupt, order, len(dst))
+	}
+
+	for i := range order {
+		v, err := d.br.readSigned(uint(bitsPerSample))
+		if err != nil {
+			return err
+		}
+
+		dst[i] = v
+	}
+
+	return nil
+}
+
+// decodeFixed восстанавливает сигнал фиксированным предсказателем: разности порядка order от предыдущих сэмплов.
+func (d *FlacDecoder) decodeFixed(dst []int64, order, bitsPerSample int) error {
+	if err := d.readWarmup(dst, order, bitsPerSample); err != nil {
+		return err
+	}
+
+	if err := d.decodeResidual(dst, order); err != nil {
+		return err
+	}
+
+	for i := order; i < len(dst); i++ {
+		switch order {
+		case 1:
+			dst[i] += dst[i-1]
+		case 2:
+			dst[i] += 2*dst[i-1] - dst[i-2]
+		case 3:
+			dst[i] += 3*dst[i-1] - 3*dst[i-2] + dst[i-3]
+		case 4:
+			dst[i] += 4*dst[i-1] - 6*dst[i-2] + 4*dst[i-3] - dst[i-4]
+		}
+	}
+
+	return nil
+}
+
+// decodeLPC восстанавливает сигнал линейным предсказателем: точность коэффициентов (4 бита + 1), сдвиг (5 бит со
+// знаком), коэффициенты, затем остатки.
+func (d *FlacDecoder) decodeLPC(dst []int64, order, bitsPerSample int) error {
+	br := d.br
+
+	if err := d.readWarmup(dst, order, bitsPerSample); err != nil {
+		return err
+	}
+
+	precision, err := br.readBits(4)
+	if err != nil {
+		return err
+	}
+
+	if precision == 15 {
+		return fmt.Errorf("%w: invalid lpc precision", ErrFlacCorrupt)
+	}
+
+	shift, err := br.readSigned(5)
+	if err != nil {
+		return err
+	}
+
+	if shift < 0 {
+		return fmt.Errorf("%w: negative lpc shift", ErrFlacCorrupt)
+	}
+
+	var coefficients [32]int64
+	for i := range order {
+		if coefficients[i], err = br.readSigned(uint(precision + 1)); err != nil {
+			return err
+		}
+	}
+
+	if err = d.decodeResidual(dst, order); err != nil {
+		return err
+	}
+
+	for i := order; i < len(dst); i++ {
+		var prediction int64
+		for j := range order {
+			prediction += coefficients[j] * dst[i-1-j]
+		}
+
+		dst[i] += prediction >> shift
+	}
+
+	return nil
+}
+
+// decodeResidual читает остатки предсказания в dst[order:]. Блок делится на 2^partitionOrder разделов со своим
+// параметром Rice; escape-значение параметра означает, что раздел записан «как есть» фиксированной разрядностью.
+func (d *FlacDecoder) decodeResidual(dst []int64, order int) error {
+	br := d.br
+
+	method, err := br.readBits(2)
+	if err != nil {
+		return err
+	}
+
+	var paramBits uint
+
+	switch method {
+	case 0:
+		paramBits = 4
+	case 1:
+		paramBits = 5
+	default:
+		return fmt.Errorf("%w: reserved residual coding method", ErrFlacCorrupt)
+	}
+
+	escape := uint64(1)<<paramBits - 1
+
+	partitionOrder, err := br.readBits(4)
+	if err != nil {
+		return err
+	}
+
+	partitions := 1 << partitionOrder
+	partitionSize := len(dst) >> partitionOrder
+
+	if partitionSize<<partitionOrder != len(dst) || partitionSize < order {
+		return fmt.Errorf("%w: invalid residual partition order %d", ErrFlacCorrupt, partitionOrder)
+	}
+
+	i := order
+
+	for p := range partitions {
+		n := partitionSize
+		if p == 0 {
+			n -= order
+		}
+
+		k, err := br.readBits(paramBits)
+		if err != nil {
+			return err
+		}
+
+		if k == escape {
+			rawBits, err := br.readBits(5)
+			if err != nil {
+				return err
+			}
+
+			for end := i + n; i < end; i++ {
+				if dst[i], err = br.readSigned(uint(rawBits)); err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
+		for end := i + n; i < end; i++ {
+			q, err := br.readUnary()
+			if err != nil {
+				return err
+			}
+
+			low, err := br.readBits(uint(k))
+			if err != nil {
+				return err
+			}
+
+			v := q<<k | low
+			dst[i] = int64(v>>1) ^ -int64(v&1)
+		}
+	}
+
+	return nil
+}
+
+// decorrelate восстанавливает левый и правый каналы из разностных схем и переносит сэмплы в выходные срезы.
+func (d *FlacDecoder) decorrelate(channelCode uint64, channels, blockSize int) {
+	switch channelCode {
+	case flacChannelsLeftSide:
+		left, side := d.work[0], d.work[1]
+		for i := range blockSize {
+			side[i] = left[i] - side[i]
+		}
+	case flacChannelsSideRight:
+		side, right := d.work[0], d.work[1]
+		for i := range blockSize {
+			side[i] += right[i]
+		}
+	case flacChannelsMidSide:
+		mid, side := d.work[0], d.work[1]
+		for i := range blockSize {
+			m := mid[i]<<1 | side[i]&1
+			mid[i], side[i] = (m+side[i])>>1, (m-side[i])>>1
+		}
+	}
+
+	for ch := range channels {
+		for i := range blockSize {
+			d.out[ch][i] = int32(d.work[ch][i])
+		}
+	}
+}
+
+// readFlacStreamHeader читает сигнатуру fLaC и все метаблоки, оставляя r на первом фрейме.
+func readFlacStreamHeader(r *bufio.Reader) (flacStreamInfo, error) {
+	var header [10]byte
+
+	if _, err := io.ReadFull(r, header[:4]); err != nil {
+		return flacStreamInfo{}, fmt.Errorf("reading flac header: %w", err)
+	}
+
+	if bytes.HasPrefix(header[:], []byte("ID3")) {
+		if _, err := io.ReadFull(r, header[4:]); err != nil {
+			return flacStreamInfo{}, fmt.Errorf("reading id3v2 header: %w", err)
+		}
+
+		// Размер тега synchsafe (по 7 бит в байте), плюс 10 байт подвала, если он есть.
+		tagSize := int64(header[6])<<21 | int64(header[7])<<14 | int64(header[8])<<7 | int64(header[9])
+		if header[5]&0x10 != 0 {
+			tagSize += 10
+		}
+
+		if _, err := io.CopyN(io.Discard, r, tagSize); err != nil {
+			return flacStreamInfo{}, fmt.Errorf("skipping id3v2 tag: %w", err)
+		}
+
+		if _, err := io.ReadFull(r, header[:4]); err != nil {
+			return flacStreamInfo{}, fmt.Errorf("reading flac header: %w", err)
+		}
+	}
+
+	if string(header[:4]) != "fLaC" {
+		return flacStreamInfo{}, fmt.Errorf("invalid flac signature: %q", header[:4])
+	}
+
+	var (
+		info          flacStreamInfo
+		hasStreamInfo bool
+	)
+
+	for {
+		var h [4]byte
+		if _, err := io.ReadFull(r, h[:]); err != nil {
+			return flacStreamInfo{}, fmt.Errorf("reading metadata header: %w", err)
+		}
+
+		blockLen := int64(h[1])<<16 | int64(h[2])<<8 | int64(h[3])
+
+		if h[0]&0x7F == flacBlockStreamInfo && !hasStreamInfo {
+			data := make([]byte, blockLen)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return flacStreamInfo{}, fmt.Errorf("reading metadata block: %w", err)
+			}
+
+			var err error
+			if info, err = parseFlacStreamInfo(data); err != nil {
+				return flacStreamInfo{}, err
+			}
+
+			hasStreamInfo = true
+		} else if _, err := io.CopyN(io.Discard, r, blockLen); err != nil {
+			return flacStreamInfo{}, fmt.Errorf("skipping metadata block: %w", err)
+		}
+
+		if h[0]&0x80 != 0 {
+			break
+		}
+	}
+
+	if !hasStreamInfo {
+		return flacStreamInfo{}, fmt.Errorf("%w: missing STREAMINFO", ErrFlacCorrupt)
+	}
+
+	return info, nil
+}
+
+// VerifyFlac декодирует весь поток и сверяет MD5 полученного PCM с подписью из STREAMINFO. PCM для подписи
+// складывается так же, как это делает эталонный кодер: сэмплы всех каналов по очереди, little-endian, по
+// ceil(bitsPerSample/8) байт. Возвращает ErrFlacCorrupt для повреждённого или обрезанного потока и
+// ErrFlacMD5Mismatch, если подпись не сошлась; если кодер подпись не записал (все нули), проверяются только CRC.
+func VerifyFlac(r io.Reader) error {
+	d, err := NewFlacDecoder(r)
+	if err != nil {
+		return err
+	}
+
+	var (
+		hash           = md5.New()
+		bytesPerSample = (d.info.bitsPerSample + 7) / 8
+		buf            []byte
+	)
+
+	for {
+		frame, err := d.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		buf = buf[:0]
+
+		for i := range frame[0] {
+			for _, channel := range frame {
+				v := channel[i]
+				for b := range bytesPerSample {
+					buf = append(buf, byte(v>>(8*b)))
+				}
+			}
+		}
+
+		hash.Write(buf)
+	}
+
+	if d.info.totalSamples > 0 && d.decoded != d.info.totalSamples {
+		return fmt.Errorf("%w: decoded %d samples, STREAMINFO declares %d", ErrFlacCorrupt, d.decoded, d.info.totalSamples)
+	}
+
+	if d.info.md5 == [16]byte{} {
+		return nil
+	}
+
+	if !bytes.Equal(hash.Sum(nil), d.info.md5[:]) {
+		return ErrFlacMD5Mismatch
+	}
+
+	return nil
+}
@@ -3,18 +3,30 @@ package audio
 import (
 	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-	"io/fs"
-	"os"
-	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/untea/bottom_babruysk/internal/domain"
 )
 
-// TrackFileMetadata сжатое представление ключевой метаинформации, извлечённой из FLAC-файла. Эта структура
+// Типы метаблоков FLAC.
+const (
+	flacBlockStreamInfo    = 0
+	flacBlockVorbisComment = 4
+	flacBlockCueSheet      = 5
+	flacBlockPicture       = 6
+)
+
+var (
+	errFlacPictureInvalid  = errors.New("invalid flac picture block")
+	errFlacCueSheetInvalid = errors.New("invalid flac cuesheet block")
+)
+
+// TrackFileMetadata сжатое представление ключевой метаинформации, извлечённой из аудиофайла. Эта структура
 // приблизительно отражает подмножество полей domain.TrackFile, но намеренно отделена, чтобы не связывать parser жёстко
 // с деталями хранения.
 type TrackFileMetadata struct {
@@ -23,19 +35,24 @@ type TrackFileMetadata struct {
 	// Filename только базовое имя файла без директорий. Удобно иметь его под рукой; при этом вызывающая сторона всё
 	// равно может отдельно сохранять полный путь или ключ в хранилище объектов.
 	Filename string
-	// Format всегда domain.FormatFLAC для FLAC-файлов. Держим его здесь, чтобы при сохранении не вычислять значение
-	// повторно.
+	// Format контейнер файла (domain.FormatFLAC, domain.FormatMP3, ...). Держим его здесь, чтобы при сохранении не
+	// вычислять значение повторно.
 	Format domain.Format
-	// Codec всегда domain.CodecFLAC для FLAC-файлов.
+	// Codec кодек аудиопотока внутри контейнера.
 	Codec domain.Codec
+	// Mime MIME-тип файла, определённый по содержимому (audio/flac, audio/mpeg, ...).
+	Mime string
 	// SampleRate частота дискретизации в Гц (например 44100).
 	SampleRate int
 	// Channels количество каналов в аудиопотоке.
 	Channels int
-	// BitsPerSample разрядность PCM. Частые значения: 16 или 24.
+	// ChannelMask раскладка каналов в битах WAVE_FORMAT_EXTENSIBLE (0x1 FL, 0x2 FR, 0x4 FC, 0x8 LFE, ...). 0, если
+	// контейнер её не хранит: тогда подразумевается стандартная раскладка для Channels.
+	ChannelMask uint32
+	// BitsPerSample разрядность PCM. Частые значения: 16 или 24. Для кодеков с потерями 0.
 	BitsPerSample int
-	// Bitrate упрощённый расчёт сырого аудиобитрейта (кбит/с):
-	// SampleRate * BitsPerSample * Channels / 1000.
+	// Bitrate битрейт в кбит/с. Для FLAC это упрощённый расчёт сырого аудиобитрейта:
+	// SampleRate * BitsPerSample * Channels / 1000, для кодеков с потерями — средний битрейт потока.
 	Bitrate int
 	// TotalSamples общее число PCM-сэмплов. В паре с SampleRate позволяет вычислить длительность.
 	TotalSamples uint64
@@ -43,21 +60,28 @@ type TrackFileMetadata struct {
 	Duration time.Duration
 	// Size размер файла на диске в байтах.
 	Size int64
-	// MD5Signature 16-байтная MD5-подпись не кодированных PCM-данных из блока STREAMINFO FLAC.
+	// MD5Signature 16-байтная MD5-подпись не кодированных PCM-данных из блока STREAMINFO FLAC. Для остальных
+	// форматов нулевая.
 	MD5Signature [16]byte
+	// Tags текстовые теги файла (название, исполнитель, альбом, ...). Ключи приведены к нижнему регистру в стиле
+	// Vorbis comments: title, artist, album, date, genre, comment, tracknumber, ...
+	Tags map[string]string
+	// Cover обложка, если она встроена в файл.
+	Cover *Picture
+	// CueSheet разметка образа диска на треки, если она встроена в файл (блок CUESHEET FLAC).
+	CueSheet *CueSheet
 }
 
 // ParseFlacFile открывает файл по пути и пытается разобрать метаблок STREAMINFO, чтобы извлечь базовые параметры
-// потока FLAC. Если файл не начинается с магической последовательности "fLaC" либо не содержит валидного STREAMINFO,
+// потока FLAC. Заодно читаются теги из VORBIS_COMMENT, обложка из PICTURE и разметка из CUESHEET. Если файл не начинается с магической последовательности "fLaC" либо не содержит валидного STREAMINFO,
 // будет возвращена ошибка. Некритичные проблемы (например, неожиданный порядок метаблоков) допускаются, пока удаётся
 // найти и разобрать STREAMINFO.
 func ParseFlacFile(filePath string) (*TrackFileMetadata, error) {
-	f, err := os.Open(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("open flac file: %w", err)
-	}
+	return parseFile(filePath, "flac", parseFlac)
+}
 
-	defer f.Close()
+func parseFlac(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	f := io.NewSectionReader(r, 0, size)
 
 	// Читаем и валидируем магическую сигнатуру FLAC.
 	// Файл обязан начинаться с ASCII-последовательности "fLaC".
@@ -70,11 +94,21 @@ func ParseFlacFile(filePath string) (*TrackFileMetadata, error) {
 		return nil, fmt.Errorf("invalid flac signature: %q", string(header[:]))
 	}
 
-	var streamInfoData []byte
+	var (
+		meta           = &TrackFileMetadata{}
+		streamInfoData []byte
+		coverType      = -1
+	)
+
 	for {
 		var h [4]byte
 
 		if _, err := io.ReadFull(f, h[:]); err != nil {
+			// Обрезанные метаданные после STREAMINFO не мешают узнать параметры потока.
+			if streamInfoData != nil {
+				break
+			}
+
 			return nil, fmt.Errorf("reading metadata header: %w", err)
 		}
 
@@ -85,14 +119,42 @@ func ParseFlacFile(filePath string) (*TrackFileMetadata, error) {
 		// записанное в следующих трёх байтах.
 		blockLen := (uint32(h[1]) << 16) | (uint32(h[2]) << 8) | uint32(h[3])
 
-		data := make([]byte, blockLen)
-		if _, err := io.ReadFull(f, data); err != nil {
-			return nil, fmt.Errorf("reading metadata block: %w", err)
+		var data []byte
+
+		switch blockType {
+		case flacBlockStreamInfo, flacBlockVorbisComment, flacBlockPicture, flacBlockCueSheet:
+			data = make([]byte, blockLen)
+			if _, err := io.ReadFull(f, data); err != nil {
+				return nil, fmt.Errorf("reading metadata block: %w", err)
+			}
+		default:
+			// PADDING, SEEKTABLE, APPLICATION нам не нужны, а PADDING бывает большим: не читаем его.
+			if _, err := f.Seek(int64(blockLen), io.SeekCurrent); err != nil {
+				return nil, fmt.Errorf("skipping metadata block: %w", err)
+			}
 		}
 
-		if blockType == 0 { // STREAMINFO
-			streamInfoData = data
-			break
+		// Ошибки в необязательных блоках не мешают разобрать поток: такой блок просто пропускается.
+		switch blockType {
+		case flacBlockStreamInfo:
+			if streamInfoData == nil {
+				streamInfoData = data
+			}
+		case flacBlockVorbisComment:
+			pictures, err := parseVorbisComment(data, meta)
+			if err == nil {
+				for _, picture := range pictures {
+					setCover(meta, &coverType, picture)
+				}
+			}
+		case flacBlockPicture:
+			if picture, err := parseFlacPicture(data); err == nil {
+				setCover(meta, &coverType, picture)
+			}
+		case flacBlockCueSheet:
+			if cueSheet, err := parseFlacCueSheet(data); err == nil {
+				meta.CueSheet = cueSheet
+			}
 		}
 
 		if isLast {
@@ -100,8 +162,57 @@ func ParseFlacFile(filePath string) (*TrackFileMetadata, error) {
 		}
 	}
 
-	if len(streamInfoData) < 34 {
-		return nil, fmt.Errorf("invalid STREAMINFO block length: %d", len(streamInfoData))
+	info, err := parseFlacStreamInfo(streamInfoData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Считаем простой битрейт. Важно: FLAC VBR кодек, поэтому эта оценка предполагает как если бы PCM без сжатия и
+	// может не совпасть с реальным закодированным битрейтом.
+	var bitrate int
+
+	if info.sampleRate != 0 {
+		bitrate = info.sampleRate * info.bitsPerSample * info.channels / 1000
+	}
+
+	// Длительность: через float64, чтобы избежать переполнения при конвертации в time.Duration.
+	var duration time.Duration
+
+	if info.sampleRate != 0 {
+		seconds := float64(info.totalSamples) / float64(info.sampleRate)
+		duration = time.Duration(seconds * float64(time.Second))
+	}
+
+	meta.Format = domain.FormatFLAC
+	meta.Codec = domain.CodecFLAC
+	meta.Mime = MimeFLAC
+	meta.SampleRate = info.sampleRate
+	meta.Channels = info.channels
+	meta.BitsPerSample = info.bitsPerSample
+	meta.Bitrate = bitrate
+	meta.TotalSamples = info.totalSamples
+	meta.Duration = duration
+	meta.Size = size
+	meta.MD5Signature = info.md5
+
+	return meta, nil
+}
+
+// flacStreamInfo разобранный блок STREAMINFO.
+type flacStreamInfo struct {
+	maxBlockSize  int
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	totalSamples  uint64
+	md5           [16]byte
+}
+
+func parseFlacStreamInfo(data []byte) (flacStreamInfo, error) {
+	var info flacStreamInfo
+
+	if len(data) < 34 {
+		return info, fmt.Errorf("invalid STREAMINFO block length: %d", len(data))
 	}
 
 	// Согласно спецификации FLAC (https://xiph.org/flac/documentation.html), блок STREAMINFO содержит (в байтах):
@@ -111,91 +222,157 @@ func ParseFlacFile(filePath string) (*TrackFileMetadata, error) {
 	//  7:9  максимальный размер фрейма (24 бита)
 	// 10:17 sample rate, channels, bits per sample, total samples (упаковано)
 	// 18:33 MD5-подпись
-	// Мы игнорируем размеры блоков/фреймов и читаем 8 байт с offset 10.
-	packed := binary.BigEndian.Uint64(streamInfoData[10:18])
-	sampleRate := uint32((packed >> 44) & 0xFFFFF) // 20 бит на sample rate
-	channels := uint8((packed>>41)&0x7) + 1        // 3 бита + 1
-	bitsPerSample := uint8((packed>>36)&0x1F) + 1  // 5 бит + 1
-	totalSamples := packed & 0xFFFFFFFFF           // младшие 36 бит
+	// Размеры фреймов нам не нужны, а максимальный размер блока пригодится декодеру для проверки фреймов.
+	info.maxBlockSize = int(binary.BigEndian.Uint16(data[2:4]))
 
-	var md5 [16]byte
+	packed := binary.BigEndian.Uint64(data[10:18])
+	info.sampleRate = int((packed >> 44) & 0xFFFFF) // 20 бит на sample rate
+	info.channels = int((packed>>41)&0x7) + 1       // 3 бита + 1
+	info.bitsPerSample = int((packed>>36)&0x1F) + 1 // 5 бит + 1
+	info.totalSamples = packed & 0xFFFFFFFFF        // младшие 36 бит
 
-	copy(md5[:], streamInfoData[18:34])
+	copy(info.md5[:], data[18:34])
 
-	// Узнаём размер файла.
-	fi, err := f.Stat()
-	if err != nil {
-		return nil, fmt.Errorf("stat flac file: %w", err)
+	return info, nil
+}
+
+// parseFlacPicture разбирает блок PICTURE. Все числа big-endian: тип, длина и MIME, длина и описание (UTF-8),
+// ширина, высота, глубина цвета, число цветов палитры, длина и сами данные изображения.
+func parseFlacPicture(b []byte) (typedPicture, error) {
+	var picture typedPicture
+
+	if len(b) < 8 {
+		return picture, errFlacPictureInvalid
 	}
 
-	// Считаем простой битрейт. Важно: FLAC VBR кодек, поэтому эта оценка предполагает как если бы PCM без сжатия и
-	// может не совпасть с реальным закодированным битрейтом.
-	var bitrate int
+	picture.kind = binary.BigEndian.Uint32(b[0:4])
 
-	if sampleRate != 0 {
-		bitrate = int((uint64(sampleRate) * uint64(bitsPerSample) * uint64(channels)) / 1000)
+	mime, b, ok := cutFlacField(b[4:])
+	if !ok {
+		return picture, errFlacPictureInvalid
 	}
 
-	// Длительность: через float64, чтобы избежать переполнения при конвертации в time.Duration.
-	var duration time.Duration
+	description, b, ok := cutFlacField(b)
+	if !ok || len(b) < 16 {
+		return picture, errFlacPictureInvalid
+	}
 
-	if sampleRate != 0 {
-		seconds := float64(totalSamples) / float64(sampleRate)
-		duration = time.Duration(seconds * float64(time.Second))
+	// Ширину, высоту, глубину и палитру пропускаем: размеры всё равно узнаем из самого изображения.
+	data, _, ok := cutFlacField(b[16:])
+	if !ok || len(data) == 0 {
+		return picture, errFlacPictureInvalid
+	}
+
+	picture.MimeType = string(mime)
+	picture.Description = string(description)
+	picture.Data = data
+
+	// "-->" означает, что в данных ссылка на картинку, а не она сама.
+	if picture.MimeType == "-->" {
+		return picture, errFlacPictureInvalid
+	}
+
+	if picture.MimeType == "" || picture.MimeType == "image/" {
+		picture.MimeType = sniffImageMime(picture.Data)
 	}
 
-	return &TrackFileMetadata{
-		Path:          filePath,
-		Filename:      filepath.Base(filePath),
-		Format:        domain.FormatFLAC,
-		Codec:         domain.CodecFLAC,
-		SampleRate:    int(sampleRate),
-		Channels:      int(channels),
-		BitsPerSample: int(bitsPerSample),
-		Bitrate:       bitrate,
-		TotalSamples:  totalSamples,
-		Duration:      duration,
-		Size:          fi.Size(),
-		MD5Signature:  md5,
-	}, nil
+	return picture, nil
 }
 
-// WalkAndParseFlac рекурсивно обходит каталог root, проверяя каждый обычный файл и пытаясь распарсить его как FLAC.
-// Файлы с иным расширением (case-intensive) пропускаются. Ошибки парсинга отдельных файлов игнорируются, чтобы один
-// битый файл не обрывал обход целиком. Контекст позволяет досрочно отменить обход.
-func WalkAndParseFlac(ctx context.Context, root string) ([]*TrackFileMetadata, error) {
-	var results []*TrackFileMetadata
+// cutFlacField отрезает от b поле с 32-битной big-endian длиной.
+func cutFlacField(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+
+	length := binary.BigEndian.Uint32(b[:4])
+	if uint64(length) > uint64(len(b)-4) {
+		return nil, nil, false
+	}
+
+	return b[4 : 4+length], b[4+length:], true
+}
+
+// parseFlacCueSheet разбирает блок CUESHEET: номер каталога (128 байт ASCII), число сэмплов lead-in (8), флаг CD
+// и 258 зарезервированных байт, число треков (1), затем треки по 36 байт, за каждым из которых его точки индекса
+// по 12 байт. Последний трек — lead-out.
+func parseFlacCueSheet(b []byte) (*CueSheet, error) {
+	if len(b) < 396 {
+		return nil, errFlacCueSheetInvalid
+	}
 
-	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			// Если ОС вернула ошибку при чтении пути — прекращаем обход.
-			return err
+	cueSheet := &CueSheet{
+		CatalogNumber: strings.TrimRight(string(b[:128]), "\x00"),
+		LeadInSamples: binary.BigEndian.Uint64(b[128:136]),
+		IsCD:          b[136]&0x80 != 0,
+	}
+
+	count := int(b[395])
+	b = b[396:]
+
+	if count == 0 {
+		return nil, errFlacCueSheetInvalid
+	}
+
+	for i := 0; i < count; i++ {
+		if len(b) < 36 {
+			return nil, errFlacCueSheetInvalid
 		}
 
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+		track := CueTrack{
+			Offset:      binary.BigEndian.Uint64(b[0:8]),
+			Number:      int(b[8]),
+			ISRC:        strings.TrimRight(string(b[9:21]), "\x00"),
+			Audio:       b[21]&0x80 == 0,
+			PreEmphasis: b[21]&0x40 != 0,
 		}
 
-		if d.IsDir() {
-			return nil
+		indexes := int(b[35])
+		b = b[36:]
+
+		if len(b) < indexes*12 {
+			return nil, errFlacCueSheetInvalid
 		}
 
-		if ext := strings.ToLower(filepath.Ext(d.Name())); ext != ".flac" {
-			return nil
+		for j := 0; j < indexes; j++ {
+			track.Indexes = append(track.Indexes, CueIndex{
+				Offset: binary.BigEndian.Uint64(b[0:8]),
+				Number: int(b[8]),
+			})
+
+			b = b[12:]
 		}
 
-		meta, err := ParseFlacFile(path)
-		if err != nil {
-			// Пропускаем файл с ошибкой парсинга.
-			return nil
+		if i == count-1 {
+			cueSheet.LeadOut = track.Offset
+			break
 		}
 
-		results = append(results, meta)
+		cueSheet.Tracks = append(cueSheet.Tracks, track)
+	}
+
+	return cueSheet, nil
+}
+
+// WalkAndParseFlac рекурсивно обходит каталог root и разбирает все файлы с расширением .flac (регистр не важен)
+// через Scan. Ошибки парсинга отдельных файлов игнорируются, чтобы один битый файл не обрывал обход целиком;
+// результаты упорядочены по пути. Контекст позволяет досрочно отменить обход. Для импорта больших архивов лучше
+// использовать Scan напрямую: он не копит результаты в памяти и сообщает об ошибках.
+func WalkAndParseFlac(ctx context.Context, root string) ([]*TrackFileMetadata, error) {
+	var results []*TrackFileMetadata
+
+	_, err := Scan(ctx, root, ScanOptions{Extensions: []string{".flac"}}, func(result ScanResult) error {
+		// Пропускаем файл с ошибкой парсинга.
+		if result.Err == nil && result.Meta.Format == domain.FormatFLAC {
+			results = append(results, result.Meta)
+		}
 
 		return nil
 	})
 
+	slices.SortFunc(results, func(a, b *TrackFileMetadata) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+
 	return results, err
 }
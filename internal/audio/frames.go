@@ -0,0 +1,304 @@
+package audio
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+// frameReaderBuffer буфер FrameReader: с запасом больше самого длинного фрейма (ADTS до 8191 байта).
+const frameReaderBuffer = 64 << 10
+
+var adtsSampleRates = [16]int{96000, 88200, 64000, 48000, 44100, 32000, 24000, 22050, 16000, 12000, 11025, 8000, 7350, -1, -1, -1}
+
+// ElementaryStream параметры сжатого потока, общие для всех его фреймов.
+type ElementaryStream struct {
+	Codec      domain.Codec
+	SampleRate int
+	Channels   int
+	// MPEG1 для MP3: MPEG-1 (true) или MPEG-2/2.5 Layer III.
+	MPEG1 bool
+	// AudioObjectType для AAC: 2 — AAC LC, 5 — HE-AAC и т.д. (из поля profile заголовка ADTS).
+	AudioObjectType int
+}
+
+// ElementaryFrame один фрейм сжатого потока вместе с заголовком.
+type ElementaryFrame struct {
+	// Offset смещение фрейма от начала потока.
+	Offset int64
+	// Data содержимое фрейма; действительно до следующего вызова Next.
+	Data    []byte
+	Samples int
+}
+
+// elementaryHeader заголовок фрейма MP3 или ADTS, приведённый к общему виду.
+type elementaryHeader struct {
+	length  int
+	samples int
+	stream  ElementaryStream
+}
+
+// FrameReader последовательно режет поток MP3 или AAC в ADTS на фреймы, не декодируя их. ID3v2 в начале и тег
+// Xing/Info в первом фрейме MP3 пропускаются, мусор между фреймами — тоже, а обрезанный последний фрейм
+// отбрасывается.
+type FrameReader struct {
+	br     *bufio.Reader
+	codec  domain.Codec
+	stream ElementaryStream
+	offset int64
+	// pending первый фрейм, прочитанный конструктором ради параметров потока.
+	pending *ElementaryFrame
+	buffer  []byte
+}
+
+// NewFrameReader читает поток до первого фрейма. Поддерживаются кодеки MP3 и AAC (ADTS); для остальных
+// возвращается ErrUnknownFormat.
+func NewFrameReader(r io.Reader, codec domain.Codec) (*FrameReader, error) {
+	if codec != domain.CodecMP3 && codec != domain.CodecAAC {
+		return nil, fmt.Errorf("%w: no frame reader for %q", ErrUnknownFormat, codec)
+	}
+
+	fr := &FrameReader{
+		br:    bufio.NewReaderSize(r, frameReaderBuffer),
+		codec: codec,
+	}
+
+	if err := fr.skipID3v2(); err != nil {
+		return nil, err
+	}
+
+	frame, err := fr.first()
+	if err != nil {
+		return nil, err
+	}
+
+	fr.pending = frame
+
+	return fr, nil
+}
+
+// Stream параметры потока по первому фрейму.
+func (fr *FrameReader) Stream() ElementaryStream {
+	return fr.stream
+}
+
+// Next возвращает следующий фрейм или io.EOF в конце потока.
+func (fr *FrameReader) Next() (*ElementaryFrame, error) {
+	if frame := fr.pending; frame != nil {
+		fr.pending = nil
+		return frame, nil
+	}
+
+	for {
+		header, err := fr.sync()
+		if err != nil {
+			return nil, err
+		}
+
+		if header.stream != fr.stream {
+			if err = fr.discard(1); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		return fr.read(header)
+	}
+}
+
+// first ищет первый фрейм, за которым сразу идёт совместимый с ним (или конец потока), — так же, как
+// findMp3Frame, чтобы не принять случайные байты за начало звука.
+func (fr *FrameReader) first() (*ElementaryFrame, error) {
+	for {
+		header, err := fr.sync()
+		if errors.Is(err, io.EOF) {
+			return nil, errMp3FrameNotFound
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !fr.followedByCompatible(header) {
+			if err = fr.discard(1); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		fr.stream = header.stream
+
+		frame, err := fr.read(header)
+		if err != nil {
+			return nil, err
+		}
+
+		// Первый фрейм LAME-файла несёт тег Xing/Info вместо звука.
+		if mpeg, ok := parseMpegFrameHeader(frame.Data); ok && fr.codec == domain.CodecMP3 {
+			if _, err = parseMp3VBRInfo(frame.Data, mpeg); err == nil {
+				return fr.Next()
+			}
+		}
+
+		return frame, nil
+	}
+}
+
+func (fr *FrameReader) followedByCompatible(header elementaryHeader) bool {
+	b, err := fr.br.Peek(header.length + 9)
+	if len(b) <= header.length {
+		// Фрейм последний (или обрезан — тогда его отбросит read).
+		return err != nil
+	}
+
+	next, ok := fr.parseHeader(b[header.length:])
+
+	return ok && next.stream == header.stream
+}
+
+// sync пропускает байты до ближайшего заголовка фрейма.
+func (fr *FrameReader) sync() (elementaryHeader, error) {
+	for {
+		b, err := fr.br.Peek(9)
+		if len(b) < fr.headerLength() {
+			if err == nil || errors.Is(err, io.EOF) || errors.Is(err, bufio.ErrBufferFull) {
+				return elementaryHeader{}, io.EOF
+			}
+
+			return elementaryHeader{}, fmt.Errorf("reading %s stream: %w", fr.codec, err)
+		}
+
+		if header, ok := fr.parseHeader(b); ok {
+			return header, nil
+		}
+
+		if err = fr.discard(1); err != nil {
+			return elementaryHeader{}, err
+		}
+	}
+}
+
+func (fr *FrameReader) read(header elementaryHeader) (*ElementaryFrame, error) {
+	b, err := fr.br.Peek(header.length)
+	if len(b) < header.length {
+		if err == nil || errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+
+		return nil, fmt.Errorf("reading %s frame: %w", fr.codec, err)
+	}
+
+	fr.buffer = append(fr.buffer[:0], b...)
+
+	frame := &ElementaryFrame{Offset: fr.offset, Data: fr.buffer, Samples: header.samples}
+
+	if err = fr.discard(header.length); err != nil {
+		return nil, err
+	}
+
+	return frame, nil
+}
+
+func (fr *FrameReader) discard(n int) error {
+	discarded, err := fr.br.Discard(n)
+	fr.offset += int64(discarded)
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("reading %s stream: %w", fr.codec, err)
+	}
+
+	return nil
+}
+
+func (fr *FrameReader) skipID3v2() error {
+	for {
+		h, err := fr.br.Peek(10)
+		if err != nil || string(h[:3]) != "ID3" {
+			return nil
+		}
+
+		// Размер тега записан synchsafe-числом, как и в функции skipID3v2.
+		size := 10 + (int(h[6]&0x7F)<<21 | int(h[7]&0x7F)<<14 | int(h[8]&0x7F)<<7 | int(h[9]&0x7F))
+		if h[5]&0x10 != 0 { // footer present
+			size += 10
+		}
+
+		if err = fr.discard(size); err != nil {
+			return err
+		}
+	}
+}
+
+// headerLength сколько байт нужно, чтобы разобрать заголовок фрейма.
+func (fr *FrameReader) headerLength() int {
+	if fr.codec == domain.CodecAAC {
+		return 7
+	}
+
+	return 4
+}
+
+func (fr *FrameReader) parseHeader(b []byte) (elementaryHeader, bool) {
+	if fr.codec == domain.CodecAAC {
+		return parseADTSHeader(b)
+	}
+
+	mpeg, ok := parseMpegFrameHeader(b)
+	if !ok {
+		return elementaryHeader{}, false
+	}
+
+	return elementaryHeader{
+		length:  mpeg.frameLength(),
+		samples: mpeg.samplesPerFrame(),
+		stream: ElementaryStream{
+			Codec:      domain.CodecMP3,
+			SampleRate: mpeg.sampleRate,
+			Channels:   mpeg.channels(),
+			MPEG1:      mpeg.mpeg1,
+		},
+	}, true
+}
+
+// parseADTSHeader разбирает 7-байтный заголовок ADTS (ISO/IEC 13818-7, 6.2). Каждый фрейм несёт
+// number_of_raw_data_blocks + 1 блоков по 1024 сэмпла.
+func parseADTSHeader(b []byte) (elementaryHeader, bool) {
+	// Синхрослово 0xFFF и layer = 00.
+	if len(b) < 7 || b[0] != 0xFF || b[1]&0xF6 != 0xF0 {
+		return elementaryHeader{}, false
+	}
+
+	headerLength := 7
+	if b[1]&0x1 == 0 { // есть CRC
+		headerLength = 9
+	}
+
+	sampleRate := adtsSampleRates[(b[2]>>2)&0xF]
+	channels := int(b[2]&0x1)<<2 | int(b[3]>>6)
+	length := int(b[3]&0x3)<<11 | int(b[4])<<3 | int(b[5]>>5)
+
+	if sampleRate < 0 || length <= headerLength {
+		return elementaryHeader{}, false
+	}
+
+	// Нулевая конфигурация каналов означает, что раскладка описана в PCE внутри фрейма; почти всегда это стерео.
+	if channels == 0 {
+		channels = 2
+	}
+
+	return elementaryHeader{
+		length:  length,
+		samples: 1024 * (int(b[6]&0x3) + 1),
+		stream: ElementaryStream{
+			Codec:           domain.CodecAAC,
+			SampleRate:      sampleRate,
+			Channels:        channels,
+			AudioObjectType: int(b[2]>>6) + 1,
+		},
+	}, true
+}
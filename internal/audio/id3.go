@@ -0,0 +1,432 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// id3TagLimit теги ID3v2 больше этого не читаются целиком: в них почти наверняка мусор или несколько мегабайт
+// обложек, а ради названия трека держать их в памяти незачем.
+const id3TagLimit = 32 << 20
+
+var (
+	// id3TextFrames текстовые фреймы ID3v2.3/2.4 и соответствующие ключи TrackFileMetadata.Tags.
+	id3TextFrames = map[string]string{
+		"TIT2": "title",
+		"TIT3": "subtitle",
+		"TPE1": "artist",
+		"TPE2": "albumartist",
+		"TALB": "album",
+		"TRCK": "tracknumber",
+		"TPOS": "discnumber",
+		"TDRC": "date",
+		"TYER": "date",
+		"TCON": "genre",
+		"TCOM": "composer",
+		"TSRC": "isrc",
+		"TCOP": "copyright",
+		"TENC": "encodedby",
+		"TSSE": "encoder",
+	}
+
+	// id3v22TextFrames то же для ID3v2.2 с трёхбуквенными идентификаторами.
+	id3v22TextFrames = map[string]string{
+		"TT2": "title",
+		"TT3": "subtitle",
+		"TP1": "artist",
+		"TP2": "albumartist",
+		"TAL": "album",
+		"TRK": "tracknumber",
+		"TPA": "discnumber",
+		"TYE": "date",
+		"TCO": "genre",
+		"TCM": "composer",
+		"TRC": "isrc",
+		"TCR": "copyright",
+		"TEN": "encodedby",
+		"TSS": "encoder",
+	}
+
+	// id3v1Genres стандартные жанры ID3v1, на которые ссылаются числовые TCON вида "(17)".
+	id3v1Genres = []string{
+		"Blues", "Classic Rock", "Country", "Dance", "Disco", "Funk", "Grunge", "Hip-Hop", "Jazz", "Metal",
+		"New Age", "Oldies", "Other", "Pop", "R&B", "Rap", "Reggae", "Rock", "Techno", "Industrial",
+		"Alternative", "Ska", "Death Metal", "Pranks", "Soundtrack", "Euro-Techno", "Ambient", "Trip-Hop", "Vocal",
+		"Jazz+Funk", "Fusion", "Trance", "Classical", "Instrumental", "Acid", "House", "Game", "Sound Clip",
+		"Gospel", "Noise", "AlternRock", "Bass", "Soul", "Punk", "Space", "Meditative", "Instrumental Pop",
+		"Instrumental Rock", "Ethnic", "Gothic", "Darkwave", "Techno-Industrial", "Electronic", "Pop-Folk",
+		"Eurodance", "Dream", "Southern Rock", "Comedy", "Cult", "Gangsta", "Top 40", "Christian Rap", "Pop/Funk",
+		"Jungle", "Native American", "Cabaret", "New Wave", "Psychadelic", "Rave", "Showtunes", "Trailer",
+		"Lo-Fi", "Tribal", "Acid Punk", "Acid Jazz", "Polka", "Retro", "Musical", "Rock & Roll", "Hard Rock",
+	}
+)
+
+// readID3v2Tags читает теги из всех ID3v2 в начале файла (обычно он один). Битые фреймы пропускаются: теги
+// вторичны, и из-за них файл не должен становиться неразборчивым.
+func readID3v2Tags(r io.ReaderAt, size int64, meta *TrackFileMetadata) error {
+	coverType := -1
+
+	for offset := int64(0); offset+10 <= size; {
+		var h [10]byte
+		if _, err := r.ReadAt(h[:], offset); err != nil {
+			return fmt.Errorf("reading id3v2 header: %w", err)
+		}
+
+		if string(h[:3]) != "ID3" {
+			return nil
+		}
+
+		version, flags := h[3], h[5]
+		tagSize := int64(h[6]&0x7F)<<21 | int64(h[7]&0x7F)<<14 | int64(h[8]&0x7F)<<7 | int64(h[9]&0x7F)
+
+		next := offset + 10 + tagSize
+		if flags&0x10 != 0 {
+			next += 10
+		}
+
+		if version >= 2 && version <= 4 && tagSize <= id3TagLimit {
+			body, err := readAtMost(r, make([]byte, min(tagSize, size-offset-10)), offset+10)
+			if err != nil {
+				return fmt.Errorf("reading id3v2 tag: %w", err)
+			}
+
+			// В 2.2 и 2.3 unsynchronisation применяется ко всему тегу, в 2.4 — к отдельным фреймам.
+			if flags&0x80 != 0 && version < 4 {
+				body = removeUnsynchronisation(body)
+			}
+
+			parseID3v2Frames(body, version, flags, meta, &coverType)
+		}
+
+		offset = next
+	}
+
+	return nil
+}
+
+func parseID3v2Frames(body []byte, version, flags byte, meta *TrackFileMetadata, coverType *int) {
+	// Расширенный заголовок: в 2.3 размер без учёта самого поля размера, в 2.4 — synchsafe и с ним.
+	if flags&0x40 != 0 && version >= 3 && len(body) >= 4 {
+		extended := int(binary.BigEndian.Uint32(body[:4])) + 4
+		if version == 4 {
+			extended = synchsafe(body[:4])
+		}
+
+		body = body[min(extended, len(body)):]
+	}
+
+	headerLen, idLen := 10, 4
+	if version == 2 {
+		headerLen, idLen = 6, 3
+	}
+
+	for len(body) >= headerLen && body[0] != 0 {
+		id := string(body[:idLen])
+
+		var (
+			frameSize   int
+			frameFlags  uint16
+			frameOffset = headerLen
+		)
+
+		switch version {
+		case 2:
+			frameSize = int(body[3])<<16 | int(body[4])<<8 | int(body[5])
+		case 3:
+			frameSize = int(binary.BigEndian.Uint32(body[4:8]))
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		default:
+			frameSize = synchsafe(body[4:8])
+			frameFlags = binary.BigEndian.Uint16(body[8:10])
+		}
+
+		if frameSize < 0 || frameSize > len(body)-headerLen {
+			return
+		}
+
+		data := body[frameOffset : frameOffset+frameSize]
+		body = body[frameOffset+frameSize:]
+
+		data, ok := id3FrameData(data, version, flags, frameFlags)
+		if !ok || len(data) == 0 {
+			continue
+		}
+
+		applyID3v2Frame(id, data, version, meta, coverType)
+	}
+}
+
+// id3FrameData снимает с данных фрейма служебные поля из флагов формата. Сжатые и зашифрованные фреймы не
+// поддерживаются.
+func id3FrameData(data []byte, version, tagFlags byte, flags uint16) ([]byte, bool) {
+	switch version {
+	case 3:
+		if flags&0x00C0 != 0 { // compression, encryption
+			return nil, false
+		}
+
+		if flags&0x0020 != 0 && len(data) > 0 { // grouping identity
+			data = data[1:]
+		}
+	case 4:
+		if flags&0x000C != 0 { // compression, encryption
+			return nil, false
+		}
+
+		if flags&0x0040 != 0 && len(data) > 0 { // grouping identity
+			data = data[1:]
+		}
+
+		if flags&0x0001 != 0 && len(data) >= 4 { // data length indicator
+			data = data[4:]
+		}
+
+		if flags&0x0002 != 0 || tagFlags&0x80 != 0 {
+			data = removeUnsynchronisation(data)
+		}
+	}
+
+	return data, true
+}
+
+func applyID3v2Frame(id string, data []byte, version byte, meta *TrackFileMetadata, coverType *int) {
+	key := id3TextFrames[id]
+	if version == 2 {
+		key = id3v22TextFrames[id]
+	}
+
+	switch {
+	case key != "":
+		for _, value := range decodeID3Strings(data[0], data[1:]) {
+			if key == "genre" {
+				value = id3Genre(value)
+			}
+
+			if value != "" {
+				appendTag(meta, key, value)
+			}
+		}
+	case id == "TXXX" || id == "TXX":
+		// Пользовательский текст: описание и значение, например REPLAYGAIN_TRACK_GAIN.
+		values := decodeID3Strings(data[0], data[1:])
+		if len(values) >= 2 && values[0] != "" && values[1] != "" {
+			setTag(meta, strings.ToLower(values[0]), values[1])
+		}
+	case id == "COMM" || id == "COM":
+		// Язык (3 байта), короткое описание и сам текст.
+		if len(data) > 4 {
+			values := decodeID3Strings(data[0], data[4:])
+			if len(values) >= 2 && values[0] == "" && values[1] != "" {
+				setTag(meta, "comment", values[1])
+			}
+		}
+	case id == "APIC" || id == "PIC":
+		if picture, ok := parseID3Picture(data, version); ok {
+			setCover(meta, coverType, picture)
+		}
+	}
+}
+
+// parseID3Picture разбирает APIC (кодировка, MIME до нуля, тип, описание, данные) или PIC из ID3v2.2, где вместо
+// MIME три буквы формата.
+func parseID3Picture(data []byte, version byte) (typedPicture, bool) {
+	var picture typedPicture
+
+	if len(data) < 2 {
+		return picture, false
+	}
+
+	encoding := data[0]
+	data = data[1:]
+
+	if version == 2 {
+		if len(data) < 4 {
+			return picture, false
+		}
+
+		picture.MimeType = "image/" + strings.ToLower(string(data[:3]))
+		data = data[3:]
+	} else {
+		mime, rest, ok := bytes.Cut(data, []byte{0})
+		if !ok {
+			return picture, false
+		}
+
+		picture.MimeType = string(mime)
+		data = rest
+	}
+
+	if len(data) < 1 {
+		return picture, false
+	}
+
+	picture.kind = uint32(data[0])
+
+	description, image := cutID3String(encoding, data[1:])
+	picture.Description = description
+	picture.Data = bytes.Clone(image)
+
+	if len(picture.Data) == 0 {
+		return picture, false
+	}
+
+	if picture.MimeType == "" || !strings.Contains(picture.MimeType, "/") || picture.MimeType == "image/jpg" {
+		picture.MimeType = sniffImageMime(picture.Data)
+	}
+
+	return picture, true
+}
+
+// decodeID3Strings декодирует текст фрейма в заданной кодировке; в ID3v2.4 значения разделяются нулём.
+func decodeID3Strings(encoding byte, data []byte) []string {
+	var values []string
+
+	for len(data) > 0 {
+		var value string
+
+		value, data = cutID3String(encoding, data)
+		values = append(values, strings.TrimSpace(value))
+	}
+
+	return values
+}
+
+// cutID3String отрезает от data одну строку до терминатора (один ноль для однобайтовых кодировок, два для UTF-16).
+func cutID3String(encoding byte, data []byte) (string, []byte) {
+	switch encoding {
+	case 1, 2: // UTF-16 с BOM, UTF-16BE
+		end := len(data)
+
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0 && data[i+1] == 0 {
+				end = i
+
+				break
+			}
+		}
+
+		return decodeUTF16(data[:end], encoding == 2), data[min(end+2, len(data)):]
+	default: // ISO-8859-1, UTF-8
+		value, rest, _ := bytes.Cut(data, []byte{0})
+		if encoding == 0 {
+			return decodeLatin1(value), rest
+		}
+
+		return string(value), rest
+	}
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	if len(b) >= 2 {
+		switch {
+		case b[0] == 0xFF && b[1] == 0xFE:
+			b, bigEndian = b[2:], false
+		case b[0] == 0xFE && b[1] == 0xFF:
+			b, bigEndian = b[2:], true
+		}
+	}
+
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = binary.BigEndian.Uint16(b[2*i:])
+		} else {
+			units[i] = binary.LittleEndian.Uint16(b[2*i:])
+		}
+	}
+
+	return string(utf16.Decode(units))
+}
+
+func decodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+
+	return string(runes)
+}
+
+// id3Genre раскрывает ссылки на жанры ID3v1: "17", "(17)" и "(17)Rock" превращаются в "Rock".
+func id3Genre(value string) string {
+	code := value
+
+	if strings.HasPrefix(value, "(") {
+		end := strings.IndexByte(value, ')')
+		if end < 0 {
+			return value
+		}
+
+		if refined := strings.TrimSpace(value[end+1:]); refined != "" {
+			return refined
+		}
+
+		code = value[1:end]
+	}
+
+	if n, err := strconv.Atoi(code); err == nil {
+		if n >= 0 && n < len(id3v1Genres) {
+			return id3v1Genres[n]
+		}
+
+		return ""
+	}
+
+	return value
+}
+
+// readID3v1Tags читает 128-байтный ID3v1 в конце файла. Его значения не перезаписывают найденные в ID3v2.
+func readID3v1Tags(r io.ReaderAt, size int64, meta *TrackFileMetadata) error {
+	if size < 128 {
+		return nil
+	}
+
+	var tag [128]byte
+	if _, err := r.ReadAt(tag[:], size-128); err != nil {
+		return fmt.Errorf("reading id3v1 tag: %w", err)
+	}
+
+	if string(tag[:3]) != "TAG" {
+		return nil
+	}
+
+	field := func(b []byte) string {
+		return strings.TrimSpace(decodeLatin1(bytes.TrimRight(b, "\x00 ")))
+	}
+
+	for key, value := range map[string]string{
+		"title":  field(tag[3:33]),
+		"artist": field(tag[33:63]),
+		"album":  field(tag[63:93]),
+		"date":   field(tag[93:97]),
+	} {
+		if value != "" {
+			setTag(meta, key, value)
+		}
+	}
+
+	// ID3v1.1: если предпоследний байт комментария нулевой, последний — номер трека.
+	if tag[125] == 0 && tag[126] != 0 {
+		setTag(meta, "tracknumber", strconv.Itoa(int(tag[126])))
+	}
+
+	if int(tag[127]) < len(id3v1Genres) {
+		setTag(meta, "genre", id3v1Genres[tag[127]])
+	}
+
+	return nil
+}
+
+func synchsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
+
+// removeUnsynchronisation убирает нули, вставленные после 0xFF, чтобы тег не содержал ложных синхрослов MPEG.
+func removeUnsynchronisation(b []byte) []byte {
+	return bytes.ReplaceAll(b, []byte{0xFF, 0x00}, []byte{0xFF})
+}
@@ -0,0 +1,553 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// ReplayGainReference целевая громкость ReplayGain 2.0, LUFS.
+	ReplayGainReference = -18.0
+
+	// loudnessAbsoluteGate блоки тише этого порога (LUFS) не учитываются вовсе.
+	loudnessAbsoluteGate = -70.0
+	// loudnessRelativeGate порог интегральной громкости относительно средней громкости блоков, LU.
+	loudnessRelativeGate = -10.0
+	// loudnessRangeGate относительный порог для диапазона громкости (EBU Tech 3342), LU.
+	loudnessRangeGate = -20.0
+
+	// Гистограммы громкости блоков: от loudnessAbsoluteGate до loudnessHistogramMax с шагом 0.1 LU.
+	loudnessHistogramMax  = 10.0
+	loudnessHistogramStep = 0.1
+	loudnessHistogramBins = int((loudnessHistogramMax - loudnessAbsoluteGate) / loudnessHistogramStep)
+
+	// Блоки считаются по 100-мс подблокам: momentary — 400 мс, short-term — 3 с.
+	momentarySubBlocks = 4
+	shortTermSubBlocks = 30
+
+	// truePeakTaps длина фазы интерполирующего фильтра при передискретизации для true peak.
+	truePeakTaps = 12
+
+	loudnessHistogramMagic   = "BBLH"
+	loudnessHistogramVersion = 1
+)
+
+var errLoudnessHistogramInvalid = errors.New("invalid loudness histogram")
+
+// Loudness результат анализа по ITU-R BS.1770-4 и EBU R128.
+type Loudness struct {
+	// Integrated интегральная громкость, LUFS. -Inf, если весь файл тише абсолютного порога (или короче 400 мс).
+	Integrated float64
+	// Range диапазон громкости (LRA), LU.
+	Range float64
+	// TruePeak истинный пик после передискретизации, в долях полной шкалы (1.0 — 0 dBTP).
+	TruePeak float64
+	// SamplePeak максимальный модуль сэмпла, в долях полной шкалы.
+	SamplePeak float64
+	// Histogram гистограммы громкости блоков: по ним считается громкость альбома из нескольких файлов.
+	Histogram *LoudnessHistogram
+}
+
+// TruePeakDB истинный пик в dBTP.
+func (l *Loudness) TruePeakDB() float64 {
+	return 20 * math.Log10(l.TruePeak)
+}
+
+// ReplayGain усиление ReplayGain 2.0 в дБ для интегральной громкости integrated.
+func ReplayGain(integrated float64) float64 {
+	return ReplayGainReference - integrated
+}
+
+// LoudnessHistogram число 400-мс (momentary) и 3-с (short-term) блоков в каждом интервале громкости шириной
+// 0.1 LU. Гистограммы нескольких файлов складываются, что даёт громкость альбома так, как если бы его файлы
+// измерили подряд одним проходом (с точностью до ширины интервала).
+type LoudnessHistogram struct {
+	Momentary [loudnessHistogramBins]uint32
+	ShortTerm [loudnessHistogramBins]uint32
+}
+
+// Merge прибавляет к гистограмме другую.
+func (h *LoudnessHistogram) Merge(other *LoudnessHistogram) {
+	for i := range h.Momentary {
+		h.Momentary[i] += other.Momentary[i]
+		h.ShortTerm[i] += other.ShortTerm[i]
+	}
+}
+
+// Integrated интегральная громкость по momentary-блокам с абсолютным и относительным порогом, LUFS.
+func (h *LoudnessHistogram) Integrated() float64 {
+	threshold, ok := relativeThreshold(&h.Momentary, loudnessRelativeGate)
+	if !ok {
+		return math.Inf(-1)
+	}
+
+	var (
+		energy float64
+		count  uint64
+	)
+
+	for i := binOf(threshold); i < loudnessHistogramBins; i++ {
+		energy += float64(h.Momentary[i]) * binEnergy(i)
+		count += uint64(h.Momentary[i])
+	}
+
+	if count == 0 {
+		return math.Inf(-1)
+	}
+
+	return energyToLoudness(energy / float64(count))
+}
+
+// Range диапазон громкости по EBU Tech 3342: разница 95-го и 10-го процентилей громкости short-term блоков,
+// прошедших абсолютный и относительный (-20 LU) порог.
+func (h *LoudnessHistogram) Range() float64 {
+	threshold, ok := relativeThreshold(&h.ShortTerm, loudnessRangeGate)
+	if !ok {
+		return 0
+	}
+
+	from := binOf(threshold)
+
+	var count uint64
+	for i := from; i < loudnessHistogramBins; i++ {
+		count += uint64(h.ShortTerm[i])
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	low, high := percentileBin(&h.ShortTerm, from, count, 0.10), percentileBin(&h.ShortTerm, from, count, 0.95)
+
+	return float64(high-low) * loudnessHistogramStep
+}
+
+// relativeThreshold средняя громкость всех блоков гистограммы плюс gate.
+func relativeThreshold(bins *[loudnessHistogramBins]uint32, gate float64) (float64, bool) {
+	var (
+		energy float64
+		count  uint64
+	)
+
+	for i, n := range bins {
+		energy += float64(n) * binEnergy(i)
+		count += uint64(n)
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+
+	return energyToLoudness(energy/float64(count)) + gate, true
+}
+
+// percentileBin номер интервала, в который попадает p-й процентиль блоков начиная с интервала from.
+func percentileBin(bins *[loudnessHistogramBins]uint32, from int, count uint64, p float64) int {
+	target := uint64(math.Ceil(p * float64(count)))
+
+	var seen uint64
+
+	for i := from; i < loudnessHistogramBins; i++ {
+		seen += uint64(bins[i])
+		if seen >= max(target, 1) {
+			return i
+		}
+	}
+
+	return loudnessHistogramBins - 1
+}
+
+// binOf интервал, в который попадает громкость; интервалы ниже шкалы прижимаются к первому.
+func binOf(loudness float64) int {
+	bin := int(math.Floor((loudness - loudnessAbsoluteGate) / loudnessHistogramStep))
+
+	return max(0, min(bin, loudnessHistogramBins-1))
+}
+
+// binEnergy средняя энергия интервала: по его середине.
+func binEnergy(bin int) float64 {
+	return loudnessToEnergy(loudnessAbsoluteGate + (float64(bin)+0.5)*loudnessHistogramStep)
+}
+
+func energyToLoudness(energy float64) float64 {
+	return -0.691 + 10*math.Log10(energy)
+}
+
+func loudnessToEnergy(loudness float64) float64 {
+	return math.Pow(10, (loudness+0.691)/10)
+}
+
+// MarshalBinary кодирует гистограммы разреженно: сигнатура "BBLH", версия и для каждой гистограммы число
+// непустых интервалов и пары (номер, счётчик), все числа little-endian.
+func (h *LoudnessHistogram) MarshalBinary() ([]byte, error) {
+	b := append([]byte(loudnessHistogramMagic), loudnessHistogramVersion)
+
+	for _, bins := range []*[loudnessHistogramBins]uint32{&h.Momentary, &h.ShortTerm} {
+		var used uint16
+		for _, n := range bins {
+			if n > 0 {
+				used++
+			}
+		}
+
+		b = binary.LittleEndian.AppendUint16(b, used)
+
+		for i, n := range bins {
+			if n > 0 {
+				b = binary.LittleEndian.AppendUint16(b, uint16(i))
+				b = binary.LittleEndian.AppendUint32(b, n)
+			}
+		}
+	}
+
+	return b, nil
+}
+
+func (h *LoudnessHistogram) UnmarshalBinary(b []byte) error {
+	if len(b) < 5 || string(b[:4]) != loudnessHistogramMagic {
+		return errLoudnessHistogramInvalid
+	}
+
+	if b[4] != loudnessHistogramVersion {
+		return fmt.Errorf("%w: version %d", errLoudnessHistogramInvalid, b[4])
+	}
+
+	b = b[5:]
+	*h = LoudnessHistogram{}
+
+	for _, bins := range []*[loudnessHistogramBins]uint32{&h.Momentary, &h.ShortTerm} {
+		if len(b) < 2 {
+			return errLoudnessHistogramInvalid
+		}
+
+		used := int(binary.LittleEndian.Uint16(b))
+		b = b[2:]
+
+		if len(b)/6 < used {
+			return errLoudnessHistogramInvalid
+		}
+
+		for range used {
+			bin := int(binary.LittleEndian.Uint16(b))
+			if bin >= loudnessHistogramBins {
+				return errLoudnessHistogramInvalid
+			}
+
+			bins[bin] = binary.LittleEndian.Uint32(b[2:])
+			b = b[6:]
+		}
+	}
+
+	return nil
+}
+
+// AnalyzeLoudness декодирует поток до конца и измеряет его громкость.
+func AnalyzeLoudness(decoder PCMDecoder) (*Loudness, error) {
+	meter := NewLoudnessMeter(decoder.SampleRate(), decoder.Channels(), decoder.BitsPerSample())
+
+	for {
+		frame, err := decoder.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			return meter.Result(), nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		meter.Write(frame)
+	}
+}
+
+// LoudnessMeter потоковый измеритель громкости по ITU-R BS.1770-4: K-взвешивание, энергия 400-мс и 3-с блоков
+// с шагом 100 мс и true peak с передискретизацией.
+type LoudnessMeter struct {
+	scale   float64
+	weights []float64
+	filters []kWeighting
+	peaks   []truePeakMeter
+
+	// subBlock размер подблока в сэмплах, filled — сколько уже в текущем.
+	subBlock, filled int
+	// energy энергия текущего подблока по каналам.
+	energy []float64
+	// history энергия последних shortTermSubBlocks подблоков (уже взвешенная по каналам), кольцом.
+	history []float64
+	// subBlocks сколько подблоков завершено.
+	subBlocks int
+
+	samplePeak float64
+	histogram  LoudnessHistogram
+}
+
+// NewLoudnessMeter готовит измеритель для PCM с заданными параметрами. Порядок каналов — как в WAV/FLAC:
+// L, R, C, LFE, Ls, Rs; LFE не учитывается, тыловые каналы весят 1.41.
+func NewLoudnessMeter(sampleRate, channels, bitsPerSample int) *LoudnessMeter {
+	m := &LoudnessMeter{
+		scale:    1 / float64(int64(1)<<(bitsPerSample-1)),
+		weights:  channelWeights(channels),
+		filters:  make([]kWeighting, channels),
+		peaks:    make([]truePeakMeter, channels),
+		subBlock: max(1, int(math.Round(float64(sampleRate)/10))),
+		energy:   make([]float64, channels),
+		history:  make([]float64, shortTermSubBlocks),
+	}
+
+	filter := newKWeighting(float64(sampleRate))
+	phases := truePeakFilter(truePeakFactor(sampleRate))
+
+	for c := range channels {
+		m.filters[c] = filter
+		m.peaks[c] = newTruePeakMeter(phases)
+	}
+
+	return m
+}
+
+func channelWeights(channels int) []float64 {
+	weights := make([]float64, channels)
+
+	for c := range weights {
+		weights[c] = 1
+	}
+
+	switch {
+	case channels == 5:
+		weights[3], weights[4] = 1.41, 1.41
+	case channels >= 6:
+		weights[3], weights[4], weights[5] = 0, 1.41, 1.41
+	}
+
+	return weights
+}
+
+// Write добавляет сэмплы по каналам; число каналов должно совпадать с заданным при создании.
+func (m *LoudnessMeter) Write(frame [][]int32) {
+	if len(frame) != len(m.filters) || len(frame) == 0 {
+		return
+	}
+
+	for i := range frame[0] {
+		for c, channel := range frame {
+			x := float64(channel[i]) * m.scale
+
+			m.samplePeak = max(m.samplePeak, math.Abs(x))
+			m.peaks[c].write(x)
+
+			y := m.filters[c].process(x)
+			m.energy[c] += y * y
+		}
+
+		if m.filled++; m.filled == m.subBlock {
+			m.finishSubBlock()
+		}
+	}
+}
+
+// finishSubBlock закрывает 100-мс подблок и, когда набралось достаточно, учитывает заканчивающиеся на нём
+// momentary и short-term блоки.
+func (m *LoudnessMeter) finishSubBlock() {
+	var energy float64
+
+	for c := range m.energy {
+		energy += m.weights[c] * m.energy[c]
+		m.energy[c] = 0
+	}
+
+	m.history[m.subBlocks%shortTermSubBlocks] = energy / float64(m.subBlock)
+	m.subBlocks++
+	m.filled = 0
+
+	if m.subBlocks >= momentarySubBlocks {
+		m.addBlock(&m.histogram.Momentary, momentarySubBlocks)
+	}
+
+	if m.subBlocks >= shortTermSubBlocks {
+		m.addBlock(&m.histogram.ShortTerm, shortTermSubBlocks)
+	}
+}
+
+func (m *LoudnessMeter) addBlock(bins *[loudnessHistogramBins]uint32, subBlocks int) {
+	var energy float64
+
+	for i := range subBlocks {
+		energy += m.history[(m.subBlocks-1-i)%shortTermSubBlocks]
+	}
+
+	loudness := energyToLoudness(energy / float64(subBlocks))
+	if loudness < loudnessAbsoluteGate || math.IsNaN(loudness) {
+		return
+	}
+
+	bins[binOf(loudness)]++
+}
+
+// Result итог по всему, что записано. Неполный последний подблок не учитывается, как и предписывает BS.1770.
+func (m *LoudnessMeter) Result() *Loudness {
+	histogram := m.histogram
+
+	truePeak := m.samplePeak
+	for c := range m.peaks {
+		truePeak = max(truePeak, m.peaks[c].peak)
+	}
+
+	return &Loudness{
+		Integrated: histogram.Integrated(),
+		Range:      histogram.Range(),
+		TruePeak:   truePeak,
+		SamplePeak: m.samplePeak,
+		Histogram:  &histogram,
+	}
+}
+
+// biquad фильтр второго порядка в транспонированной прямой форме II.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+
+	return y
+}
+
+// kWeighting K-фильтр BS.1770: полка, моделирующая голову, и высокочастотный RLB-фильтр. Коэффициенты
+// пересчитываются из аналоговых прототипов под любую частоту дискретизации (как в libebur128).
+type kWeighting struct {
+	shelf, highPass biquad
+}
+
+func newKWeighting(sampleRate float64) kWeighting {
+	const (
+		shelfFrequency = 1681.974450955533
+		shelfGain      = 3.999843853973347
+		shelfQ         = 0.7071752369554196
+
+		highPassFrequency = 38.13547087602444
+		highPassQ         = 0.5003270373238773
+	)
+
+	k := math.Tan(math.Pi * shelfFrequency / sampleRate)
+	vh := math.Pow(10, shelfGain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+	}
+
+	k = math.Tan(math.Pi * highPassFrequency / sampleRate)
+	a0 = 1 + k/highPassQ + k*k
+
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/highPassQ + k*k) / a0,
+	}
+
+	return kWeighting{shelf: shelf, highPass: highPass}
+}
+
+func (f *kWeighting) process(x float64) float64 {
+	return f.highPass.process(f.shelf.process(x))
+}
+
+// truePeakFactor во сколько раз передискретизировать, чтобы получить хотя бы 192 кГц (BS.1770-4, приложение 2).
+func truePeakFactor(sampleRate int) int {
+	switch {
+	case sampleRate < 96000:
+		return 4
+	case sampleRate < 192000:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// truePeakFilter интерполирующий ФНЧ для передискретизации в factor раз: sinc с окном Блэкмана, по
+// truePeakTaps отсчётов на фазу. Каждая фаза нормирована на единичное усиление.
+func truePeakFilter(factor int) [][]float64 {
+	if factor == 1 {
+		return [][]float64{{1}}
+	}
+
+	size := truePeakTaps * factor
+	center := float64(size-1) / 2
+
+	phases := make([][]float64, factor)
+	for p := range phases {
+		phases[p] = make([]float64, truePeakTaps)
+	}
+
+	for n := range size {
+		t := (float64(n) - center) / float64(factor)
+
+		sinc := 1.0
+		if t != 0 {
+			sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+		}
+
+		x := 2 * math.Pi * float64(n) / float64(size-1)
+		window := 0.42 - 0.5*math.Cos(x) + 0.08*math.Cos(2*x)
+
+		phases[n%factor][n/factor] = sinc * window
+	}
+
+	for _, taps := range phases {
+		var sum float64
+		for _, tap := range taps {
+			sum += tap
+		}
+
+		for i := range taps {
+			taps[i] /= sum
+		}
+	}
+
+	return phases
+}
+
+// truePeakMeter пик одного канала после передискретизации.
+type truePeakMeter struct {
+	phases [][]float64
+	// history последние входные сэмплы, записанные дважды, чтобы окно всегда было непрерывным: самый свежий
+	// сэмпл лежит в history[pos], k шагов назад — в history[pos+k].
+	history []float64
+	pos     int
+	peak    float64
+}
+
+func newTruePeakMeter(phases [][]float64) truePeakMeter {
+	return truePeakMeter{phases: phases, history: make([]float64, 2*len(phases[0]))}
+}
+
+func (m *truePeakMeter) write(x float64) {
+	size := len(m.history) / 2
+
+	m.pos = (m.pos + size - 1) % size
+	m.history[m.pos], m.history[m.pos+size] = x, x
+
+	window := m.history[m.pos : m.pos+size]
+
+	for _, taps := range m.phases {
+		var y float64
+
+		for k, tap := range taps {
+			y += tap * window[k]
+		}
+
+		m.peak = max(m.peak, math.Abs(y))
+	}
+}
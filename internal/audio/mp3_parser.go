@@ -0,0 +1,469 @@
+package audio
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+// mp3SyncWindow сколько байт после ID3v2 просматривается в поисках первого фрейма. Встречаются файлы с мусором
+// или нулевым заполнением между тегом и звуком, но дальше мегабайта это уже не MP3.
+const mp3SyncWindow = 1 << 20
+
+// lameDecoderDelay задержка декодера MPEG Layer III в сэмплах, которую LAME учитывает в поле encoder delay.
+const lameDecoderDelay = 528 + 1
+
+var (
+	mpeg1Layer3Bitrates  = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, -1}
+	mpeg2Layer3Bitrates  = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, -1}
+	mpeg1SampleRates     = [4]int{44100, 48000, 32000, -1}
+	mpeg2SampleRates     = [4]int{22050, 24000, 16000, -1}
+	mpeg25SampleRates    = [4]int{11025, 12000, 8000, -1}
+	errMp3FrameNotFound  = errors.New("mpeg audio frame not found")
+	errMp3InvalidXingTag = errors.New("invalid xing header")
+)
+
+// mpegFrameHeader разобранный 4-байтный заголовок фрейма MPEG-1/2/2.5 Layer III.
+type mpegFrameHeader struct {
+	// mpeg1 true для MPEG-1, false для MPEG-2 и MPEG-2.5 (у них одинаковые размеры фреймов и side info).
+	mpeg1      bool
+	bitrate    int // кбит/с
+	sampleRate int
+	padding    bool
+	mono       bool
+}
+
+// parseMpegFrameHeader проверяет синхрослово и поля заголовка. Free format (bitrate index 0), зарезервированные
+// значения и слои, отличные от Layer III, считаются невалидными.
+func parseMpegFrameHeader(b []byte) (mpegFrameHeader, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mpegFrameHeader{}, false
+	}
+
+	version := (b[1] >> 3) & 0x3
+	layer := (b[1] >> 1) & 0x3
+	bitrateIndex := b[2] >> 4
+	sampleRateIndex := (b[2] >> 2) & 0x3
+
+	if version == 1 || layer != 1 || bitrateIndex == 0 {
+		return mpegFrameHeader{}, false
+	}
+
+	var h mpegFrameHeader
+
+	switch version {
+	case 3:
+		h.mpeg1 = true
+		h.bitrate = mpeg1Layer3Bitrates[bitrateIndex]
+		h.sampleRate = mpeg1SampleRates[sampleRateIndex]
+	case 2:
+		h.bitrate = mpeg2Layer3Bitrates[bitrateIndex]
+		h.sampleRate = mpeg2SampleRates[sampleRateIndex]
+	default:
+		h.bitrate = mpeg2Layer3Bitrates[bitrateIndex]
+		h.sampleRate = mpeg25SampleRates[sampleRateIndex]
+	}
+
+	if h.bitrate < 0 || h.sampleRate < 0 {
+		return mpegFrameHeader{}, false
+	}
+
+	h.padding = b[2]&0x2 != 0
+	h.mono = b[3]>>6 == 3
+
+	return h, true
+}
+
+func (h mpegFrameHeader) samplesPerFrame() int {
+	if h.mpeg1 {
+		return 1152
+	}
+
+	return 576
+}
+
+func (h mpegFrameHeader) frameLength() int {
+	length := h.samplesPerFrame() / 8 * h.bitrate * 1000 / h.sampleRate
+	if h.padding {
+		length++
+	}
+
+	return length
+}
+
+func (h mpegFrameHeader) channels() int {
+	if h.mono {
+		return 1
+	}
+
+	return 2
+}
+
+// sideInfoLength размер side info после заголовка; сразу за ним в первом фрейме лежит тег Xing/Info.
+func (h mpegFrameHeader) sideInfoLength() int {
+	switch {
+	case h.mpeg1 && !h.mono:
+		return 32
+	case h.mpeg1, !h.mono:
+		return 17
+	default:
+		return 9
+	}
+}
+
+// compatible фреймы одного потока обязаны совпадать по версии, частоте и числу каналов. Используется, чтобы не
+// принять случайные байты 0xFFEx внутри тега или картинки за начало звука.
+func (h mpegFrameHeader) compatible(other mpegFrameHeader) bool {
+	return h.mpeg1 == other.mpeg1 && h.sampleRate == other.sampleRate && h.mono == other.mono
+}
+
+// mp3VBRInfo данные из тега Xing/Info (LAME) либо VBRI (Fraunhofer) в первом фрейме.
+type mp3VBRInfo struct {
+	frames uint32
+	bytes  uint32
+	// delay и padding сэмплы, добавленные энкодером в начало и конец (из расширения LAME).
+	delay   int
+	padding int
+}
+
+// ParseMp3File разбирает MP3-файл: пропускает ID3v2 (а в конце ID3v1 и APEv2), находит первый фрейм и, если в нём
+// есть тег Xing/Info или VBRI, берёт число фреймов оттуда. Иначе обходит все заголовки фреймов, так что и
+// длительность, и средний битрейт VBR-файлов без тега тоже получаются точными. Теги и обложка читаются из ID3v2,
+// а чего там нет — из ID3v1.
+func ParseMp3File(filePath string) (*TrackFileMetadata, error) {
+	return parseFile(filePath, "mp3", parseMp3)
+}
+
+func parseMp3(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	start, err := skipID3v2(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if start >= size {
+		return nil, errMp3FrameNotFound
+	}
+
+	end, err := mp3AudioEnd(r, start, size)
+	if err != nil {
+		return nil, err
+	}
+
+	first, header, err := findMp3Frame(r, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]byte, min(int64(header.frameLength()), end-first))
+	if _, err = r.ReadAt(frame, first); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("reading first mpeg frame: %w", err)
+	}
+
+	spf := uint64(header.samplesPerFrame())
+
+	var (
+		totalSamples uint64
+		audioBytes   int64
+	)
+
+	vbr, err := parseMp3VBRInfo(frame, header)
+	if err == nil && vbr.frames > 0 {
+		totalSamples = uint64(vbr.frames) * spf
+
+		// Тег дополнительно хранит, сколько тишины энкодер дописал по краям; без неё длительность совпадает
+		// с тем, что реально проиграет плеер с поддержкой gapless.
+		if trim := uint64(vbr.delay + vbr.padding); trim < totalSamples {
+			totalSamples -= trim
+		}
+
+		audioBytes = int64(vbr.bytes)
+		if audioBytes == 0 || audioBytes > end-first {
+			audioBytes = end - first
+		}
+	} else {
+		// Фрейм с тегом не содержит звука, даже если сам тег пустой или битый.
+		if !errors.Is(err, io.EOF) {
+			first += int64(len(frame))
+		}
+
+		frames, walked, err := walkMp3Frames(r, first, end, header)
+		if err != nil {
+			return nil, err
+		}
+
+		totalSamples = frames * spf
+		audioBytes = walked
+	}
+
+	seconds := float64(totalSamples) / float64(header.sampleRate)
+
+	bitrate := header.bitrate
+	if seconds > 0 {
+		bitrate = int(float64(audioBytes) * 8 / seconds / 1000)
+	}
+
+	meta := &TrackFileMetadata{
+		Format:       domain.FormatMP3,
+		Codec:        domain.CodecMP3,
+		Mime:         MimeMP3,
+		SampleRate:   header.sampleRate,
+		Channels:     header.channels(),
+		Bitrate:      bitrate,
+		TotalSamples: totalSamples,
+		Duration:     time.Duration(seconds * float64(time.Second)),
+		Size:         size,
+	}
+
+	if err = readID3v2Tags(r, start, meta); err != nil {
+		return nil, err
+	}
+
+	if err = readID3v1Tags(r, size, meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// skipID3v2 возвращает смещение сразу после всех ID3v2-тегов в начале файла (их бывает несколько подряд).
+func skipID3v2(r io.ReaderAt, size int64) (int64, error) {
+	var offset int64
+
+	for offset+10 <= size {
+		var h [10]byte
+		if _, err := r.ReadAt(h[:], offset); err != nil {
+			return 0, fmt.Errorf("reading id3v2 header: %w", err)
+		}
+
+		if string(h[:3]) != "ID3" {
+			break
+		}
+
+		// Размер тега записан synchsafe-числом: по 7 значащих бит в каждом из 4 байт.
+		tagSize := int64(h[6]&0x7F)<<21 | int64(h[7]&0x7F)<<14 | int64(h[8]&0x7F)<<7 | int64(h[9]&0x7F)
+
+		offset += 10 + tagSize
+		if h[5]&0x10 != 0 { // footer present
+			offset += 10
+		}
+	}
+
+	return offset, nil
+}
+
+// mp3AudioEnd отрезает с конца теги ID3v1 ("TAG", 128 байт) и APEv2, чтобы они не считались звуком.
+func mp3AudioEnd(r io.ReaderAt, start, size int64) (int64, error) {
+	end := size
+
+	if end-start >= 128 {
+		var tag [3]byte
+		if _, err := r.ReadAt(tag[:], end-128); err != nil {
+			return 0, fmt.Errorf("reading id3v1 tag: %w", err)
+		}
+
+		if string(tag[:]) == "TAG" {
+			end -= 128
+		}
+	}
+
+	if end-start >= 32 {
+		var footer [32]byte
+		if _, err := r.ReadAt(footer[:], end-32); err != nil {
+			return 0, fmt.Errorf("reading apev2 footer: %w", err)
+		}
+
+		if string(footer[:8]) == "APETAGEX" {
+			// Размер включает footer, но не header; наличие header отмечено старшим битом флагов.
+			tagSize := int64(binary.LittleEndian.Uint32(footer[12:16]))
+			if binary.LittleEndian.Uint32(footer[20:24])&0x80000000 != 0 {
+				tagSize += 32
+			}
+
+			if tagSize <= end-start {
+				end -= tagSize
+			}
+		}
+	}
+
+	return end, nil
+}
+
+// findMp3Frame ищет первый фрейм, за которым сразу следует совместимый с ним фрейм (или конец потока).
+func findMp3Frame(r io.ReaderAt, start, end int64) (int64, mpegFrameHeader, error) {
+	window := make([]byte, min(end-start, mp3SyncWindow))
+
+	n, err := r.ReadAt(window, start)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, mpegFrameHeader{}, fmt.Errorf("reading mpeg stream: %w", err)
+	}
+
+	window = window[:n]
+
+	for i := 0; i+4 <= len(window); i++ {
+		skip := bytes.IndexByte(window[i:], 0xFF)
+		if skip < 0 {
+			break
+		}
+
+		i += skip
+
+		header, ok := parseMpegFrameHeader(window[i:])
+		if !ok {
+			continue
+		}
+
+		next := start + int64(i) + int64(header.frameLength())
+		if next+4 > end {
+			if next <= end {
+				return start + int64(i), header, nil
+			}
+
+			continue
+		}
+
+		var b [4]byte
+		if _, err = r.ReadAt(b[:], next); err != nil {
+			return 0, mpegFrameHeader{}, fmt.Errorf("reading mpeg stream: %w", err)
+		}
+
+		if nextHeader, ok := parseMpegFrameHeader(b[:]); ok && header.compatible(nextHeader) {
+			return start + int64(i), header, nil
+		}
+	}
+
+	return 0, mpegFrameHeader{}, errMp3FrameNotFound
+}
+
+// parseMp3VBRInfo ищет в первом фрейме тег Xing/Info (сразу после side info) или VBRI (всегда через 32 байта
+// после заголовка). Возвращает io.EOF, если ни одного тега нет.
+func parseMp3VBRInfo(frame []byte, header mpegFrameHeader) (mp3VBRInfo, error) {
+	if offset := 4 + header.sideInfoLength(); len(frame) >= offset+8 {
+		tag := frame[offset:]
+		if id := string(tag[:4]); id == "Xing" || id == "Info" {
+			return parseXingTag(tag)
+		}
+	}
+
+	if len(frame) >= 4+32+18 && string(frame[36:40]) == "VBRI" {
+		vbri := frame[36:]
+
+		return mp3VBRInfo{
+			bytes:  binary.BigEndian.Uint32(vbri[10:14]),
+			frames: binary.BigEndian.Uint32(vbri[14:18]),
+		}, nil
+	}
+
+	return mp3VBRInfo{}, io.EOF
+}
+
+func parseXingTag(tag []byte) (mp3VBRInfo, error) {
+	var info mp3VBRInfo
+
+	flags := binary.BigEndian.Uint32(tag[4:8])
+	pos := 8
+
+	field := func(present bool, length int) ([]byte, bool) {
+		if !present {
+			return nil, true
+		}
+
+		if pos+length > len(tag) {
+			return nil, false
+		}
+
+		b := tag[pos : pos+length]
+		pos += length
+
+		return b, true
+	}
+
+	frames, ok := field(flags&0x1 != 0, 4)
+	if !ok {
+		return info, errMp3InvalidXingTag
+	}
+
+	size, ok := field(flags&0x2 != 0, 4)
+	if !ok {
+		return info, errMp3InvalidXingTag
+	}
+
+	if _, ok = field(flags&0x4 != 0, 100); !ok { // TOC
+		return info, errMp3InvalidXingTag
+	}
+
+	if _, ok = field(flags&0x8 != 0, 4); !ok { // quality
+		return info, errMp3InvalidXingTag
+	}
+
+	if frames != nil {
+		info.frames = binary.BigEndian.Uint32(frames)
+	}
+
+	if size != nil {
+		info.bytes = binary.BigEndian.Uint32(size)
+	}
+
+	// Расширение LAME: 9 байт версии энкодера, затем через 12 байт два 12-битных поля delay и padding.
+	if lame := tag[pos:]; len(lame) >= 24 && (string(lame[:4]) == "LAME" || string(lame[:4]) == "Lavc") {
+		packed := int(lame[21])<<16 | int(lame[22])<<8 | int(lame[23])
+		info.delay = packed>>12 + lameDecoderDelay
+		info.padding = max(packed&0xFFF-lameDecoderDelay, 0)
+	}
+
+	return info, nil
+}
+
+// walkMp3Frames обходит заголовки всех фреймов от offset до end и возвращает их число и суммарный размер.
+// Мусор между фреймами пропускается побайтовым поиском следующего совместимого заголовка.
+func walkMp3Frames(r io.ReaderAt, offset, end int64, first mpegFrameHeader) (uint64, int64, error) {
+	br := bufio.NewReaderSize(io.NewSectionReader(r, offset, end-offset), 64<<10)
+
+	var (
+		frames uint64
+		walked int64
+	)
+
+	for pos := offset; pos+4 <= end; {
+		b, err := br.Peek(4)
+		if err != nil {
+			return 0, 0, fmt.Errorf("reading mpeg frame header: %w", err)
+		}
+
+		header, ok := parseMpegFrameHeader(b)
+		if !ok || !header.compatible(first) {
+			if _, err = br.Discard(1); err != nil {
+				return 0, 0, fmt.Errorf("reading mpeg stream: %w", err)
+			}
+
+			pos++
+
+			continue
+		}
+
+		length := int64(header.frameLength())
+		if pos+length > end {
+			// Обрезанный последний фрейм декодер всё равно проиграет целиком.
+			length = end - pos
+		}
+
+		if _, err = br.Discard(int(length)); err != nil {
+			return 0, 0, fmt.Errorf("reading mpeg stream: %w", err)
+		}
+
+		frames++
+		walked += length
+		pos += length
+	}
+
+	if frames == 0 {
+		return 0, 0, errMp3FrameNotFound
+	}
+
+	return frames, walked, nil
+}
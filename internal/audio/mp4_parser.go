@@ -0,0 +1,585 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+const (
+	// mp4MaxBoxPayload больше этого payload служебных box в память не читается. Обложка в covr обычно укладывается
+	// в пару мегабайт.
+	mp4MaxBoxPayload = 16 << 20
+	// mp4MaxDepth ограничение вложенности, чтобы зацикленный или вредоносный файл не уводил в бесконечную рекурсию.
+	mp4MaxDepth = 16
+
+	// Типы значений в box data внутри ilst.
+	mp4DataUTF8 = 1
+	mp4DataJPEG = 13
+	mp4DataPNG  = 14
+
+	// Теги дескрипторов в esds (ISO/IEC 14496-1).
+	mp4ESDescriptorTag            = 0x03
+	mp4DecoderConfigDescriptorTag = 0x04
+
+	// Значения objectTypeIndication, которые означают MP3, а не AAC, внутри mp4a.
+	mp4ObjectTypeMPEG1Audio = 0x6B
+	mp4ObjectTypeMPEG2Audio = 0x69
+)
+
+var (
+	errMp4Invalid     = errors.New("invalid mp4 file")
+	errMp4NoAudio     = errors.New("mp4 file has no audio track")
+	errMp4Unsupported = errors.New("unsupported mp4 audio codec")
+
+	// mp4TextTags iTunes-атомы ilst с текстовыми значениями и соответствующие ключи TrackFileMetadata.Tags.
+	mp4TextTags = map[string]string{
+		"\xa9nam": "title",
+		"\xa9ART": "artist",
+		"aART":    "albumartist",
+		"\xa9alb": "album",
+		"\xa9day": "date",
+		"\xa9gen": "genre",
+		"\xa9cmt": "comment",
+		"\xa9wrt": "composer",
+		"\xa9too": "encoder",
+		"cprt":    "copyright",
+	}
+)
+
+// mp4Box box ISO-BMFF: тип и границы payload в файле (без заголовка).
+type mp4Box struct {
+	kind       string
+	start, end int64
+}
+
+// mp4AudioTrack то, что удалось достать из trak со звуком.
+type mp4AudioTrack struct {
+	codec         domain.Codec
+	sampleRate    int
+	channels      int
+	bitsPerSample int
+	avgBitrate    int // бит/с из esds или alac, 0 если не указан
+	timescale     uint32
+	duration      uint64
+}
+
+// ParseMp4File разбирает MP4/M4A (ISO-BMFF): параметры первой звуковой дорожки из moov/trak/mdia, длительность из
+// mdhd (или mvhd, если в mdhd её нет), кодек по sample entry (mp4a — AAC, alac — ALAC), средний битрейт из esds, а
+// также iTunes-теги и обложку из moov/udta/meta/ilst.
+func ParseMp4File(filePath string) (*TrackFileMetadata, error) {
+	return parseFile(filePath, "mp4", parseMp4)
+}
+
+func parseMp4(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	top, err := mp4Children(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+
+	ftyp, ok := findMp4Box(top, "ftyp")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing ftyp box", errMp4Invalid)
+	}
+
+	moov, ok := findMp4Box(top, "moov")
+	if !ok {
+		return nil, fmt.Errorf("%w: missing moov box", errMp4Invalid)
+	}
+
+	meta := &TrackFileMetadata{Format: domain.FormatMP4, Mime: MimeMP4, Size: size}
+
+	brand, err := readMp4Payload(r, ftyp, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	switch string(brand) {
+	case "M4A ", "M4B ", "M4P ":
+		meta.Format = domain.FormatM4A
+	}
+
+	moovChildren, err := mp4Children(r, moov.start, moov.end)
+	if err != nil {
+		return nil, err
+	}
+
+	track, err := findMp4AudioTrack(r, moovChildren)
+	if err != nil {
+		return nil, err
+	}
+
+	timescale, duration := track.timescale, track.duration
+	if timescale == 0 || duration == 0 {
+		if mvhd, ok := findMp4Box(moovChildren, "mvhd"); ok {
+			timescale, duration, err = parseMp4TimeHeader(r, mvhd)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if timescale == 0 {
+		return nil, fmt.Errorf("%w: zero timescale", errMp4Invalid)
+	}
+
+	seconds := float64(duration) / float64(timescale)
+
+	meta.Codec = track.codec
+	meta.SampleRate = track.sampleRate
+	meta.Channels = track.channels
+	meta.Duration = time.Duration(seconds * float64(time.Second))
+	meta.TotalSamples = uint64(math.Round(seconds * float64(track.sampleRate)))
+
+	if track.codec == domain.CodecALAC {
+		meta.BitsPerSample = track.bitsPerSample
+	}
+
+	switch {
+	case track.avgBitrate > 0:
+		meta.Bitrate = track.avgBitrate / 1000
+	case seconds > 0:
+		meta.Bitrate = int(float64(size) * 8 / seconds / 1000)
+	}
+
+	if err = parseMp4Metadata(r, moovChildren, meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// mp4Children перечисляет box верхнего уровня в диапазоне [start, end).
+func mp4Children(r io.ReaderAt, start, end int64) ([]mp4Box, error) {
+	var boxes []mp4Box
+
+	for offset := start; offset+8 <= end; {
+		var header [16]byte
+
+		b, err := readAtMost(r, header[:min(16, end-offset)], offset)
+		if err != nil {
+			return nil, fmt.Errorf("reading mp4 box header: %w", err)
+		}
+
+		size := int64(binary.BigEndian.Uint32(b[0:4]))
+		kind := string(b[4:8])
+		headerSize := int64(8)
+
+		switch size {
+		case 0: // box до конца родителя
+			size = end - offset
+		case 1: // 64-битный размер сразу за типом
+			if len(b) < 16 {
+				return nil, fmt.Errorf("%w: truncated large box %q", errMp4Invalid, kind)
+			}
+
+			size = int64(binary.BigEndian.Uint64(b[8:16]))
+			headerSize = 16
+		}
+
+		if size < headerSize || offset+size > end {
+			return nil, fmt.Errorf("%w: box %q has invalid size %d", errMp4Invalid, kind, size)
+		}
+
+		boxes = append(boxes, mp4Box{kind: kind, start: offset + headerSize, end: offset + size})
+		offset += size
+	}
+
+	return boxes, nil
+}
+
+func findMp4Box(boxes []mp4Box, kind string) (mp4Box, bool) {
+	for _, box := range boxes {
+		if box.kind == kind {
+			return box, true
+		}
+	}
+
+	return mp4Box{}, false
+}
+
+// findMp4Path спускается по цепочке контейнеров, например "mdia", "minf", "stbl".
+func findMp4Path(r io.ReaderAt, box mp4Box, path ...string) (mp4Box, bool, error) {
+	if len(path) > mp4MaxDepth {
+		return mp4Box{}, false, fmt.Errorf("%w: box nesting too deep", errMp4Invalid)
+	}
+
+	for _, kind := range path {
+		children, err := mp4Children(r, box.start, box.end)
+		if err != nil {
+			return mp4Box{}, false, err
+		}
+
+		var ok bool
+		if box, ok = findMp4Box(children, kind); !ok {
+			return mp4Box{}, false, nil
+		}
+	}
+
+	return box, true, nil
+}
+
+func readMp4Payload(r io.ReaderAt, box mp4Box, limit int64) ([]byte, error) {
+	size := box.end - box.start
+	if size > mp4MaxBoxPayload {
+		return nil, fmt.Errorf("%w: box %q is too large (%d bytes)", errMp4Invalid, box.kind, size)
+	}
+
+	b, err := readAtMost(r, make([]byte, min(size, limit)), box.start)
+	if err != nil {
+		return nil, fmt.Errorf("reading mp4 box %q: %w", box.kind, err)
+	}
+
+	return b, nil
+}
+
+// parseMp4TimeHeader читает timescale и duration из mvhd или mdhd. Обе — full box, у версии 1 времена 64-битные.
+func parseMp4TimeHeader(r io.ReaderAt, box mp4Box) (uint32, uint64, error) {
+	b, err := readMp4Payload(r, box, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	switch {
+	case len(b) >= 32 && b[0] == 1:
+		return binary.BigEndian.Uint32(b[20:24]), binary.BigEndian.Uint64(b[24:32]), nil
+	case len(b) >= 20 && b[0] == 0:
+		return binary.BigEndian.Uint32(b[12:16]), uint64(binary.BigEndian.Uint32(b[16:20])), nil
+	default:
+		return 0, 0, fmt.Errorf("%w: malformed %s box", errMp4Invalid, box.kind)
+	}
+}
+
+// findMp4AudioTrack возвращает первую дорожку с обработчиком soun.
+func findMp4AudioTrack(r io.ReaderAt, moov []mp4Box) (*mp4AudioTrack, error) {
+	for _, trak := range moov {
+		if trak.kind != "trak" {
+			continue
+		}
+
+		mdia, ok, err := findMp4Path(r, trak, "mdia")
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		hdlr, ok, err := findMp4Path(r, mdia, "hdlr")
+		if err != nil || !ok {
+			continue
+		}
+
+		// hdlr: version/flags (4), pre_defined (4), handler_type (4).
+		handler, err := readMp4Payload(r, hdlr, 12)
+		if err != nil || len(handler) < 12 || string(handler[8:12]) != "soun" {
+			continue
+		}
+
+		track := &mp4AudioTrack{}
+
+		if mdhd, ok, _ := findMp4Path(r, mdia, "mdhd"); ok {
+			track.timescale, track.duration, err = parseMp4TimeHeader(r, mdhd)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		stsd, ok, err := findMp4Path(r, mdia, "minf", "stbl", "stsd")
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			return nil, fmt.Errorf("%w: audio track without stsd", errMp4Invalid)
+		}
+
+		if err = parseMp4SampleEntry(r, stsd, track); err != nil {
+			return nil, err
+		}
+
+		return track, nil
+	}
+
+	return nil, errMp4NoAudio
+}
+
+// parseMp4SampleEntry разбирает первый AudioSampleEntry из stsd. Тип entry определяет кодек, а вложенный esds или
+// alac уточняет битрейт и разрядность.
+func parseMp4SampleEntry(r io.ReaderAt, stsd mp4Box, track *mp4AudioTrack) error {
+	// stsd: version/flags (4), entry_count (4), затем сами entry как обычные box.
+	entries, err := mp4Children(r, stsd.start+8, stsd.end)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return fmt.Errorf("%w: empty stsd", errMp4Invalid)
+	}
+
+	entry := entries[0]
+
+	switch entry.kind {
+	case "mp4a":
+		track.codec = domain.CodecAAC
+	case "alac":
+		track.codec = domain.CodecALAC
+	default:
+		return fmt.Errorf("%w: %q", errMp4Unsupported, entry.kind)
+	}
+
+	b, err := readMp4Payload(r, entry, 64)
+	if err != nil {
+		return err
+	}
+
+	// SampleEntry: reserved (6), data_reference_index (2); дальше звуковая часть QuickTime/ISO: version (2),
+	// revision (2), vendor (4), channelcount (2), samplesize (2), compression_id (2), packet_size (2),
+	// samplerate (16.16).
+	if len(b) < 28 {
+		return fmt.Errorf("%w: audio sample entry too short", errMp4Invalid)
+	}
+
+	version := binary.BigEndian.Uint16(b[8:10])
+	track.channels = int(binary.BigEndian.Uint16(b[16:18]))
+	track.bitsPerSample = int(binary.BigEndian.Uint16(b[18:20]))
+	track.sampleRate = int(binary.BigEndian.Uint32(b[24:28]) >> 16)
+
+	childrenStart := entry.start + 28
+
+	switch version {
+	case 1:
+		childrenStart += 16
+	case 2:
+		// Версия 2 хранит частоту как float64 и число каналов отдельно, потому что 16.16 не хватает для 192 кГц.
+		if len(b) < 28+36 {
+			return fmt.Errorf("%w: audio sample entry v2 too short", errMp4Invalid)
+		}
+
+		track.sampleRate = int(math.Float64frombits(binary.BigEndian.Uint64(b[32:40])))
+		track.channels = int(binary.BigEndian.Uint32(b[40:44]))
+		track.bitsPerSample = int(binary.BigEndian.Uint32(b[48:52]))
+		childrenStart += 36
+	}
+
+	children, err := mp4Children(r, childrenStart, entry.end)
+	if err != nil {
+		return err
+	}
+
+	if esds, ok := findMp4Box(children, "esds"); ok {
+		if err = parseMp4ESDS(r, esds, track); err != nil {
+			return err
+		}
+	}
+
+	if alac, ok := findMp4Box(children, "alac"); ok {
+		if err = parseMp4ALACConfig(r, alac, track); err != nil {
+			return err
+		}
+	}
+
+	if track.channels == 0 || track.sampleRate == 0 {
+		return fmt.Errorf("%w: zero channels or sample rate", errMp4Invalid)
+	}
+
+	return nil
+}
+
+// parseMp4ESDS достаёт из ES_Descriptor → DecoderConfigDescriptor тип объекта и средний битрейт.
+func parseMp4ESDS(r io.ReaderAt, esds mp4Box, track *mp4AudioTrack) error {
+	b, err := readMp4Payload(r, esds, 256)
+	if err != nil {
+		return err
+	}
+
+	if len(b) < 4 {
+		return fmt.Errorf("%w: esds too short", errMp4Invalid)
+	}
+
+	es, ok := mp4Descriptor(b[4:], mp4ESDescriptorTag)
+	if !ok || len(es) < 3 {
+		return fmt.Errorf("%w: esds without ES descriptor", errMp4Invalid)
+	}
+
+	// ES_ID (2) и флаги (1); флаги говорят, какие необязательные поля идут дальше.
+	flags := es[2]
+	es = es[3:]
+
+	if flags&0x80 != 0 && len(es) >= 2 { // streamDependenceFlag
+		es = es[2:]
+	}
+
+	if flags&0x40 != 0 && len(es) >= 1 { // URL_Flag
+		es = es[min(1+int(es[0]), len(es)):]
+	}
+
+	if flags&0x20 != 0 && len(es) >= 2 { // OCRstreamFlag
+		es = es[2:]
+	}
+
+	config, ok := mp4Descriptor(es, mp4DecoderConfigDescriptorTag)
+	if !ok || len(config) < 13 {
+		return nil
+	}
+
+	// objectTypeIndication (1), streamType (1), bufferSizeDB (3), maxBitrate (4), avgBitrate (4).
+	if objectType := config[0]; objectType == mp4ObjectTypeMPEG1Audio || objectType == mp4ObjectTypeMPEG2Audio {
+		track.codec = domain.CodecMP3
+	}
+
+	track.avgBitrate = int(binary.BigEndian.Uint32(config[9:13]))
+
+	return nil
+}
+
+// mp4Descriptor находит в начале b дескриптор с тегом tag и возвращает его содержимое. Длина дескриптора записана
+// переменным числом байт, по 7 бит в каждом.
+func mp4Descriptor(b []byte, tag byte) ([]byte, bool) {
+	if len(b) < 2 || b[0] != tag {
+		return nil, false
+	}
+
+	var length int
+
+	i := 1
+	for ; i < len(b) && i <= 4; i++ {
+		length = length<<7 | int(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			i++
+			break
+		}
+	}
+
+	if i+length > len(b) {
+		return b[i:], true
+	}
+
+	return b[i : i+length], true
+}
+
+// parseMp4ALACConfig читает ALACSpecificConfig: frameLength (4), compatibleVersion (1), bitDepth (1), pb, mb, kb,
+// numChannels (1), maxRun (2), maxFrameBytes (4), avgBitRate (4), sampleRate (4).
+func parseMp4ALACConfig(r io.ReaderAt, alac mp4Box, track *mp4AudioTrack) error {
+	b, err := readMp4Payload(r, alac, 64)
+	if err != nil {
+		return err
+	}
+
+	// Full box: version/flags (4) перед конфигом.
+	if len(b) < 4+24 {
+		return fmt.Errorf("%w: alac config too short", errMp4Invalid)
+	}
+
+	config := b[4:]
+	track.bitsPerSample = int(config[5])
+	track.channels = int(config[9])
+	track.avgBitrate = int(binary.BigEndian.Uint32(config[16:20]))
+	track.sampleRate = int(binary.BigEndian.Uint32(config[20:24]))
+
+	return nil
+}
+
+// parseMp4Metadata читает iTunes-теги из moov/udta/meta/ilst (или moov/meta/ilst).
+func parseMp4Metadata(r io.ReaderAt, moov []mp4Box, meta *TrackFileMetadata) error {
+	metaBox, ok := findMp4Box(moov, "meta")
+	if !ok {
+		udta, ok := findMp4Box(moov, "udta")
+		if !ok {
+			return nil
+		}
+
+		metaBox, ok, _ = findMp4Path(r, udta, "meta")
+		if !ok {
+			return nil
+		}
+	}
+
+	// В ISO meta — full box с 4 байтами version/flags, а в старом QuickTime сразу идут дочерние box. Отличаем по
+	// тому, похож ли следующий за заголовком тип на hdlr.
+	start := metaBox.start
+
+	if head, err := readMp4Payload(r, mp4Box{kind: "meta", start: start, end: metaBox.end}, 12); err == nil &&
+		len(head) >= 12 && string(head[8:12]) == "hdlr" {
+		start += 4
+	}
+
+	children, err := mp4Children(r, start, metaBox.end)
+	if err != nil {
+		return nil //nolint:nilerr // битые теги не повод отбрасывать сам звук
+	}
+
+	ilst, ok := findMp4Box(children, "ilst")
+	if !ok {
+		return nil
+	}
+
+	items, err := mp4Children(r, ilst.start, ilst.end)
+	if err != nil {
+		return nil //nolint:nilerr // см. выше
+	}
+
+	for _, item := range items {
+		data, ok, err := findMp4Path(r, item, "data")
+		if err != nil || !ok {
+			continue
+		}
+
+		payload, err := readMp4Payload(r, data, mp4MaxBoxPayload)
+		if err != nil || len(payload) < 8 {
+			continue
+		}
+
+		// data: type indicator (4, младшие 3 байта — тип значения), locale (4), значение.
+		kind := binary.BigEndian.Uint32(payload[0:4]) & 0xFFFFFF
+		value := payload[8:]
+
+		switch {
+		case mp4TextTags[item.kind] != "" && kind == mp4DataUTF8:
+			if text := string(bytes.TrimRight(value, "\x00")); text != "" {
+				setTag(meta, mp4TextTags[item.kind], text)
+			}
+		case item.kind == "trkn" || item.kind == "disk":
+			// Бинарное значение: reserved (2), номер (2), всего (2).
+			if len(value) < 6 {
+				continue
+			}
+
+			numberKey, totalKey := "tracknumber", "tracktotal"
+			if item.kind == "disk" {
+				numberKey, totalKey = "discnumber", "disctotal"
+			}
+
+			if n := binary.BigEndian.Uint16(value[2:4]); n > 0 {
+				setTag(meta, numberKey, strconv.Itoa(int(n)))
+			}
+
+			if n := binary.BigEndian.Uint16(value[4:6]); n > 0 {
+				setTag(meta, totalKey, strconv.Itoa(int(n)))
+			}
+		case item.kind == "covr" && meta.Cover == nil && len(value) > 0:
+			meta.Cover = &Picture{MimeType: mp4CoverMime(kind, value), Data: bytes.Clone(value)}
+		}
+	}
+
+	return nil
+}
+
+func mp4CoverMime(kind uint32, data []byte) string {
+	switch {
+	case kind == mp4DataJPEG:
+		return "image/jpeg"
+	case kind == mp4DataPNG:
+		return "image/png"
+	default:
+		return sniffImageMime(data)
+	}
+}
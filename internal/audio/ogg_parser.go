@@ -0,0 +1,252 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+const (
+	oggPageHeaderSize = 27
+	// oggMaxPageSize заголовок, 255 сегментов в таблице и 255 байт в каждом сегменте.
+	oggMaxPageSize = oggPageHeaderSize + 255 + 255*255
+	// oggTailChunk сколько байт за раз читается с конца файла при поиске последней страницы потока.
+	oggTailChunk = 64 << 10
+	// opusGranuleRate гранулы Opus всегда считаются в 48 кГц независимо от частоты исходника.
+	opusGranuleRate = 48000
+)
+
+var (
+	errOggInvalidPage        = errors.New("invalid ogg page")
+	errOggUnsupportedStream  = errors.New("unsupported ogg stream")
+	errOggLastPageNotFound   = errors.New("ogg stream end not found")
+	oggCRCTable              = makeOggCRCTable()
+	oggCapturePattern        = []byte("OggS")
+	opusIdentificationMagic  = []byte("OpusHead")
+	vorbisIdentificationHead = []byte("\x01vorbis")
+)
+
+// oggPage заголовок страницы Ogg (RFC 3533, раздел 6) и её полезная нагрузка.
+type oggPage struct {
+	headerType byte
+	granule    int64
+	serial     uint32
+	segments   []byte
+	data       []byte
+}
+
+// firstPacket возвращает первый пакет страницы (или его начало, если пакет продолжается на следующей странице).
+func (p oggPage) firstPacket() []byte {
+	var length int
+
+	for _, segment := range p.segments {
+		length += int(segment)
+		if segment < 255 {
+			break
+		}
+	}
+
+	return p.data[:min(length, len(p.data))]
+}
+
+// ParseOggFile разбирает Ogg-файл с потоком Opus или Vorbis. Параметры берутся из identification header первой
+// страницы, а длительность из granule position последней страницы того же логического потока (для Opus за вычетом
+// pre-skip).
+func ParseOggFile(filePath string) (*TrackFileMetadata, error) {
+	return parseFile(filePath, "ogg", parseOgg)
+}
+
+func parseOgg(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	first, err := readOggPage(r, 0, size)
+	if err != nil {
+		return nil, err
+	}
+
+	if first.headerType&0x02 == 0 {
+		return nil, fmt.Errorf("%w: first page is not a beginning of stream", errOggInvalidPage)
+	}
+
+	meta := &TrackFileMetadata{
+		Format: domain.FormatOGG,
+		Mime:   MimeOGG,
+		Size:   size,
+	}
+
+	var (
+		granuleRate int
+		preSkip     int64
+	)
+
+	packet := first.firstPacket()
+
+	switch {
+	case bytes.HasPrefix(packet, opusIdentificationMagic) && len(packet) >= 19:
+		// OpusHead: версия, каналы, pre-skip (LE16), частота исходника (LE32), усиление, схема каналов (RFC 7845).
+		// Декодер Opus всегда выдаёт 48 кГц, частоту исходника он только сообщает, поэтому её не используем.
+		meta.Codec = domain.CodecOPUS
+		meta.Channels = int(packet[9])
+		meta.SampleRate = opusGranuleRate
+		preSkip = int64(binary.LittleEndian.Uint16(packet[10:12]))
+		granuleRate = opusGranuleRate
+	case bytes.HasPrefix(packet, vorbisIdentificationHead) && len(packet) >= 30:
+		// Vorbis identification header: версия (LE32), каналы, частота (LE32), три битрейта (LE32), размеры блоков.
+		meta.Codec = domain.CodecVORBIS
+		meta.Channels = int(packet[11])
+		meta.SampleRate = int(binary.LittleEndian.Uint32(packet[12:16]))
+		granuleRate = meta.SampleRate
+	default:
+		return nil, fmt.Errorf("%w: %q", errOggUnsupportedStream, packet[:min(len(packet), 8)])
+	}
+
+	if meta.Channels == 0 || granuleRate == 0 {
+		return nil, fmt.Errorf("%w: invalid identification header", errOggUnsupportedStream)
+	}
+
+	last, err := findLastOggPage(r, size, first.serial)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := max(last.granule-preSkip, 0)
+	seconds := float64(samples) / float64(granuleRate)
+
+	meta.TotalSamples = uint64(samples)
+	meta.Duration = time.Duration(seconds * float64(time.Second))
+
+	if seconds > 0 {
+		meta.Bitrate = int(float64(size) * 8 / seconds / 1000)
+	}
+
+	return meta, nil
+}
+
+// readOggPage читает и проверяет (включая CRC) страницу, начинающуюся ровно с offset.
+func readOggPage(r io.ReaderAt, offset, size int64) (oggPage, error) {
+	buf := make([]byte, min(int64(oggMaxPageSize), size-offset))
+	if len(buf) < oggPageHeaderSize {
+		return oggPage{}, fmt.Errorf("%w: truncated header", errOggInvalidPage)
+	}
+
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return oggPage{}, fmt.Errorf("reading ogg page: %w", err)
+	}
+
+	page, ok := parseOggPage(buf[:n])
+	if !ok {
+		return oggPage{}, errOggInvalidPage
+	}
+
+	return page, nil
+}
+
+// parseOggPage разбирает страницу в начале b. Возвращает false, если это не страница Ogg, она обрезана или не
+// совпадает контрольная сумма.
+func parseOggPage(b []byte) (oggPage, bool) {
+	if len(b) < oggPageHeaderSize || !bytes.HasPrefix(b, oggCapturePattern) || b[4] != 0 {
+		return oggPage{}, false
+	}
+
+	segmentCount := int(b[26])
+	if len(b) < oggPageHeaderSize+segmentCount {
+		return oggPage{}, false
+	}
+
+	segments := b[oggPageHeaderSize : oggPageHeaderSize+segmentCount]
+
+	var dataLength int
+	for _, segment := range segments {
+		dataLength += int(segment)
+	}
+
+	pageLength := oggPageHeaderSize + segmentCount + dataLength
+	if len(b) < pageLength {
+		return oggPage{}, false
+	}
+
+	page := oggPage{
+		headerType: b[5],
+		granule:    int64(binary.LittleEndian.Uint64(b[6:14])),
+		serial:     binary.LittleEndian.Uint32(b[14:18]),
+		segments:   segments,
+		data:       b[oggPageHeaderSize+segmentCount : pageLength],
+	}
+
+	// CRC считается по всей странице с обнулённым полем контрольной суммы.
+	expected := binary.LittleEndian.Uint32(b[22:26])
+
+	crc := oggCRCUpdate(0, b[:22])
+	crc = oggCRCUpdate(crc, []byte{0, 0, 0, 0})
+	crc = oggCRCUpdate(crc, b[26:pageLength])
+
+	if crc != expected {
+		return oggPage{}, false
+	}
+
+	return page, true
+}
+
+// findLastOggPage ищет с конца файла последнюю страницу логического потока serial с выставленной granule position.
+// Читает блоками назад, пока не найдёт: у файлов из нескольких сцепленных потоков нужная страница может оказаться
+// далеко от конца.
+func findLastOggPage(r io.ReaderAt, size int64, serial uint32) (oggPage, error) {
+	end := size
+
+	for end > 0 {
+		start := max(end-oggTailChunk, 0)
+
+		// Захватываем хвост предыдущего окна, чтобы страница на стыке двух окон читалась целиком.
+		buf := make([]byte, min(end-start+oggMaxPageSize, size-start))
+
+		n, err := r.ReadAt(buf, start)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return oggPage{}, fmt.Errorf("reading ogg stream: %w", err)
+		}
+
+		buf = buf[:n]
+
+		for i := bytes.LastIndex(buf[:min(int(end-start), len(buf))], oggCapturePattern); i >= 0; i = bytes.LastIndex(buf[:i], oggCapturePattern) {
+			page, ok := parseOggPage(buf[i:])
+			if ok && page.serial == serial && page.granule != -1 {
+				return page, nil
+			}
+		}
+
+		end = start
+	}
+
+	return oggPage{}, errOggLastPageNotFound
+}
+
+// makeOggCRCTable таблица для CRC-32 Ogg: полином 0x04c11db7, без отражения битов, начальное значение 0.
+func makeOggCRCTable() *[256]uint32 {
+	var table [256]uint32
+
+	for i := range table {
+		crc := uint32(i) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return &table
+}
+
+func oggCRCUpdate(crc uint32, b []byte) uint32 {
+	for _, v := range b {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^v]
+	}
+
+	return crc
+}
@@ -0,0 +1,45 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// parseFile открывает файл и передаёт его парсеру контейнера вместе с размером, а затем дописывает в результат путь
+// и имя файла. kind используется только в тексте ошибок.
+func parseFile(filePath, kind string, parse func(r io.ReaderAt, size int64) (*TrackFileMetadata, error)) (*TrackFileMetadata, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("open %s file: %w", kind, err)
+	}
+
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat %s file: %w", kind, err)
+	}
+
+	meta, err := parse(f, fi.Size())
+	if err != nil {
+		return nil, err
+	}
+
+	meta.Path = filePath
+	meta.Filename = filepath.Base(filePath)
+
+	return meta, nil
+}
+
+// readAtMost читает до len(buf) байт с offset. Конец файла ошибкой не считается: возвращается прочитанное.
+func readAtMost(r io.ReaderAt, buf []byte, offset int64) ([]byte, error) {
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+// PCMDecoder последовательный декодер аудиопотока в PCM.
+type PCMDecoder interface {
+	SampleRate() int
+	Channels() int
+	// BitsPerSample разрядность сэмплов, которые возвращает ReadFrame.
+	BitsPerSample() int
+	// TotalSamples число сэмплов на канал, если контейнер его хранит; иначе 0.
+	TotalSamples() uint64
+	// ReadFrame возвращает очередную порцию сэмплов по каналам; срезы действительны до следующего вызова. В конце
+	// потока возвращает io.EOF.
+	ReadFrame() ([][]int32, error)
+}
+
+// NewPCMDecoder выбирает декодер по формату файла. Пока поддерживаются FLAC и WAV; для остальных форматов
+// возвращается ErrUnknownFormat.
+func NewPCMDecoder(r io.Reader, format domain.Format) (PCMDecoder, error) {
+	switch format {
+	case domain.FormatFLAC:
+		return NewFlacDecoder(r)
+	case domain.FormatWAV:
+		return NewWavDecoder(r)
+	default:
+		return nil, fmt.Errorf("%w: no pcm decoder for %q", ErrUnknownFormat, format)
+	}
+}
+
+// pcmRange PCMDecoder, который отдаёт только сэмплы [start, end) исходного потока.
+type pcmRange struct {
+	PCMDecoder
+	start, end uint64
+	position   uint64
+	frame      [][]int32
+}
+
+// NewPCMRange ограничивает декодер диапазоном сэмплов [start, end); end = 0 — до конца потока. Нужен для
+// треков, размеченных в одном файле образа: сэмплы до start декодируются и отбрасываются.
+func NewPCMRange(decoder PCMDecoder, start, end uint64) PCMDecoder {
+	return &pcmRange{PCMDecoder: decoder, start: start, end: end}
+}
+
+func (r *pcmRange) TotalSamples() uint64 {
+	total := r.PCMDecoder.TotalSamples()
+	if r.end > 0 && (total == 0 || r.end < total) {
+		total = r.end
+	}
+
+	if total <= r.start {
+		return 0
+	}
+
+	return total - r.start
+}
+
+func (r *pcmRange) ReadFrame() ([][]int32, error) {
+	for {
+		if r.end > 0 && r.position >= r.end {
+			return nil, io.EOF
+		}
+
+		frame, err := r.PCMDecoder.ReadFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(frame) == 0 {
+			continue
+		}
+
+		from, to := r.position, r.position+uint64(len(frame[0]))
+		r.position = to
+
+		if to <= r.start {
+			continue
+		}
+
+		first := max(r.start, from) - from
+		last := to - from
+
+		if r.end > 0 && to > r.end {
+			last = r.end - from
+		}
+
+		if first == 0 && last == to-from {
+			return frame, nil
+		}
+
+		r.frame = r.frame[:0]
+		for _, channel := range frame {
+			r.frame = append(r.frame, channel[first:last])
+		}
+
+		return r.frame, nil
+	}
+}
@@ -0,0 +1,49 @@
+package audio
+
+import "bytes"
+
+// Picture встроенная в файл картинка (обложка альбома).
+type Picture struct {
+	// MimeType тип изображения, например image/jpeg.
+	MimeType string
+	// Description необязательное описание из тега.
+	Description string
+	// Data содержимое изображения как есть.
+	Data []byte
+}
+
+// pictureFrontCover тип картинки «лицевая обложка» (типы общие для ID3v2 APIC и блока PICTURE FLAC).
+const pictureFrontCover = 3
+
+// typedPicture картинка вместе с её типом.
+type typedPicture struct {
+	kind uint32
+	Picture
+}
+
+// setCover запоминает картинку как обложку, если обложки ещё нет или новая картинка — лицевая обложка, а найденная
+// раньше нет.
+func setCover(meta *TrackFileMetadata, coverType *int, picture typedPicture) {
+	if meta.Cover != nil && (*coverType == pictureFrontCover || picture.kind != pictureFrontCover) {
+		return
+	}
+
+	meta.Cover = &picture.Picture
+	*coverType = int(picture.kind)
+}
+
+// sniffImageMime определяет тип изображения по сигнатуре, когда контейнер его не сообщает.
+func sniffImageMime(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8, 0xFF}):
+		return "image/jpeg"
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return "image/png"
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		return "image/gif"
+	case bytes.HasPrefix(data, []byte("BM")):
+		return "image/bmp"
+	default:
+		return "application/octet-stream"
+	}
+}
@@ -0,0 +1,118 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// MIME-типы, которые Probe проставляет в TrackFileMetadata.Mime.
+const (
+	MimeFLAC = "audio/flac"
+	MimeMP3  = "audio/mpeg"
+	MimeOGG  = "audio/ogg"
+	MimeWAV  = "audio/wav"
+	MimeAIFF = "audio/aiff"
+	MimeMP4  = "audio/mp4"
+)
+
+// sniffLength столько байт из начала файла (после ID3v2) получает функция распознавания формата.
+const sniffLength = 16
+
+var ErrUnknownFormat = errors.New("unknown audio format")
+
+// containerParser парсер одного контейнера в реестре Probe. match получает первые байты файла (после ID3v2, если
+// он есть) и решает, его ли это формат; parse получает весь файл.
+type containerParser struct {
+	name  string
+	match func(head []byte) bool
+	parse func(r io.ReaderAt, size int64) (*TrackFileMetadata, error)
+}
+
+// parsers реестр форматов в порядке проверки. MP3 последний: синхрослово фрейма короткое и у других форматов
+// могло бы совпасть случайно.
+var parsers = []containerParser{
+	{name: "flac", match: hasPrefix("fLaC"), parse: parseFlac},
+	{name: "ogg", match: hasPrefix("OggS"), parse: parseOgg},
+	{name: "wav", match: matchRIFF, parse: parseWav},
+	{name: "aiff", match: matchAIFF, parse: parseAiff},
+	{name: "mp4", match: matchMP4, parse: parseMp4},
+	{name: "mp3", match: matchMP3, parse: parseMp3},
+}
+
+// Probe определяет формат по содержимому, а не по расширению или заявленному MIME, и разбирает файл
+// соответствующим парсером. Для чтения используется только io.ReaderAt, поэтому источником может быть как файл на
+// диске, так и объект в хранилище (storage.ObjectReader). Возвращает ErrUnknownFormat, если сигнатура не опознана.
+func Probe(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	// ID3v2 встречается не только перед MP3, но и перед FLAC; распознаём то, что идёт за ним.
+	offset, err := skipID3v2(r, size)
+	if err != nil {
+		return nil, err
+	}
+
+	head, err := readAtMost(r, make([]byte, sniffLength), offset)
+	if err != nil {
+		return nil, fmt.Errorf("reading audio header: %w", err)
+	}
+
+	for _, p := range parsers {
+		if !p.match(head) {
+			continue
+		}
+
+		// Остальные парсеры ожидают свою сигнатуру в начале, поэтому ID3v2 отрезаем; MP3 пропускает его сам.
+		source, sourceSize := r, size
+		if offset > 0 && p.name != "mp3" {
+			source, sourceSize = io.NewSectionReader(r, offset, size-offset), size-offset
+		}
+
+		meta, err := p.parse(source, sourceSize)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.name, err)
+		}
+
+		meta.Size = size
+
+		return meta, nil
+	}
+
+	if offset > 0 {
+		// Тег есть, а сигнатуры после него нет: скорее всего MP3 с мусором между тегом и первым фреймом.
+		meta, err := parseMp3(r, size)
+		if err == nil {
+			return meta, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: header %q", ErrUnknownFormat, head)
+}
+
+func hasPrefix(prefix string) func([]byte) bool {
+	return func(head []byte) bool {
+		return bytes.HasPrefix(head, []byte(prefix))
+	}
+}
+
+func matchRIFF(head []byte) bool {
+	if len(head) < 12 || string(head[8:12]) != "WAVE" {
+		return false
+	}
+
+	riff := string(head[:4])
+
+	return riff == "RIFF" || riff == "RF64" || riff == "BW64"
+}
+
+func matchAIFF(head []byte) bool {
+	return len(head) >= 12 && string(head[:4]) == "FORM" && (string(head[8:12]) == "AIFF" || string(head[8:12]) == "AIFC")
+}
+
+func matchMP4(head []byte) bool {
+	return len(head) >= 8 && string(head[4:8]) == "ftyp"
+}
+
+func matchMP3(head []byte) bool {
+	_, ok := parseMpegFrameHeader(head)
+	return ok
+}
@@ -0,0 +1,214 @@
+package audio
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// AudioExtensions расширения файлов, которые Scan по умолчанию отдаёт на разбор. Формат всё равно определяется по
+// содержимому; расширения нужны только чтобы не открывать обложки, cue и прочие файлы рядом с музыкой.
+var AudioExtensions = []string{
+	".flac", ".mp3", ".ogg", ".oga", ".opus", ".wav", ".aif", ".aiff", ".aifc", ".m4a", ".m4b", ".mp4",
+}
+
+// ScanOptions настройки Scan.
+type ScanOptions struct {
+	// Workers число файлов, разбираемых одновременно. По умолчанию GOMAXPROCS.
+	Workers int
+	// Extensions расширения (с точкой, регистр не важен), которые нужно разбирать. По умолчанию AudioExtensions.
+	Extensions []string
+}
+
+// ScanProgress счётчики сканирования на момент очередного результата.
+type ScanProgress struct {
+	// Found сколько подходящих файлов (и нечитаемых каталогов) найдено обходом; окончательное значение, только
+	// когда WalkDone.
+	Found int64
+	// Done сколько файлов уже обработано, включая неудачные.
+	Done int64
+	// Failed сколько файлов не удалось разобрать.
+	Failed int64
+	// Bytes суммарный размер успешно разобранных файлов.
+	Bytes int64
+	// WalkDone обход каталога завершён, и Found больше не изменится.
+	WalkDone bool
+}
+
+// ScanResult результат разбора одного файла. Ровно одно из Meta и Err не nil.
+type ScanResult struct {
+	Path     string
+	Meta     *TrackFileMetadata
+	Err      error
+	Progress ScanProgress
+}
+
+// Scan рекурсивно обходит root и разбирает подходящие файлы через Probe пулом из Workers горутин. Результаты по
+// мере готовности передаются в handle; handle вызывается из горутины, вызвавшей Scan, поэтому синхронизация в нём
+// не нужна. Ошибки отдельных файлов (в том числе нечитаемых подкаталогов) приходят в ScanResult.Err и обход не
+// прерывают. Если handle вернул ошибку или отменён ctx, сканирование останавливается, в том числе посреди разбора
+// файла, и Scan возвращает эту ошибку; итоговые счётчики возвращаются в любом случае.
+func Scan(ctx context.Context, root string, options ScanOptions, handle func(ScanResult) error) (ScanProgress, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	allowed := options.Extensions
+	if len(allowed) == 0 {
+		allowed = AudioExtensions
+	}
+
+	extensions := make(map[string]bool, len(allowed))
+	for _, ext := range allowed {
+		extensions[strings.ToLower(ext)] = true
+	}
+
+	var (
+		found    atomic.Int64
+		walkDone atomic.Bool
+		walkErr  error
+		paths    = make(chan string, workers)
+		results  = make(chan ScanResult, workers)
+		wg       sync.WaitGroup
+	)
+
+	go func() {
+		defer close(paths)
+		defer walkDone.Store(true)
+
+		walkErr = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+
+			if err != nil {
+				// Без корня сканировать нечего; нечитаемый подкаталог — ошибка только этого каталога.
+				if path == root {
+					return err
+				}
+
+				found.Add(1)
+
+				return sendScanResult(ctx, results, ScanResult{Path: path, Err: err})
+			}
+
+			if d.IsDir() || !d.Type().IsRegular() || !extensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+
+			found.Add(1)
+
+			select {
+			case paths <- path:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	for range workers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for path := range paths {
+				meta, err := probeFile(ctx, path)
+				if ctx.Err() != nil {
+					return
+				}
+
+				if sendScanResult(ctx, results, ScanResult{Path: path, Meta: meta, Err: err}) != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var (
+		progress  ScanProgress
+		handleErr error
+	)
+
+	for result := range results {
+		// После ошибки handle результаты только вычитываем, чтобы воркеры могли завершиться.
+		if handleErr != nil {
+			continue
+		}
+
+		progress.Done++
+
+		if result.Err != nil {
+			progress.Failed++
+		} else {
+			progress.Bytes += result.Meta.Size
+		}
+
+		progress.Found = found.Load()
+		progress.WalkDone = walkDone.Load()
+		result.Progress = progress
+
+		if handleErr = handle(result); handleErr != nil {
+			cancel()
+		}
+	}
+
+	progress.Found = found.Load()
+	progress.WalkDone = true
+
+	if handleErr != nil {
+		return progress, handleErr
+	}
+
+	if err := ctx.Err(); err != nil {
+		return progress, err
+	}
+
+	return progress, walkErr
+}
+
+func sendScanResult(ctx context.Context, results chan<- ScanResult, result ScanResult) error {
+	select {
+	case results <- result:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// probeFile разбирает файл через Probe. Чтение идёт через contextReaderAt, поэтому отмена ctx прерывает даже
+// долгий разбор (например, обход всех фреймов MP3 без Xing).
+func probeFile(ctx context.Context, path string) (*TrackFileMetadata, error) {
+	return parseFile(path, "audio", func(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+		return Probe(contextReaderAt{ctx: ctx, r: r}, size)
+	})
+}
+
+// contextReaderAt проверяет отмену контекста перед каждым чтением.
+type contextReaderAt struct {
+	ctx context.Context
+	r   io.ReaderAt
+}
+
+func (c contextReaderAt) ReadAt(p []byte, offset int64) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.r.ReadAt(p, offset)
+}
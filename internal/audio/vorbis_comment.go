@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"strings"
+)
+
+// vorbisPictureTag тег, в котором Ogg и иногда FLAC хранят картинку: блок PICTURE FLAC, закодированный в base64.
+const vorbisPictureTag = "metadata_block_picture"
+
+// tagSeparator разделитель значений повторяющегося тега (например, нескольких GENRE).
+const tagSeparator = "; "
+
+var errVorbisCommentInvalid = errors.New("invalid vorbis comment")
+
+// parseVorbisComment разбирает Vorbis comment (блок VORBIS_COMMENT FLAC, заголовок комментариев Vorbis и OpusTags
+// без своих сигнатур): длина и строка vendor, число полей и сами поля вида KEY=value, все длины little-endian.
+// Ключи приводятся к нижнему регистру, значения повторяющихся ключей склеиваются через tagSeparator. Картинки из
+// METADATA_BLOCK_PICTURE попадают не в теги, а возвращаются отдельно вместе с типом.
+func parseVorbisComment(b []byte, meta *TrackFileMetadata) ([]typedPicture, error) {
+	vendor, b, ok := cutVorbisString(b)
+	if !ok {
+		return nil, errVorbisCommentInvalid
+	}
+
+	if len(b) < 4 {
+		return nil, errVorbisCommentInvalid
+	}
+
+	count := binary.LittleEndian.Uint32(b[:4])
+	b = b[4:]
+
+	var pictures []typedPicture
+
+	for i := uint32(0); i < count; i++ {
+		var field string
+
+		field, b, ok = cutVorbisString(b)
+		if !ok {
+			return nil, errVorbisCommentInvalid
+		}
+
+		key, value, found := strings.Cut(field, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		if !found || key == "" || value == "" {
+			continue
+		}
+
+		if key == vorbisPictureTag {
+			// Битая картинка не повод отказываться от остальных тегов.
+			data, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				continue
+			}
+
+			if picture, err := parseFlacPicture(data); err == nil {
+				pictures = append(pictures, picture)
+			}
+
+			continue
+		}
+
+		appendTag(meta, key, value)
+	}
+
+	if vendor != "" {
+		setTag(meta, "encoder", vendor)
+	}
+
+	return pictures, nil
+}
+
+// cutVorbisString отрезает от b строку с 32-битной little-endian длиной.
+func cutVorbisString(b []byte) (string, []byte, bool) {
+	if len(b) < 4 {
+		return "", nil, false
+	}
+
+	length := binary.LittleEndian.Uint32(b[:4])
+	if uint64(length) > uint64(len(b)-4) {
+		return "", nil, false
+	}
+
+	return string(b[4 : 4+length]), b[4+length:], true
+}
+
+// appendTag добавляет значение тега, а если ключ уже есть, дописывает его через tagSeparator.
+func appendTag(meta *TrackFileMetadata, key, value string) {
+	if existing, ok := meta.Tags[key]; ok {
+		meta.Tags[key] = existing + tagSeparator + value
+		return
+	}
+
+	setTag(meta, key, value)
+}
+
+// SplitTag разбивает значение тега, склеенное из нескольких через tagSeparator (или записанное так самим
+// пользователем), на отдельные непустые значения.
+func SplitTag(value string) []string {
+	var values []string
+
+	for _, v := range strings.Split(value, ";") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
@@ -0,0 +1,263 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// wavFrameSamples сколько сэмплов на канал WavDecoder отдаёт за один ReadFrame.
+const wavFrameSamples = 4096
+
+// wavFloatBits разрядность, в которую WavDecoder переводит IEEE float.
+const wavFloatBits = 24
+
+// WavDecoder последовательно читает PCM из WAV (RIFF, RF64/BW64): целые 8–32 бита и IEEE float 32/64 бита, в том
+// числе в обёртке WAVE_FORMAT_EXTENSIBLE. Float переводится в 24-битные целые.
+type WavDecoder struct {
+	r          *bufio.Reader
+	meta       TrackFileMetadata
+	formatTag  uint16
+	blockAlign int
+	// containerBits ширина ячейки одного сэмпла в битах; значащие биты (meta.BitsPerSample) выровнены по старшим.
+	containerBits int
+	// remaining сколько байт осталось в chunk data; -1, если размер не известен и читать нужно до конца потока.
+	remaining    int64
+	totalSamples uint64
+
+	buf []byte
+	out [][]int32
+}
+
+// NewWavDecoder читает заголовок и chunk до начала data. Источник читается строго последовательно, поэтому chunk
+// "fmt " обязан идти раньше data, как это делают все известные кодеры.
+func NewWavDecoder(r io.Reader) (*WavDecoder, error) {
+	d := &WavDecoder{r: bufio.NewReaderSize(r, 64<<10)}
+
+	var header [12]byte
+	if _, err := io.ReadFull(d.r, header[:]); err != nil {
+		return nil, fmt.Errorf("reading wav header: %w", err)
+	}
+
+	riff := string(header[:4])
+	if (riff != "RIFF" && riff != "RF64" && riff != "BW64") || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: signature %q", errWavInvalid, header[:])
+	}
+
+	var (
+		ds64Data  int64 = -1
+		hasFormat bool
+	)
+
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(d.r, chunk[:]); err != nil {
+			return nil, fmt.Errorf("%w: data chunk not found: %w", errWavInvalid, err)
+		}
+
+		id := string(chunk[:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+
+		switch id {
+		case "ds64":
+			if chunkSize > textChunkLimit {
+				return nil, fmt.Errorf("%w: ds64 chunk too large", errWavInvalid)
+			}
+
+			b := make([]byte, chunkSize)
+			if _, err := io.ReadFull(d.r, b); err != nil || len(b) < 16 {
+				return nil, fmt.Errorf("%w: truncated ds64 chunk", errWavInvalid)
+			}
+
+			ds64Data = int64(binary.LittleEndian.Uint64(b[8:16]))
+		case "fmt ":
+			if chunkSize > textChunkLimit {
+				return nil, fmt.Errorf("%w: fmt chunk too large", errWavInvalid)
+			}
+
+			b := make([]byte, chunkSize)
+			if _, err := io.ReadFull(d.r, b); err != nil {
+				return nil, fmt.Errorf("reading wav fmt chunk: %w", err)
+			}
+
+			blockAlign, formatTag, err := parseWavFormat(b, &d.meta)
+			if err != nil {
+				return nil, err
+			}
+
+			d.blockAlign, d.formatTag = blockAlign, formatTag
+			d.containerBits = blockAlign / d.meta.Channels * 8
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return nil, fmt.Errorf("%w: data chunk before fmt", errWavInvalid)
+			}
+
+			if err := d.checkFormat(); err != nil {
+				return nil, err
+			}
+
+			d.remaining = chunkSize
+
+			switch {
+			case chunkSize == rf64SizePlaceholder && ds64Data >= 0:
+				d.remaining = ds64Data
+			case chunkSize == rf64SizePlaceholder, chunkSize == 0:
+				// Поток писался без перемотки, и размер так и не проставлен.
+				d.remaining = -1
+			}
+
+			if d.remaining > 0 {
+				d.totalSamples = uint64(d.remaining / int64(d.blockAlign))
+			}
+
+			return d, nil
+		default:
+			if _, err := io.CopyN(io.Discard, d.r, chunkSize); err != nil {
+				return nil, fmt.Errorf("%w: data chunk not found: %w", errWavInvalid, err)
+			}
+		}
+
+		if chunkSize&1 != 0 {
+			if _, err := d.r.Discard(1); err != nil {
+				return nil, fmt.Errorf("%w: data chunk not found: %w", errWavInvalid, err)
+			}
+		}
+	}
+}
+
+func (d *WavDecoder) checkFormat() error {
+	bits, container := d.meta.BitsPerSample, d.containerBits
+
+	if d.blockAlign%d.meta.Channels != 0 || bits <= 0 || bits > container {
+		return fmt.Errorf("%w: %d-bit samples in %d-byte blocks", errWavInvalid, bits, d.blockAlign)
+	}
+
+	switch {
+	case d.formatTag == wavFormatIEEEFloat && (container == 32 || container == 64):
+	case d.formatTag == wavFormatPCM && container >= 8 && container <= 32:
+	default:
+		return fmt.Errorf("%w: %d-bit container", errWavUnsupported, container)
+	}
+
+	return nil
+}
+
+func (d *WavDecoder) SampleRate() int {
+	return d.meta.SampleRate
+}
+
+func (d *WavDecoder) Channels() int {
+	return d.meta.Channels
+}
+
+// BitsPerSample разрядность сэмплов, которые возвращает ReadFrame: для float это wavFloatBits.
+func (d *WavDecoder) BitsPerSample() int {
+	if d.formatTag == wavFormatIEEEFloat {
+		return wavFloatBits
+	}
+
+	return d.meta.BitsPerSample
+}
+
+// TotalSamples число сэмплов на канал по размеру chunk data; 0, если размер не записан.
+func (d *WavDecoder) TotalSamples() uint64 {
+	return d.totalSamples
+}
+
+// ReadFrame возвращает следующие до wavFrameSamples сэмплов по каналам. Срезы переиспользуются и действительны
+// только до следующего вызова. В конце data возвращает io.EOF; неполный последний блок отбрасывается.
+func (d *WavDecoder) ReadFrame() ([][]int32, error) {
+	want := int64(wavFrameSamples * d.blockAlign)
+	if d.remaining >= 0 {
+		want = min(want, d.remaining-d.remaining%int64(d.blockAlign))
+	}
+
+	if want == 0 {
+		return nil, io.EOF
+	}
+
+	if cap(d.buf) < int(want) {
+		d.buf = make([]byte, want)
+	}
+
+	// Как и parseWav, недописанный файл не считаем ошибкой: data просто заканчивается раньше, чем обещал заголовок.
+	n, err := io.ReadFull(d.r, d.buf[:want])
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, err
+	}
+
+	if d.remaining >= 0 {
+		d.remaining -= int64(n)
+		if err != nil {
+			d.remaining = 0
+		}
+	}
+
+	samples := n / d.blockAlign
+	if samples == 0 {
+		return nil, io.EOF
+	}
+
+	d.decode(d.buf[:samples*d.blockAlign], samples)
+
+	return d.out, nil
+}
+
+func (d *WavDecoder) decode(b []byte, samples int) {
+	channels := d.meta.Channels
+
+	if len(d.out) != channels || cap(d.out[0]) < samples {
+		d.out = make([][]int32, channels)
+		for c := range d.out {
+			d.out[c] = make([]int32, wavFrameSamples)
+		}
+	}
+
+	for c := range d.out {
+		d.out[c] = d.out[c][:samples]
+	}
+
+	width := d.containerBits / 8
+	shift := d.containerBits - d.meta.BitsPerSample
+
+	for i := range samples {
+		for c := range channels {
+			cell := b[(i*channels+c)*width:]
+
+			var v int32
+
+			switch {
+			case d.formatTag == wavFormatIEEEFloat && width == 4:
+				v = floatToPCM(float64(math.Float32frombits(binary.LittleEndian.Uint32(cell))))
+			case d.formatTag == wavFormatIEEEFloat:
+				v = floatToPCM(math.Float64frombits(binary.LittleEndian.Uint64(cell)))
+			case width == 1:
+				// 8-битный WAV беззнаковый.
+				v = int32(cell[0]) - 128
+			case width == 2:
+				v = int32(int16(binary.LittleEndian.Uint16(cell))) >> shift
+			case width == 3:
+				v = int32(uint32(cell[0])<<8|uint32(cell[1])<<16|uint32(cell[2])<<24) >> (8 + shift)
+			default:
+				v = int32(binary.LittleEndian.Uint32(cell)) >> shift
+			}
+
+			d.out[c][i] = v
+		}
+	}
+}
+
+// floatToPCM переводит сэмпл из [-1, 1] в wavFloatBits-битное целое с насыщением.
+func floatToPCM(f float64) int32 {
+	const scale = 1 << (wavFloatBits - 1)
+
+	if math.IsNaN(f) {
+		return 0
+	}
+
+	return int32(math.Max(-scale, math.Min(scale-1, math.Round(f*scale))))
+}
@@ -0,0 +1,146 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// wavSubFormatPCM хвост GUID KSDATAFORMAT_SUBTYPE_PCM после двухбайтового format tag.
+var wavSubFormatPCM = []byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71}
+
+var errWavTooLarge = errors.New("wav data exceeds 4 GiB")
+
+// WavEncoder пишет целочисленный PCM в WAV. Размеры в заголовке считаются по totalSamples заранее, поэтому поток
+// не требует перемотки; сколько сэмплов записано на самом деле, проверяет Close.
+type WavEncoder struct {
+	w        io.Writer
+	channels int
+	// width ширина ячейки сэмпла в байтах, shift — сдвиг значащих бит к старшим (как требует WAV).
+	width int
+	shift int
+
+	totalSamples uint64
+	written      uint64
+	buffer       []byte
+}
+
+// NewWavEncoder сразу пишет заголовок. Для разрядности больше 16 бит или больше двух каналов используется
+// WAVE_FORMAT_EXTENSIBLE, иначе — обычный PCM.
+func NewWavEncoder(w io.Writer, sampleRate, channels, bitsPerSample int, totalSamples uint64) (*WavEncoder, error) {
+	if channels <= 0 || sampleRate <= 0 || bitsPerSample < 8 || bitsPerSample > 32 {
+		return nil, fmt.Errorf("%w: %d channels, %d Hz, %d bits", errWavUnsupported, channels, sampleRate, bitsPerSample)
+	}
+
+	width := (bitsPerSample + 7) / 8
+	blockAlign := width * channels
+
+	dataSize := totalSamples * uint64(blockAlign)
+	if dataSize+dataSize&1 > math.MaxUint32-80 {
+		return nil, errWavTooLarge
+	}
+
+	extensible := bitsPerSample > 16 || channels > 2
+
+	fmtSize := 16
+	if extensible {
+		fmtSize = 40
+	}
+
+	header := make([]byte, 0, 20+fmtSize+8)
+	header = append(header, "RIFF"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(4+8+fmtSize+8+int(dataSize+dataSize&1)))
+	header = append(header, "WAVEfmt "...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(fmtSize))
+
+	formatTag := uint16(wavFormatPCM)
+	if extensible {
+		formatTag = wavFormatExtensible
+	}
+
+	header = binary.LittleEndian.AppendUint16(header, formatTag)
+	header = binary.LittleEndian.AppendUint16(header, uint16(channels))
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate*blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, uint16(width*8))
+
+	if extensible {
+		header = binary.LittleEndian.AppendUint16(header, 22)
+		header = binary.LittleEndian.AppendUint16(header, uint16(bitsPerSample))
+		// Маску каналов не указываем: раскладку исходника мы не знаем.
+		header = binary.LittleEndian.AppendUint32(header, 0)
+		header = binary.LittleEndian.AppendUint16(header, wavFormatPCM)
+		header = append(header, wavSubFormatPCM...)
+	}
+
+	header = append(header, "data"...)
+	header = binary.LittleEndian.AppendUint32(header, uint32(dataSize))
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &WavEncoder{
+		w:            w,
+		channels:     channels,
+		width:        width,
+		shift:        width*8 - bitsPerSample,
+		totalSamples: totalSamples,
+	}, nil
+}
+
+// Write дописывает порцию сэмплов по каналам, в том же виде, в каком её отдаёт PCMDecoder.ReadFrame.
+func (e *WavEncoder) Write(frame [][]int32) error {
+	if len(frame) != e.channels {
+		return fmt.Errorf("wav encoder: frame has %d channels, want %d", len(frame), e.channels)
+	}
+
+	samples := len(frame[0])
+	if e.written+uint64(samples) > e.totalSamples {
+		return fmt.Errorf("wav encoder: more than %d samples written", e.totalSamples)
+	}
+
+	e.buffer = e.buffer[:0]
+
+	for i := range samples {
+		for _, channel := range frame {
+			v := channel[i] << e.shift
+
+			switch e.width {
+			case 1:
+				// 8-битный WAV беззнаковый.
+				e.buffer = append(e.buffer, byte(v+128))
+			case 2:
+				e.buffer = binary.LittleEndian.AppendUint16(e.buffer, uint16(v))
+			case 3:
+				e.buffer = append(e.buffer, byte(v), byte(v>>8), byte(v>>16))
+			default:
+				e.buffer = binary.LittleEndian.AppendUint32(e.buffer, uint32(v))
+			}
+		}
+	}
+
+	e.written += uint64(samples)
+
+	_, err := e.w.Write(e.buffer)
+
+	return err
+}
+
+// Close дописывает байт выравнивания data-чанка. Если записано меньше сэмплов, чем обещано в заголовке, файл
+// получился бы битым, поэтому это ошибка.
+func (e *WavEncoder) Close() error {
+	if e.written != e.totalSamples {
+		return fmt.Errorf("wav encoder: %d samples written, header promises %d", e.written, e.totalSamples)
+	}
+
+	if e.totalSamples*uint64(e.width*e.channels)&1 == 1 {
+		_, err := e.w.Write([]byte{0})
+		return err
+	}
+
+	return nil
+}
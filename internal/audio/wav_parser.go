@@ -0,0 +1,208 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatIEEEFloat  = 0x0003
+	wavFormatExtensible = 0xFFFE
+	// rf64SizePlaceholder значение 32-битного размера, которое в RF64/BW64 означает «смотри chunk ds64».
+	rf64SizePlaceholder = 0xFFFFFFFF
+	// textChunkLimit больше этого теги в LIST/INFO и текстовых chunk AIFF не читаются: там бывают мегабайты мусора.
+	textChunkLimit = 1 << 20
+)
+
+var (
+	errWavInvalid     = errors.New("invalid wav file")
+	errWavUnsupported = errors.New("unsupported wav encoding")
+
+	// wavInfoTags соответствие идентификаторов LIST/INFO ключам TrackFileMetadata.Tags.
+	wavInfoTags = map[string]string{
+		"INAM": "title",
+		"IART": "artist",
+		"IPRD": "album",
+		"ICRD": "date",
+		"IGNR": "genre",
+		"ICMT": "comment",
+		"ITRK": "tracknumber",
+		"IPRT": "tracknumber",
+		"ICOP": "copyright",
+		"ISFT": "encoder",
+	}
+)
+
+// ParseWavFile разбирает WAV (RIFF), а также RF64/BW64 для файлов больше 4 ГиБ. Поддерживается PCM и IEEE float,
+// в том числе в обёртке WAVE_FORMAT_EXTENSIBLE; длительность считается точно по размеру chunk data.
+func ParseWavFile(filePath string) (*TrackFileMetadata, error) {
+	return parseFile(filePath, "wav", parseWav)
+}
+
+func parseWav(r io.ReaderAt, size int64) (*TrackFileMetadata, error) {
+	var header [12]byte
+	if _, err := r.ReadAt(header[:], 0); err != nil {
+		return nil, fmt.Errorf("reading wav header: %w", err)
+	}
+
+	riff := string(header[:4])
+	if (riff != "RIFF" && riff != "RF64" && riff != "BW64") || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("%w: signature %q", errWavInvalid, header[:])
+	}
+
+	var (
+		meta            = &TrackFileMetadata{Format: domain.FormatWAV, Codec: domain.CodecWAV, Mime: MimeWAV, Size: size}
+		dataSize  int64 = -1
+		ds64Data  int64 = -1
+		blockSize int
+		hasFormat bool
+	)
+
+	for offset := int64(12); offset+8 <= size; {
+		var chunk [8]byte
+		if _, err := r.ReadAt(chunk[:], offset); err != nil {
+			return nil, fmt.Errorf("reading wav chunk header: %w", err)
+		}
+
+		id := string(chunk[:4])
+		chunkSize := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		body := offset + 8
+
+		switch id {
+		case "ds64":
+			// ds64: 64-битные размеры RIFF, data и число сэмплов; идёт первым chunk в RF64.
+			b, err := readAtMost(r, make([]byte, 16), body)
+			if err != nil || len(b) < 16 {
+				return nil, fmt.Errorf("%w: truncated ds64 chunk", errWavInvalid)
+			}
+
+			ds64Data = int64(binary.LittleEndian.Uint64(b[8:16]))
+		case "fmt ":
+			b, err := readAtMost(r, make([]byte, min(chunkSize, 40)), body)
+			if err != nil {
+				return nil, fmt.Errorf("reading wav fmt chunk: %w", err)
+			}
+
+			blockSize, _, err = parseWavFormat(b, meta)
+			if err != nil {
+				return nil, err
+			}
+
+			hasFormat = true
+		case "data":
+			dataSize = chunkSize
+			if chunkSize == rf64SizePlaceholder && ds64Data >= 0 {
+				dataSize = ds64Data
+			}
+
+			// У недописанных файлов размер в заголовке больше реального; считаем только то, что есть.
+			dataSize = min(dataSize, size-body)
+			chunkSize = dataSize
+		case "LIST":
+			if err := parseWavInfo(r, body, min(chunkSize, size-body, textChunkLimit), meta); err != nil {
+				return nil, err
+			}
+		}
+
+		// Chunk выравниваются по чётной границе.
+		offset = body + chunkSize + chunkSize&1
+	}
+
+	if !hasFormat || dataSize < 0 {
+		return nil, fmt.Errorf("%w: missing fmt or data chunk", errWavInvalid)
+	}
+
+	meta.TotalSamples = uint64(dataSize / int64(blockSize))
+	meta.Duration = time.Duration(float64(meta.TotalSamples) / float64(meta.SampleRate) * float64(time.Second))
+	meta.Bitrate = meta.SampleRate * meta.BitsPerSample * meta.Channels / 1000
+
+	return meta, nil
+}
+
+// parseWavFormat разбирает chunk "fmt " (WAVEFORMATEX/WAVEFORMATEXTENSIBLE) и возвращает размер блока (одного
+// сэмпла по всем каналам) в байтах и итоговый format tag: wavFormatPCM или wavFormatIEEEFloat.
+func parseWavFormat(b []byte, meta *TrackFileMetadata) (int, uint16, error) {
+	if len(b) < 16 {
+		return 0, 0, fmt.Errorf("%w: fmt chunk too short", errWavInvalid)
+	}
+
+	formatTag := binary.LittleEndian.Uint16(b[0:2])
+	meta.Channels = int(binary.LittleEndian.Uint16(b[2:4]))
+	meta.SampleRate = int(binary.LittleEndian.Uint32(b[4:8]))
+	blockAlign := int(binary.LittleEndian.Uint16(b[12:14]))
+	meta.BitsPerSample = int(binary.LittleEndian.Uint16(b[14:16]))
+
+	if formatTag == wavFormatExtensible {
+		if len(b) < 40 {
+			return 0, 0, fmt.Errorf("%w: extensible fmt chunk too short", errWavInvalid)
+		}
+
+		// Контейнер может быть шире значащих бит (например, 24 бита в 32-битных ячейках).
+		if validBits := int(binary.LittleEndian.Uint16(b[18:20])); validBits > 0 {
+			meta.BitsPerSample = validBits
+		}
+
+		meta.ChannelMask = binary.LittleEndian.Uint32(b[20:24])
+		// Первые два байта GUID подформата совпадают с обычным format tag.
+		formatTag = binary.LittleEndian.Uint16(b[24:26])
+	}
+
+	if formatTag != wavFormatPCM && formatTag != wavFormatIEEEFloat {
+		return 0, 0, fmt.Errorf("%w: format tag 0x%04x", errWavUnsupported, formatTag)
+	}
+
+	if meta.Channels == 0 || meta.SampleRate == 0 || blockAlign == 0 {
+		return 0, 0, fmt.Errorf("%w: zero channels, sample rate or block align", errWavInvalid)
+	}
+
+	return blockAlign, formatTag, nil
+}
+
+// parseWavInfo читает теги из LIST/INFO. Остальные LIST (например, adtl с метками) пропускаются.
+func parseWavInfo(r io.ReaderAt, offset, size int64, meta *TrackFileMetadata) error {
+	b, err := readAtMost(r, make([]byte, size), offset)
+	if err != nil {
+		return fmt.Errorf("reading wav LIST chunk: %w", err)
+	}
+
+	if len(b) < 4 || string(b[:4]) != "INFO" {
+		return nil
+	}
+
+	for b = b[4:]; len(b) >= 8; {
+		id := string(b[:4])
+		length := int(binary.LittleEndian.Uint32(b[4:8]))
+
+		if 8+length > len(b) {
+			break
+		}
+
+		value := strings.TrimSpace(string(bytes.TrimRight(b[8:8+length], "\x00")))
+		if key, ok := wavInfoTags[id]; ok && value != "" {
+			setTag(meta, key, value)
+		}
+
+		b = b[min(8+length+length&1, len(b)):]
+	}
+
+	return nil
+}
+
+// setTag добавляет тег, не перезаписывая уже найденное значение.
+func setTag(meta *TrackFileMetadata, key, value string) {
+	if meta.Tags == nil {
+		meta.Tags = make(map[string]string)
+	}
+
+	if _, ok := meta.Tags[key]; !ok {
+		meta.Tags[key] = value
+	}
+}
@@ -0,0 +1,255 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+const (
+	// WaveformMaxPoints ориентир числа точек самого подробного уровня: от WaveformMaxPoints до вдвое большего
+	// (меньше только у очень коротких файлов).
+	WaveformMaxPoints = 4096
+	// waveformLevelFactor во сколько раз каждый следующий уровень грубее предыдущего.
+	waveformLevelFactor = 4
+	// waveformLevels сколько уровней детализации хранится.
+	waveformLevels = 3
+
+	waveformMagic   = "BBWF"
+	waveformVersion = 1
+)
+
+var errWaveformInvalid = errors.New("invalid waveform data")
+
+// WaveformLevel один уровень детализации: пары (min, max) 16-битных пиков подряд, каждая пара покрывает
+// SamplesPerPoint сэмплов (последняя — остаток).
+type WaveformLevel struct {
+	SamplesPerPoint int
+	Peaks           []int16
+}
+
+// Points число точек уровня.
+func (l WaveformLevel) Points() int {
+	return len(l.Peaks) / 2
+}
+
+// Waveform обзор формы волны для плеера: пики по всем каналам сразу, приведённые к 16 битам. Levels идут от
+// самого подробного к самому грубому.
+type Waveform struct {
+	SampleRate int
+	// Samples сколько сэмплов на канал реально декодировано.
+	Samples uint64
+	Levels  []WaveformLevel
+}
+
+// ComputeWaveform декодирует поток до конца и считает пики на нескольких уровнях детализации. Шаг самого
+// подробного уровня выбирается по TotalSamples, а если длина не известна (или заголовок соврал), точки по ходу
+// попарно сливаются, чтобы их оставалось не больше 2*WaveformMaxPoints.
+func ComputeWaveform(decoder PCMDecoder) (*Waveform, error) {
+	shift := decoder.BitsPerSample() - 16
+
+	spp := 1
+	if total := decoder.TotalSamples(); total > 0 {
+		spp = int(max(1, (total+WaveformMaxPoints-1)/WaveformMaxPoints))
+	}
+
+	var (
+		peaks   = make([]int16, 0, 4*WaveformMaxPoints)
+		samples uint64
+		// filled сколько сэмплов уже вошло в текущую точку, lo и hi — её пики.
+		filled int
+		lo, hi int32 = math.MaxInt32, math.MinInt32
+	)
+
+	for {
+		frame, err := decoder.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(frame) == 0 {
+			continue
+		}
+
+		for i := range frame[0] {
+			for _, channel := range frame {
+				lo, hi = min(lo, channel[i]), max(hi, channel[i])
+			}
+
+			if filled++; filled < spp {
+				continue
+			}
+
+			peaks = append(peaks, int16(scaleTo16(lo, shift)), int16(scaleTo16(hi, shift)))
+			lo, hi, filled = math.MaxInt32, math.MinInt32, 0
+
+			if len(peaks) >= 4*WaveformMaxPoints {
+				peaks = mergePeaks(peaks, 2)
+				spp *= 2
+			}
+		}
+
+		samples += uint64(len(frame[0]))
+	}
+
+	if filled > 0 {
+		peaks = append(peaks, int16(scaleTo16(lo, shift)), int16(scaleTo16(hi, shift)))
+	}
+
+	waveform := &Waveform{
+		SampleRate: decoder.SampleRate(),
+		Samples:    samples,
+		Levels:     []WaveformLevel{{SamplesPerPoint: spp, Peaks: peaks}},
+	}
+
+	for range waveformLevels - 1 {
+		last := waveform.Levels[len(waveform.Levels)-1]
+		if last.Points() <= 1 {
+			break
+		}
+
+		waveform.Levels = append(waveform.Levels, WaveformLevel{
+			SamplesPerPoint: last.SamplesPerPoint * waveformLevelFactor,
+			Peaks:           mergePeaks(last.Peaks, waveformLevelFactor),
+		})
+	}
+
+	return waveform, nil
+}
+
+// scaleTo16 приводит сэмпл к 16 битам: shift — на сколько бит исходная разрядность больше 16 (или меньше, если
+// отрицательный).
+func scaleTo16(v int32, shift int) int32 {
+	if shift >= 0 {
+		return v >> shift
+	}
+
+	return v << -shift
+}
+
+// mergePeaks сливает каждые factor точек в одну. Результат пишется в новый срез, исходный не меняется.
+func mergePeaks(peaks []int16, factor int) []int16 {
+	points := len(peaks) / 2
+	merged := make([]int16, 0, (points+factor-1)/factor*2)
+
+	for from := 0; from < points; from += factor {
+		merged = append(merged, mergeRange(peaks, from, min(from+factor, points))...)
+	}
+
+	return merged
+}
+
+// mergeRange возвращает пару (min, max) по точкам [from, to).
+func mergeRange(peaks []int16, from, to int) []int16 {
+	lo, hi := peaks[2*from], peaks[2*from+1]
+
+	for i := from + 1; i < to; i++ {
+		lo, hi = min(lo, peaks[2*i]), max(hi, peaks[2*i+1])
+	}
+
+	return []int16{lo, hi}
+}
+
+// Resample возвращает пики ровно на points точек (или меньше, если столько нет даже на самом подробном уровне)
+// и сколько сэмплов в среднем приходится на точку. Берётся самый грубый уровень, которого хватает, и его точки
+// сливаются группами.
+func (w *Waveform) Resample(points int) (int, []int16) {
+	if len(w.Levels) == 0 || points <= 0 {
+		return 0, nil
+	}
+
+	level := w.Levels[0]
+
+	for i := len(w.Levels) - 1; i >= 0; i-- {
+		if w.Levels[i].Points() >= points {
+			level = w.Levels[i]
+			break
+		}
+	}
+
+	n := level.Points()
+	if points >= n {
+		return level.SamplesPerPoint, level.Peaks
+	}
+
+	peaks := make([]int16, 0, 2*points)
+	for i := range points {
+		peaks = append(peaks, mergeRange(level.Peaks, i*n/points, (i+1)*n/points)...)
+	}
+
+	return int((w.Samples + uint64(points) - 1) / uint64(points)), peaks
+}
+
+// MarshalBinary кодирует обзор в компактный формат для хранения: сигнатура "BBWF", версия, частота, число
+// сэмплов и уровни (шаг, число точек, пары int16), все числа little-endian.
+func (w *Waveform) MarshalBinary() ([]byte, error) {
+	size := 4 + 1 + 4 + 8 + 1
+	for _, level := range w.Levels {
+		size += 8 + 2*len(level.Peaks)
+	}
+
+	b := make([]byte, 0, size)
+	b = append(b, waveformMagic...)
+	b = append(b, waveformVersion)
+	b = binary.LittleEndian.AppendUint32(b, uint32(w.SampleRate))
+	b = binary.LittleEndian.AppendUint64(b, w.Samples)
+	b = append(b, byte(len(w.Levels)))
+
+	for _, level := range w.Levels {
+		b = binary.LittleEndian.AppendUint32(b, uint32(level.SamplesPerPoint))
+		b = binary.LittleEndian.AppendUint32(b, uint32(level.Points()))
+
+		for _, peak := range level.Peaks {
+			b = binary.LittleEndian.AppendUint16(b, uint16(peak))
+		}
+	}
+
+	return b, nil
+}
+
+func (w *Waveform) UnmarshalBinary(b []byte) error {
+	if len(b) < 18 || string(b[:4]) != waveformMagic {
+		return errWaveformInvalid
+	}
+
+	if b[4] != waveformVersion {
+		return fmt.Errorf("%w: version %d", errWaveformInvalid, b[4])
+	}
+
+	w.SampleRate = int(binary.LittleEndian.Uint32(b[5:9]))
+	w.Samples = binary.LittleEndian.Uint64(b[9:17])
+	count := int(b[17])
+	b = b[18:]
+
+	w.Levels = make([]WaveformLevel, 0, count)
+
+	for range count {
+		if len(b) < 8 {
+			return errWaveformInvalid
+		}
+
+		level := WaveformLevel{SamplesPerPoint: int(binary.LittleEndian.Uint32(b[:4]))}
+		points := int(binary.LittleEndian.Uint32(b[4:8]))
+		b = b[8:]
+
+		if len(b)/4 < points {
+			return errWaveformInvalid
+		}
+
+		level.Peaks = make([]int16, 2*points)
+		for i := range level.Peaks {
+			level.Peaks[i] = int16(binary.LittleEndian.Uint16(b[2*i:]))
+		}
+
+		b = b[4*points:]
+		w.Levels = append(w.Levels, level)
+	}
+
+	return nil
+}
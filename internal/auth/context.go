@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+type principalKey struct{}
+
+// WithPrincipal кладёт в контекст того, от чьего имени выполняется запрос.
+func WithPrincipal(ctx context.Context, principal *domain.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext возвращает principal запроса; false — запрос анонимный.
+func PrincipalFromContext(ctx context.Context) (*domain.Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*domain.Principal)
+
+	return principal, ok && principal != nil
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Параметры argon2id по рекомендации OWASP для серверов: 64 МиБ памяти, 3 прохода. Они записываются в сам хеш,
+// так что их можно менять, не ломая проверку старых паролей.
+const (
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 2
+	argon2KeyLen  = 32
+	argon2SaltLen = 16
+)
+
+// Пределы параметров хеша, который принимает VerifyPassword. Хеш читается из БД, а раньше пароль хешировал клиент,
+// так что параметры в нём могут быть любыми: t=0 или p=0 роняют argon2.IDKey, а огромный m заставил бы выделять
+// гигабайты памяти на каждую попытку входа. Пределы с запасом покрывают рост параметров выше текущих.
+const (
+	argon2MaxMemory  = 256 * 1024
+	argon2MaxTime    = 10
+	argon2MaxThreads = 16
+	argon2MinSaltLen = 8
+	argon2MaxSaltLen = 64
+	argon2MinKeyLen  = 16
+	argon2MaxKeyLen  = 64
+)
+
+var errPasswordHashInvalid = errors.New("invalid password hash")
+
+// HashPassword хеширует пароль argon2id со случайной солью. Хеш записывается в стандартном PHC-формате:
+// $argon2id$v=19$m=65536,t=3,p=2$<соль>$<хеш>.
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, argon2Time, argon2Memory, argon2Threads, argon2KeyLen)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argon2Memory, argon2Time, argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword сверяет пароль с хешем из HashPassword за время, не зависящее от того, где они разошлись.
+func VerifyPassword(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return false, errPasswordHashInvalid
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, fmt.Errorf("%w: version %q", errPasswordHashInvalid, parts[2])
+	}
+
+	var (
+		memory, time uint32
+		threads      uint8
+	)
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &time, &threads); err != nil {
+		return false, fmt.Errorf("%w: parameters %q", errPasswordHashInvalid, parts[3])
+	}
+
+	// argon2 требует не меньше 8 КиБ памяти на поток.
+	if time < 1 || time > argon2MaxTime ||
+		threads < 1 || threads > argon2MaxThreads ||
+		memory < 8*uint32(threads) || memory > argon2MaxMemory {
+		return false, fmt.Errorf("%w: parameters %q out of range", errPasswordHashInvalid, parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(salt) < argon2MinSaltLen || len(salt) > argon2MaxSaltLen {
+		return false, fmt.Errorf("%w: salt", errPasswordHashInvalid)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) < argon2MinKeyLen || len(key) > argon2MaxKeyLen {
+		return false, fmt.Errorf("%w: key", errPasswordHashInvalid)
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, time, memory, threads, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(actual, key) == 1, nil
+}
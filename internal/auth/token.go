@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrTokenInvalid = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token expired")
+)
+
+// jwtHeader заголовок всех выпускаемых токенов. Другие алгоритмы (в том числе "none") при проверке не принимаются.
+const jwtHeader = `{"alg":"HS256","typ":"JWT"}`
+
+// refreshTokenLen длина refresh-токена в байтах до кодирования.
+const refreshTokenLen = 32
+
+// AccessClaims содержимое access-токена.
+type AccessClaims struct {
+	UserID    uuid.UUID
+	SessionID uuid.UUID
+	ExpiresAt time.Time
+}
+
+type jwtClaims struct {
+	Subject   string `json:"sub"`
+	SessionID string `json:"sid"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// TokenSigner выпускает и проверяет access-токены: JWT, подписанные HMAC-SHA256 общим секретом сервера.
+type TokenSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewTokenSigner(secret []byte, ttl time.Duration) *TokenSigner {
+	return &TokenSigner{
+		secret: secret,
+		ttl:    ttl,
+	}
+}
+
+// Sign выпускает access-токен пользователя userID в сессии sessionID и возвращает его вместе со временем истечения.
+func (s *TokenSigner) Sign(userID, sessionID uuid.UUID, now time.Time) (string, time.Time, error) {
+	expiresAt := now.Add(s.ttl).Truncate(time.Second)
+
+	payload, err := json.Marshal(jwtClaims{
+		Subject:   userID.String(),
+		SessionID: sessionID.String(),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("marshal claims: %w", err)
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(jwtHeader)) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(s.sign(unsigned)), expiresAt, nil
+}
+
+// Verify проверяет подпись и срок действия токена. Возвращает ErrTokenInvalid или ErrTokenExpired.
+func (s *TokenSigner) Verify(token string, now time.Time) (*AccessClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, s.sign(parts[0]+"."+parts[1])) {
+		return nil, ErrTokenInvalid
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+
+	if err = decodeSegment(parts[0], &header); err != nil || header.Algorithm != "HS256" {
+		return nil, ErrTokenInvalid
+	}
+
+	var claims jwtClaims
+	if err = decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0)
+	if !now.Before(expiresAt) {
+		return nil, ErrTokenExpired
+	}
+
+	return &AccessClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+func (s *TokenSigner) sign(unsigned string) []byte {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsigned))
+
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+// NewRefreshToken случайный непрозрачный refresh-токен. В БД хранится только HashRefreshToken от него.
+func NewRefreshToken() (string, error) {
+	token := make([]byte, refreshTokenLen)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// HashRefreshToken SHA-256 токена в hex. Токен случаен и длинен, поэтому соль и медленный хеш ему не нужны.
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
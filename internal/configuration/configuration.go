@@ -1,9 +1,12 @@
 package configuration
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -11,8 +14,33 @@ import (
 type Configuration struct {
 	DatabaseConnectionURL string
 	HTTPAddress           string
+
+	StorageDriver     string
+	StoragePath       string
+	S3Endpoint        string
+	S3Region          string
+	S3Bucket          string
+	S3AccessKeyID     string
+	S3SecretAccessKey string
+	S3UsePathStyle    bool
+
+	// TranscodeEncoder внешний кодировщик (ffmpeg) для lossy-копий; без него доступны только PCM-копии.
+	TranscodeEncoder string
+	// TranscodeRenditions копии, которые получаются для каждого трека, например "mp3:320,mp3:128".
+	TranscodeRenditions string
+
+	// AuthTokenSecret ключ подписи access-токенов, не короче authTokenSecretMinLen байт.
+	AuthTokenSecret     string
+	AuthAccessTokenTTL  time.Duration
+	AuthRefreshTokenTTL time.Duration
 }
 
+const (
+	authTokenSecretMinLen      = 32
+	defaultAuthAccessTokenTTL  = 15 * time.Minute
+	defaultAuthRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
 func Load() (configuration *Configuration, err error) {
 	if err = godotenv.Load(); err != nil {
 		log.Fatalf("error loading .env file")
@@ -30,8 +58,57 @@ func Load() (configuration *Configuration, err error) {
 		dbUser, dbPassword, dbHost, dbPort, dbName, dbSSLMode,
 	)
 
+	s3UsePathStyle, _ := strconv.ParseBool(os.Getenv("S3_USE_PATH_STYLE"))
+
+	authTokenSecret := os.Getenv("AUTH_TOKEN_SECRET")
+	if len(authTokenSecret) < authTokenSecretMinLen {
+		return nil, fmt.Errorf("AUTH_TOKEN_SECRET must be at least %d bytes", authTokenSecretMinLen)
+	}
+
+	authAccessTokenTTL, err := durationOrDefault("AUTH_ACCESS_TOKEN_TTL", defaultAuthAccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	authRefreshTokenTTL, err := durationOrDefault("AUTH_REFRESH_TOKEN_TTL", defaultAuthRefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Configuration{
 		DatabaseConnectionURL: databaseConnectionURL,
 		HTTPAddress:           os.Getenv("HTTP_ADDRESS"),
+		StorageDriver:         os.Getenv("STORAGE_DRIVER"),
+		StoragePath:           os.Getenv("STORAGE_PATH"),
+		S3Endpoint:            os.Getenv("S3_ENDPOINT"),
+		S3Region:              os.Getenv("S3_REGION"),
+		S3Bucket:              os.Getenv("S3_BUCKET"),
+		S3AccessKeyID:         os.Getenv("S3_ACCESS_KEY_ID"),
+		S3SecretAccessKey:     os.Getenv("S3_SECRET_ACCESS_KEY"),
+		S3UsePathStyle:        s3UsePathStyle,
+		TranscodeEncoder:      os.Getenv("TRANSCODE_ENCODER"),
+		TranscodeRenditions:   os.Getenv("TRANSCODE_RENDITIONS"),
+		AuthTokenSecret:       authTokenSecret,
+		AuthAccessTokenTTL:    authAccessTokenTTL,
+		AuthRefreshTokenTTL:   authRefreshTokenTTL,
 	}, nil
 }
+
+// durationOrDefault читает переменную окружения вида "15m"; пустая — значение по умолчанию.
+func durationOrDefault(key string, fallback time.Duration) (time.Duration, error) {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback, nil
+	}
+
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("parse %s: %w", key, err)
+	}
+
+	if value <= 0 {
+		return 0, errors.New(key + " must be positive")
+	}
+
+	return value, nil
+}
@@ -14,11 +14,18 @@ type Album struct {
 	ReleaseDate *time.Time `db:"release_date" json:"release_date,omitempty"`
 	CreatedAt   *time.Time `db:"created_at"   json:"created_at,omitempty"`
 	UpdatedAt   *time.Time `db:"updated_at"   json:"updated_at,omitempty"`
+
+	IntegratedLoudness  *float64 `db:"integrated_loudness"   json:"integrated_loudness,omitempty"`
+	LoudnessRange       *float64 `db:"loudness_range"        json:"loudness_range,omitempty"`
+	TruePeak            *float64 `db:"true_peak"             json:"true_peak,omitempty"`
+	ReplayGainAlbumGain *float64 `db:"replaygain_album_gain" json:"replaygain_album_gain,omitempty"`
+	ReplayGainAlbumPeak *float64 `db:"replaygain_album_peak" json:"replaygain_album_peak,omitempty"`
 }
 
 type (
+	// CreateAlbumRequest OwnerID заполняет сервис: альбом принадлежит тому, кто его создал.
 	CreateAlbumRequest struct {
-		OwnerID     *uuid.UUID `db:"owner_id"     json:"owner_id,omitempty"`
+		OwnerID     *uuid.UUID `db:"owner_id"     json:"-"`
 		Title       *string    `db:"title"        json:"title,omitempty"`
 		Description *string    `db:"description"  json:"description,omitempty"`
 		ReleaseDate *time.Time `db:"release_date" json:"release_date,omitempty"`
@@ -0,0 +1,66 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Principal тот, от чьего имени выполняется запрос: пользователь и сессия, в которой выдан его access-токен.
+type Principal struct {
+	UserID    uuid.UUID `db:"user_id"`
+	SessionID uuid.UUID `db:"session_id"`
+	Role      Role      `db:"role"`
+}
+
+func (p *Principal) IsAdmin() bool {
+	return p != nil && p.Role == RoleAdmin
+}
+
+// Tokens пара токенов сессии. Access-токен подписан сервером и живёт недолго; refresh-токен непрозрачен, годится
+// на один обмен (POST /auth/refresh) и при нём заменяется новым.
+type Tokens struct {
+	AccessToken           *string    `json:"access_token,omitempty"`
+	AccessTokenExpiresAt  *time.Time `json:"access_token_expires_at,omitempty"`
+	RefreshToken          *string    `json:"refresh_token,omitempty"`
+	RefreshTokenExpiresAt *time.Time `json:"refresh_token_expires_at,omitempty"`
+}
+
+type (
+	// RegisterRequest самостоятельная регистрация: пользователь всегда получает роль RoleUser.
+	RegisterRequest struct {
+		Email       *string `json:"email,omitempty"`
+		Password    *string `json:"password,omitempty"`
+		DisplayName *string `json:"display_name,omitempty"`
+	}
+
+	RegisterResponse struct {
+		User *User `json:"user,omitempty"`
+	}
+)
+
+type (
+	LoginRequest struct {
+		Email     *string `json:"email,omitempty"`
+		Password  *string `json:"password,omitempty"`
+		UserAgent *string `json:"-" header:"User-Agent"`
+	}
+
+	LoginResponse struct {
+		User   *User   `json:"user,omitempty"`
+		Tokens *Tokens `json:"tokens,omitempty"`
+	}
+)
+
+type (
+	RefreshRequest struct {
+		RefreshToken *string `json:"refresh_token,omitempty"`
+	}
+
+	RefreshResponse struct {
+		Tokens *Tokens `json:"tokens,omitempty"`
+	}
+)
+
+// LogoutRequest выход из сессии, в которой выдан access-токен запроса.
+type LogoutRequest struct{}
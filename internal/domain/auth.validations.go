@@ -0,0 +1,28 @@
+package domain
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+)
+
+func (r *RegisterRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Email, validation.Required, is.Email),
+		validation.Field(&r.Password, validation.Required, passwordRule),
+		validation.Field(&r.DisplayName, validation.Required),
+	)
+}
+
+// Validate при входе длина пароля не проверяется: неверный пароль любой длины — это просто неверные учётные данные.
+func (r *LoginRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Email, validation.Required, is.Email),
+		validation.Field(&r.Password, validation.Required),
+	)
+}
+
+func (r *RefreshRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.RefreshToken, validation.Required),
+	)
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Blob объект хранилища с содержимым файла. Одинаковое содержимое хранится одним объектом, на который ссылаются
+// все track_files и uploads с этим содержимым.
+type Blob struct {
+	S3Key     *string    `db:"s3_key"     json:"-"`
+	Checksum  *string    `db:"checksum"   json:"checksum,omitempty"`
+	Size      *int64     `db:"size"       json:"size,omitempty"`
+	Mime      *string    `db:"mime"       json:"mime,omitempty"`
+	RefCount  *int       `db:"ref_count"  json:"-"`
+	StoredAt  *time.Time `db:"stored_at"  json:"stored_at,omitempty"`
+	CreatedAt *time.Time `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt *time.Time `db:"updated_at" json:"-"`
+}
+
+// AcquireBlobRequest регистрация содержимого с контрольной суммой Checksum. Если такое содержимое уже известно,
+// возвращается его запись, а S3Key, Size и Mime запроса не используются.
+type AcquireBlobRequest struct {
+	Checksum *string `db:"checksum"`
+	S3Key    *string `db:"s3_key"`
+	Size     *int64  `db:"size"`
+	Mime     *string `db:"mime"`
+}
+
+type (
+	// GetBlobRequest поиск сохранённого содержимого по SHA-256. Клиент проверяет так, нужно ли вообще передавать
+	// файл. Если задан ReferencedBy, находится только содержимое, на которое уже ссылается файл трека этого
+	// пользователя или его загрузка; заполняет его сервис.
+	GetBlobRequest struct {
+		Checksum     *string    `db:"checksum"      json:"-" path:"checksum"`
+		ReferencedBy *uuid.UUID `db:"referenced_by" json:"-"`
+	}
+
+	GetBlobResponse struct {
+		Blob *Blob `json:"blob,omitempty"`
+	}
+)
+
+type MarkBlobStoredRequest struct {
+	S3Key *string `db:"s3_key"`
+}
+
+// DeleteUnreferencedBlobRequest удаление записи одного объекта, на который никто не ссылается дольше GracePeriod.
+// За GracePeriod у только что сохранённого или найденного по контрольной сумме содержимого должна успеть появиться
+// ссылка из track_files или uploads.
+type DeleteUnreferencedBlobRequest struct {
+	GracePeriod *time.Duration `db:"grace_period"`
+}
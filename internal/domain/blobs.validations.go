@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"regexp"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// checksumRule контрольная сумма содержимого — SHA-256 в виде 64 строчных hex-цифр.
+var checksumRule = validation.Match(regexp.MustCompile(`^[0-9a-f]{64}$`)).Error("must be a lowercase hex SHA-256")
+
+func (r *GetBlobRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Checksum, validation.Required, checksumRule),
+	)
+}
+
+func (r *AcquireBlobRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Checksum, validation.Required, checksumRule),
+		validation.Field(&r.S3Key, validation.Required),
+		validation.Field(&r.Size, validation.NotNil, validation.Min(int64(0))),
+		validation.Field(&r.Mime, validation.Required),
+	)
+}
@@ -4,6 +4,23 @@ import "errors"
 
 var (
 	ErrNotFound = errors.New("not found")
+
+	ErrNotAcceptable = errors.New("no representation matches Accept")
+
+	ErrUploadOffsetMismatch = errors.New("upload offset mismatch")
+	ErrUploadNotResumable   = errors.New("upload is not accepting data")
+
+	ErrAlreadyImported = errors.New("file already imported")
+
+	ErrChecksumMismatch = errors.New("content does not match checksum")
+
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrEmailTaken         = errors.New("email is already registered")
+
+	ErrUnauthenticated    = errors.New("authentication required")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrRefreshTokenReused = errors.New("refresh token has already been used, session revoked")
+	ErrPermissionDenied   = errors.New("permission denied")
 )
 
 type ErrorResponse struct {
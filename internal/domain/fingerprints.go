@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// DuplicateDefaultThreshold порог сходства отпечатков, если клиент не указал threshold. У разных записей
+	// сходство около 0.5, у копий одной — от 0.8.
+	DuplicateDefaultThreshold = 0.7
+	// DuplicateDefaultLimit сколько дубликатов возвращается, если клиент не указал limit.
+	DuplicateDefaultLimit = 20
+	// DuplicateDurationTolerance насколько длительность дубликата может отличаться от длительности трека: с другими
+	// отпечатки даже не сравниваются.
+	DuplicateDurationTolerance = 10 * time.Second
+)
+
+type TrackFileFingerprint struct {
+	TrackFileID *uuid.UUID     `db:"track_file_id" json:"track_file_id,omitempty"`
+	TrackID     *uuid.UUID     `db:"track_id"      json:"track_id,omitempty"`
+	Duration    *time.Duration `db:"duration"      json:"duration,omitempty"`
+	Fingerprint []byte         `db:"fingerprint"   json:"-"`
+	Error       *string        `db:"error"         json:"error,omitempty"`
+	CreatedAt   *time.Time     `db:"created_at"    json:"created_at,omitempty"`
+	UpdatedAt   *time.Time     `db:"updated_at"    json:"updated_at,omitempty"`
+}
+
+// ClaimFingerprintRequest захват следующего исходного файла трека, для которого ещё нет отпечатка. Расчёт, не
+// завершившийся за StaleAfter, считается брошенным и захватывается повторно.
+type ClaimFingerprintRequest struct {
+	StaleAfter *time.Duration `db:"stale_after"`
+}
+
+// SaveFingerprintRequest результат расчёта: либо Fingerprint с его ключами поиска LookupKeys, либо Error.
+type SaveFingerprintRequest struct {
+	TrackFileID *uuid.UUID `db:"track_file_id"`
+	Fingerprint []byte     `db:"fingerprint"`
+	LookupKeys  []int32    `db:"lookup_keys"`
+	Error       *string    `db:"error"`
+}
+
+type (
+	// ListFingerprintsRequest готовые отпечатки: либо файлов трека TrackID, либо, если задан ExcludeTrackID,
+	// файлов всех остальных треков длительностью от MinDuration до MaxDuration. Если заданы LookupKeys, берутся
+	// только отпечатки, у которых есть хотя бы один из этих ключей поиска.
+	ListFingerprintsRequest struct {
+		TrackID        *uuid.UUID     `db:"track_id"`
+		ExcludeTrackID *uuid.UUID     `db:"exclude_track_id"`
+		MinDuration    *time.Duration `db:"min_duration"`
+		MaxDuration    *time.Duration `db:"max_duration"`
+		LookupKeys     []int32        `db:"lookup_keys"`
+	}
+
+	ListFingerprintsResponse struct {
+		Fingerprints []*TrackFileFingerprint
+	}
+)
+
+type (
+	// ListDuplicatesRequest треки, похожие на трек по звучанию, со сходством не ниже Threshold.
+	ListDuplicatesRequest struct {
+		TrackID   *uuid.UUID `json:"-" path:"id"`
+		Threshold *float64   `json:"-" query:"threshold"`
+		Limit     *int       `json:"-" query:"limit"`
+	}
+
+	// Duplicate трек и сходство его отпечатка с отпечатком исходного трека, от 0 до 1.
+	Duplicate struct {
+		Track      *Track  `json:"track"`
+		Similarity float64 `json:"similarity"`
+	}
+
+	// ListDuplicatesResponse дубликаты в порядке убывания сходства.
+	ListDuplicatesResponse struct {
+		Duplicates []*Duplicate `json:"duplicates"`
+	}
+)
+
+type (
+	// MergeTracksRequest слияние дубликатов DuplicateIDs в трек TrackID: лайки, элементы плейлистов и позиции в
+	// альбомах переходят к TrackID, а сами дубликаты удаляются вместе с файлами.
+	MergeTracksRequest struct {
+		TrackID      *uuid.UUID  `db:"track_id"      json:"-"             path:"id"`
+		DuplicateIDs []uuid.UUID `db:"duplicate_ids" json:"duplicate_ids"`
+	}
+
+	MergeTracksResponse struct {
+		TrackID *uuid.UUID `json:"track_id"`
+		Merged  int        `json:"merged"`
+	}
+)
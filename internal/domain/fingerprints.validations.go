@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func (r *ListDuplicatesRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.Threshold, validation.When(r.Threshold != nil, validation.Min(0.0), validation.Max(1.0))),
+		validation.Field(&r.Limit, validation.When(r.Limit != nil, validation.Min(1))),
+	)
+}
+
+func (r *MergeTracksRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.DuplicateIDs, validation.Required, validation.Each(validation.Required), validation.By(r.notSelf)),
+	)
+}
+
+// notSelf не даёт слить трек с самим собой: он был бы удалён.
+func (r *MergeTracksRequest) notSelf(any) error {
+	if r.TrackID == nil {
+		return nil
+	}
+
+	for _, id := range r.DuplicateIDs {
+		if id == *r.TrackID {
+			return errors.New("must not contain the track itself")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClaimHLSIndexRequest захват следующего сжатого файла трека, который ещё не разбит на сегменты HLS. Разбиение,
+// не завершившееся за StaleAfter, считается брошенным и захватывается повторно.
+type ClaimHLSIndexRequest struct {
+	StaleAfter *time.Duration `db:"stale_after"`
+}
+
+// SaveHLSIndexRequest результат разбиения: либо Segments, либо Error.
+type SaveHLSIndexRequest struct {
+	TrackFileID *uuid.UUID `db:"track_file_id"`
+	Segments    []byte     `db:"segments"`
+	Error       *string    `db:"error"`
+}
+
+// HLSVariant копия трека, готовая к раздаче по HLS: файл и его разбиение на сегменты.
+type HLSVariant struct {
+	TrackFileID *uuid.UUID `db:"track_file_id"`
+	S3Key       *string    `db:"s3_key"`
+	Codec       *Codec     `db:"codec"`
+	Bitrate     *int       `db:"bitrate"`
+	Segments    []byte     `db:"segments"`
+}
+
+// ListHLSVariantsRequest готовые к HLS копии трека; TrackFileID оставляет только одну из них.
+type ListHLSVariantsRequest struct {
+	TrackID     *uuid.UUID `db:"track_id"`
+	TrackFileID *uuid.UUID `db:"track_file_id"`
+}
+
+type ListHLSVariantsResponse struct {
+	Variants []*HLSVariant
+}
+
+type (
+	// GetHLSMasterPlaylistRequest мастер-плейлист трека со всеми копиями.
+	GetHLSMasterPlaylistRequest struct {
+		TrackID *uuid.UUID `json:"-" path:"id"`
+	}
+
+	// GetHLSMediaPlaylistRequest медиа-плейлист одной копии трека.
+	GetHLSMediaPlaylistRequest struct {
+		TrackID     *uuid.UUID `json:"-" path:"id"`
+		TrackFileID *uuid.UUID `json:"-" path:"file_id"`
+	}
+
+	// GetHLSSegmentRequest сегмент MPEG-TS копии трека по номеру из медиа-плейлиста.
+	GetHLSSegmentRequest struct {
+		TrackID     *uuid.UUID `json:"-" path:"id"`
+		TrackFileID *uuid.UUID `json:"-" path:"file_id"`
+		Segment     *int       `json:"-" path:"segment"`
+	}
+
+	// GetHLSResponse готовый плейлист или сегмент.
+	GetHLSResponse struct {
+		ContentType string
+		Body        []byte
+	}
+)
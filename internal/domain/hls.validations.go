@@ -0,0 +1,26 @@
+package domain
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func (r *GetHLSMasterPlaylistRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+	)
+}
+
+func (r *GetHLSMediaPlaylistRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.TrackFileID, validation.Required),
+	)
+}
+
+func (r *GetHLSSegmentRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.TrackFileID, validation.Required),
+		validation.Field(&r.Segment, validation.NotNil, validation.Min(0)),
+	)
+}
@@ -0,0 +1,114 @@
+package domain
+
+import (
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportTrackRequest импорт одного файла из существующей библиотеки: трек, его файл и связи с исполнителями,
+// альбомом и жанрами. Исполнители, альбом и жанры ищутся по имени (без учёта регистра) и создаются, если их ещё нет.
+// S3Key и UploadedAt заполняет сервис при сохранении Body.
+type ImportTrackRequest struct {
+	OwnerID     *uuid.UUID  `db:"owner_id"     json:"owner_id,omitempty"`
+	Title       *string     `db:"title"        json:"title,omitempty"`
+	Artists     []string    `db:"artists"      json:"artists,omitempty"`
+	Album       *string     `db:"album"        json:"album,omitempty"`
+	AlbumArtist *string     `db:"album_artist" json:"album_artist,omitempty"`
+	ReleaseDate *time.Time  `db:"release_date" json:"release_date,omitempty"`
+	DiscNumber  *int        `db:"disc_number"  json:"disc_number,omitempty"`
+	Position    *int        `db:"position"     json:"position,omitempty"`
+	Genres      []string    `db:"genres"       json:"genres,omitempty"`
+	Visibility  *Visibility `db:"visibility"   json:"visibility,omitempty"`
+
+	Filename   *string        `db:"filename"    json:"filename,omitempty"`
+	S3Key      *string        `db:"s3_key"      json:"-"`
+	Mime       *string        `db:"mime"        json:"mime,omitempty"`
+	Format     *Format        `db:"format"      json:"format,omitempty"`
+	Codec      *Codec         `db:"codec"       json:"codec,omitempty"`
+	Bitrate    *int           `db:"bitrate"     json:"bitrate,omitempty"`
+	SampleRate *int           `db:"sample_rate" json:"sample_rate,omitempty"`
+	Channels   *int           `db:"channels"    json:"channels,omitempty"`
+	Size       *int64         `db:"size"        json:"size,omitempty"`
+	Duration   *time.Duration `db:"duration"    json:"duration,omitempty"`
+	Checksum   *string        `db:"checksum"    json:"checksum,omitempty"`
+	UploadedAt *time.Time     `db:"uploaded_at" json:"-"`
+	// StartSample и EndSample границы трека, если файл — образ диска (см. ImportCueSheetRequest).
+	StartSample *int64 `db:"start_sample" json:"-"`
+	EndSample   *int64 `db:"end_sample"   json:"-"`
+
+	Body io.Reader `json:"-"`
+}
+
+type ImportTrackResponse struct {
+	TrackID     *uuid.UUID `json:"track_id"`
+	TrackFileID *uuid.UUID `json:"track_file_id"`
+	AlbumID     *uuid.UUID `json:"album_id,omitempty"`
+}
+
+// ImportCueSheetRequest импорт образа диска, размеченного cue: файл сохраняется один раз, а каждому треку разметки
+// достаётся свой трек на своей позиции в альбоме и свой файл трека с границами в общем объекте. Поля альбома и
+// файла общие для всех треков и значат то же, что в ImportTrackRequest.
+type ImportCueSheetRequest struct {
+	OwnerID     *uuid.UUID  `db:"owner_id"     json:"owner_id,omitempty"`
+	Album       *string     `db:"album"        json:"album,omitempty"`
+	AlbumArtist *string     `db:"album_artist" json:"album_artist,omitempty"`
+	ReleaseDate *time.Time  `db:"release_date" json:"release_date,omitempty"`
+	DiscNumber  *int        `db:"disc_number"  json:"disc_number,omitempty"`
+	Genres      []string    `db:"genres"       json:"genres,omitempty"`
+	Visibility  *Visibility `db:"visibility"   json:"visibility,omitempty"`
+
+	Filename   *string    `db:"filename"    json:"filename,omitempty"`
+	S3Key      *string    `db:"s3_key"      json:"-"`
+	Mime       *string    `db:"mime"        json:"mime,omitempty"`
+	Format     *Format    `db:"format"      json:"format,omitempty"`
+	Codec      *Codec     `db:"codec"       json:"codec,omitempty"`
+	Bitrate    *int       `db:"bitrate"     json:"bitrate,omitempty"`
+	SampleRate *int       `db:"sample_rate" json:"sample_rate,omitempty"`
+	Channels   *int       `db:"channels"    json:"channels,omitempty"`
+	Size       *int64     `db:"size"        json:"size,omitempty"`
+	Checksum   *string    `db:"checksum"    json:"checksum,omitempty"`
+	UploadedAt *time.Time `db:"uploaded_at" json:"-"`
+
+	Tracks []ImportCueTrack `json:"tracks,omitempty"`
+
+	Body io.Reader `json:"-"`
+}
+
+// ImportCueTrack трек образа: сэмплы [StartSample, EndSample) общего файла.
+type ImportCueTrack struct {
+	Title       *string        `json:"title,omitempty"`
+	Artists     []string       `json:"artists,omitempty"`
+	Position    *int           `json:"position,omitempty"`
+	StartSample *int64         `json:"start_sample,omitempty"`
+	EndSample   *int64         `json:"end_sample,omitempty"`
+	Duration    *time.Duration `json:"duration,omitempty"`
+}
+
+type ImportCueSheetResponse struct {
+	AlbumID *uuid.UUID             `json:"album_id,omitempty"`
+	Tracks  []*ImportTrackResponse `json:"tracks"`
+}
+
+type (
+	// ImportTracksRequest импорт нескольких треков в одной транзакции: либо все, либо ни одного.
+	ImportTracksRequest struct {
+		Tracks []ImportTrackRequest
+	}
+
+	ImportTracksResponse struct {
+		Tracks []*ImportTrackResponse
+	}
+)
+
+type (
+	// FindTrackFileByChecksumRequest поиск уже сохранённого файла по SHA-256 содержимого.
+	FindTrackFileByChecksumRequest struct {
+		Checksum *string `db:"checksum" query:"checksum"`
+	}
+
+	FindTrackFileByChecksumResponse struct {
+		TrackFile *TrackFile `json:"track_file"`
+	}
+)
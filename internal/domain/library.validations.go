@@ -0,0 +1,76 @@
+package domain
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	validatron "github.com/untea/bottom_babruysk/internal/domain/validation"
+)
+
+func (r *ImportTrackRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Title, validation.Required),
+		validation.Field(&r.Artists, validation.Each(validation.Required)),
+		validation.Field(&r.Album, validation.When(r.Album != nil, validation.Required)),
+		validation.Field(&r.AlbumArtist, validation.When(r.Album != nil, validation.Required)),
+		validation.Field(&r.ReleaseDate, validation.When(r.Album != nil, validation.Required)),
+		validation.Field(&r.DiscNumber, validation.When(r.DiscNumber != nil, validation.Min(1))),
+		validation.Field(&r.Position, validation.When(r.Position != nil, validation.Min(1))),
+		validation.Field(&r.Genres, validation.Each(validation.Required)),
+		validation.Field(&r.Visibility, validation.When(r.Visibility != nil, validatron.InSetPtr(visibilitySet))),
+		validation.Field(&r.Filename, validation.Required),
+		validation.Field(&r.Mime, validation.Required),
+		validation.Field(&r.Format, validation.Required, validatron.InStringsPtr(formatSet, "format")),
+		validation.Field(&r.Codec, validation.Required, validatron.InStringsPtr(codecSet, "codec")),
+		validation.Field(&r.Bitrate, validation.Required, validation.Min(1)),
+		validation.Field(&r.SampleRate, validation.Required, validation.Min(1)),
+		validation.Field(&r.Channels, validation.Required, validation.Min(1)),
+		validation.Field(&r.Size, validation.Required, validation.Min(int64(1))),
+		validation.Field(&r.Duration, validation.Required),
+		validation.Field(&r.Checksum, validation.Required, checksumRule),
+		validation.Field(&r.Body, validation.NotNil),
+	)
+}
+
+func (r *ImportCueSheetRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Album, validation.Required),
+		validation.Field(&r.AlbumArtist, validation.Required),
+		validation.Field(&r.ReleaseDate, validation.Required),
+		validation.Field(&r.DiscNumber, validation.When(r.DiscNumber != nil, validation.Min(1))),
+		validation.Field(&r.Genres, validation.Each(validation.Required)),
+		validation.Field(&r.Visibility, validation.When(r.Visibility != nil, validatron.InSetPtr(visibilitySet))),
+		validation.Field(&r.Filename, validation.Required),
+		validation.Field(&r.Mime, validation.Required),
+		validation.Field(&r.Format, validation.Required, validatron.InStringsPtr(formatSet, "format")),
+		validation.Field(&r.Codec, validation.Required, validatron.InStringsPtr(codecSet, "codec")),
+		validation.Field(&r.Bitrate, validation.Required, validation.Min(1)),
+		validation.Field(&r.SampleRate, validation.Required, validation.Min(1)),
+		validation.Field(&r.Channels, validation.Required, validation.Min(1)),
+		validation.Field(&r.Size, validation.Required, validation.Min(int64(1))),
+		validation.Field(&r.Checksum, validation.Required, checksumRule),
+		validation.Field(&r.Tracks, validation.Required),
+		validation.Field(&r.Body, validation.NotNil),
+	)
+}
+
+func (r ImportCueTrack) Validate() error {
+	var start int64
+	if r.StartSample != nil {
+		start = *r.StartSample
+	}
+
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Title, validation.Required),
+		validation.Field(&r.Artists, validation.Each(validation.Required)),
+		validation.Field(&r.Position, validation.Required, validation.Min(1)),
+		validation.Field(&r.StartSample, validation.NotNil, validation.Min(int64(0))),
+		validation.Field(&r.EndSample, validation.Required, validation.Min(start+1)),
+		validation.Field(&r.Duration, validation.Required),
+	)
+}
+
+func (r *FindTrackFileByChecksumRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Checksum, validation.Required, checksumRule),
+	)
+}
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ClaimLoudnessRequest захват следующего файла трека, громкость которого ещё не измерена. Анализ, не
+// завершившийся за StaleAfter, считается брошенным и захватывается повторно.
+type ClaimLoudnessRequest struct {
+	StaleAfter *time.Duration `db:"stale_after"`
+}
+
+// SaveLoudnessRequest результат анализа: либо измерения вместе с Histogram, либо Error.
+type SaveLoudnessRequest struct {
+	TrackFileID         *uuid.UUID `db:"track_file_id"`
+	IntegratedLoudness  *float64   `db:"integrated_loudness"`
+	LoudnessRange       *float64   `db:"loudness_range"`
+	TruePeak            *float64   `db:"true_peak"`
+	ReplayGainTrackGain *float64   `db:"replaygain_track_gain"`
+	ReplayGainTrackPeak *float64   `db:"replaygain_track_peak"`
+	Histogram           []byte     `db:"histogram"`
+	Error               *string    `db:"error"`
+}
+
+type (
+	// ListAlbumLoudnessSourcesRequest данные для пересчёта громкости всех альбомов, в которые входит трек.
+	ListAlbumLoudnessSourcesRequest struct {
+		TrackID *uuid.UUID `db:"track_id"`
+	}
+
+	// AlbumLoudnessSource измерения одного трека альбома (по самому раннему проанализированному файлу).
+	AlbumLoudnessSource struct {
+		AlbumID             *uuid.UUID `db:"album_id"`
+		TrackID             *uuid.UUID `db:"track_id"`
+		TruePeak            *float64   `db:"true_peak"`
+		ReplayGainTrackPeak *float64   `db:"replaygain_track_peak"`
+		Histogram           []byte     `db:"histogram"`
+	}
+
+	ListAlbumLoudnessSourcesResponse struct {
+		Sources []*AlbumLoudnessSource
+	}
+)
+
+// UpdateAlbumLoudnessRequest сохранение громкости альбома, посчитанной по его трекам.
+type UpdateAlbumLoudnessRequest struct {
+	AlbumID             *uuid.UUID `db:"id"`
+	IntegratedLoudness  *float64   `db:"integrated_loudness"`
+	LoudnessRange       *float64   `db:"loudness_range"`
+	TruePeak            *float64   `db:"true_peak"`
+	ReplayGainAlbumGain *float64   `db:"replaygain_album_gain"`
+	ReplayGainAlbumPeak *float64   `db:"replaygain_album_peak"`
+}
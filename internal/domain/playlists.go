@@ -17,8 +17,9 @@ type Playlist struct {
 }
 
 type (
+	// CreatePlaylistRequest OwnerID заполняет сервис: плейлист принадлежит тому, кто его создал.
 	CreatePlaylistRequest struct {
-		OwnerID     *uuid.UUID  `db:"owner_id"    json:"owner_id,omitempty"`
+		OwnerID     *uuid.UUID  `db:"owner_id"    json:"-"`
 		Title       *string     `db:"title"       json:"title,omitempty"`
 		Description *string     `db:"description" json:"description,omitempty"`
 		Visibility  *Visibility `db:"visibility"  json:"visibility,omitempty"`
@@ -40,13 +41,17 @@ type (
 )
 
 type (
+	// ListPlaylistsRequest ViewerID и ViewerIsAdmin заполняет сервис: в список попадают публичные плейлисты и все
+	// плейлисты самого зрителя, а администратору — все плейлисты.
 	ListPlaylistsRequest struct {
-		Limit      *int        `db:"limit"       query:"limit"`
-		Offset     *int        `db:"offset"      query:"offset"`
-		OwnerID    *uuid.UUID  `db:"owner_id"    query:"owner_id"`
-		Visibility *Visibility `db:"visibility"  query:"visibility"`
-		SortField  *string     `db:"sort_field"  query:"sort_field"`
-		SortOrder  *string     `db:"sort_order"  query:"sort_order"`
+		Limit         *int        `db:"limit"           query:"limit"`
+		Offset        *int        `db:"offset"          query:"offset"`
+		OwnerID       *uuid.UUID  `db:"owner_id"        query:"owner_id"`
+		Visibility    *Visibility `db:"visibility"      query:"visibility"`
+		SortField     *string     `db:"sort_field"      query:"sort_field"`
+		SortOrder     *string     `db:"sort_order"      query:"sort_order"`
+		ViewerID      *uuid.UUID  `db:"viewer_id"       json:"-"`
+		ViewerIsAdmin *bool       `db:"viewer_is_admin" json:"-"`
 	}
 
 	ListPlaylistsResponse struct {
@@ -0,0 +1,63 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session вход пользователя с одного устройства. Хеш refresh-токена наружу не отдаётся.
+type Session struct {
+	ID          *uuid.UUID `db:"id"           json:"id,omitempty"`
+	UserID      *uuid.UUID `db:"user_id"      json:"user_id,omitempty"`
+	UserAgent   *string    `db:"user_agent"   json:"user_agent,omitempty"`
+	Current     *bool      `db:"-"            json:"current,omitempty"`
+	CreatedAt   *time.Time `db:"created_at"   json:"created_at,omitempty"`
+	RefreshedAt *time.Time `db:"refreshed_at" json:"refreshed_at,omitempty"`
+	ExpiresAt   *time.Time `db:"expires_at"   json:"expires_at,omitempty"`
+	RevokedAt   *time.Time `db:"revoked_at"   json:"revoked_at,omitempty"`
+}
+
+// CreateSessionRequest новая сессия, refresh-токен которой действует TTL.
+type CreateSessionRequest struct {
+	UserID           *uuid.UUID     `db:"user_id"`
+	RefreshTokenHash *string        `db:"refresh_token_hash"`
+	UserAgent        *string        `db:"user_agent"`
+	TTL              *time.Duration `db:"ttl"`
+}
+
+// RotateSessionRequest замена действующего refresh-токена RefreshTokenHash на NewRefreshTokenHash с продлением
+// сессии на TTL.
+type RotateSessionRequest struct {
+	RefreshTokenHash    *string        `db:"refresh_token_hash"`
+	NewRefreshTokenHash *string        `db:"new_refresh_token_hash"`
+	TTL                 *time.Duration `db:"ttl"`
+}
+
+// RevokeSessionByRotatedTokenRequest отзыв сессии, которой принадлежал уже заменённый refresh-токен.
+type RevokeSessionByRotatedTokenRequest struct {
+	RefreshTokenHash *string `db:"refresh_token_hash"`
+}
+
+// GetPrincipalRequest поиск действующей сессии из access-токена.
+type GetPrincipalRequest struct {
+	SessionID *uuid.UUID `db:"session_id"`
+	UserID    *uuid.UUID `db:"user_id"`
+}
+
+type (
+	// ListSessionsRequest UserID заполняет сервис: пользователь видит только свои сессии.
+	ListSessionsRequest struct {
+		UserID *uuid.UUID `db:"user_id" json:"-"`
+	}
+
+	ListSessionsResponse struct {
+		Sessions []*Session `json:"sessions,omitempty"`
+	}
+)
+
+// RevokeSessionRequest отзыв сессии пользователя UserID (заполняет сервис), например, потерянного устройства.
+type RevokeSessionRequest struct {
+	ID     *uuid.UUID `db:"id"      json:"-" path:"id"`
+	UserID *uuid.UUID `db:"user_id" json:"-"`
+}
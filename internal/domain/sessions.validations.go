@@ -0,0 +1,18 @@
+package domain
+
+import (
+	"github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func (r *ListSessionsRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.UserID, validation.Required),
+	)
+}
+
+func (r *RevokeSessionRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.ID, validation.Required),
+		validation.Field(&r.UserID, validation.Required),
+	)
+}
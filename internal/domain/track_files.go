@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,7 @@ const (
 	CodecALAC Codec = "alac"
 	CodecAPE  Codec = "ape"
 	CodecSHN  Codec = "shn"
+	CodecPCM  Codec = "pcm"
 
 	// Lossy
 	CodecMP3    Codec = "mp3"
@@ -38,42 +40,53 @@ const (
 	FormatWAV         Format = "wav"
 	FormatWEBM        Format = "webm"
 	FormatAAC         Format = "aac"
+	FormatAIFF        Format = "aiff"
 )
 
 type TrackFile struct {
-	ID         *uuid.UUID     `db:"id"          json:"id,omitempty"`
-	TrackID    *uuid.UUID     `db:"track_id"    json:"track_id,omitempty"`
-	Filename   *string        `db:"filename"    json:"filename,omitempty"`
-	S3Key      *string        `db:"s3_key"      json:"s3_key,omitempty"`
-	Mime       *string        `db:"mime"        json:"mime,omitempty"`
-	Format     *Format        `db:"format"      json:"format,omitempty"`
-	Codec      *Codec         `db:"codec"       json:"codec,omitempty"`
-	Bitrate    *int           `db:"bitrate"     json:"bitrate,omitempty"`
-	SampleRate *int           `db:"sample_rate" json:"sample_rate,omitempty"`
-	Channels   *int           `db:"channels"    json:"channels,omitempty"`
-	Size       *int64         `db:"size"        json:"size,omitempty"`
-	Duration   *time.Duration `db:"duration"    json:"duration,omitempty"`
-	Checksum   *string        `db:"checksum"    json:"checksum,omitempty"`
-	CreatedAt  *time.Time     `db:"created_at"  json:"created_at,omitempty"`
-	UpdatedAt  *time.Time     `db:"updated_at"  json:"updated_at,omitempty"`
-	UploadedAt *time.Time     `db:"uploaded_at" json:"uploaded_at,omitempty"`
+	ID             *uuid.UUID     `db:"id"              json:"id,omitempty"`
+	TrackID        *uuid.UUID     `db:"track_id"        json:"track_id,omitempty"`
+	Filename       *string        `db:"filename"        json:"filename,omitempty"`
+	S3Key          *string        `db:"s3_key"          json:"s3_key,omitempty"`
+	Mime           *string        `db:"mime"            json:"mime,omitempty"`
+	Format         *Format        `db:"format"          json:"format,omitempty"`
+	Codec          *Codec         `db:"codec"           json:"codec,omitempty"`
+	Bitrate        *int           `db:"bitrate"         json:"bitrate,omitempty"`
+	SampleRate     *int           `db:"sample_rate"     json:"sample_rate,omitempty"`
+	Channels       *int           `db:"channels"        json:"channels,omitempty"`
+	Size           *int64         `db:"size"            json:"size,omitempty"`
+	Duration       *time.Duration `db:"duration"        json:"duration,omitempty"`
+	Checksum       *string        `db:"checksum"        json:"checksum,omitempty"`
+	Healthy        *bool          `db:"healthy"         json:"healthy,omitempty"`
+	IntegrityError *string        `db:"integrity_error" json:"integrity_error,omitempty"`
+	SourceID       *uuid.UUID     `db:"source_id"       json:"source_id,omitempty"`
+	StartSample    *int64         `db:"start_sample"    json:"start_sample,omitempty"`
+	EndSample      *int64         `db:"end_sample"      json:"end_sample,omitempty"`
+	CreatedAt      *time.Time     `db:"created_at"      json:"created_at,omitempty"`
+	UpdatedAt      *time.Time     `db:"updated_at"      json:"updated_at,omitempty"`
+	UploadedAt     *time.Time     `db:"uploaded_at"     json:"uploaded_at,omitempty"`
 }
 
 type (
 	CreateTrackFileRequest struct {
-		TrackID    *uuid.UUID     `db:"track_id"    json:"track_id,omitempty"`
-		Filename   *string        `db:"filename"    json:"filename,omitempty"`
-		S3Key      *string        `db:"s3_key"      json:"s3_key,omitempty"`
-		Mime       *string        `db:"mime"        json:"mime,omitempty"`
-		Format     *Format        `db:"format"      json:"format,omitempty"`
-		Codec      *Codec         `db:"codec"       json:"codec,omitempty"`
-		Bitrate    *int           `db:"bitrate"     json:"bitrate,omitempty"`
-		SampleRate *int           `db:"sample_rate" json:"sample_rate,omitempty"`
-		Channels   *int           `db:"channels"    json:"channels,omitempty"`
-		Size       *int64         `db:"size"        json:"size,omitempty"`
-		Duration   *time.Duration `db:"duration"    json:"duration,omitempty"`
-		Checksum   *string        `db:"checksum"    json:"checksum,omitempty"`
-		UploadedAt *time.Time     `db:"uploaded_at" json:"uploaded_at,omitempty"`
+		TrackID        *uuid.UUID     `db:"track_id"        json:"track_id,omitempty"`
+		Filename       *string        `db:"filename"        json:"filename,omitempty"`
+		S3Key          *string        `db:"s3_key"          json:"s3_key,omitempty"`
+		Mime           *string        `db:"mime"            json:"mime,omitempty"`
+		Format         *Format        `db:"format"          json:"format,omitempty"`
+		Codec          *Codec         `db:"codec"           json:"codec,omitempty"`
+		Bitrate        *int           `db:"bitrate"         json:"bitrate,omitempty"`
+		SampleRate     *int           `db:"sample_rate"     json:"sample_rate,omitempty"`
+		Channels       *int           `db:"channels"        json:"channels,omitempty"`
+		Size           *int64         `db:"size"            json:"size,omitempty"`
+		Duration       *time.Duration `db:"duration"        json:"duration,omitempty"`
+		Checksum       *string        `db:"checksum"        json:"checksum,omitempty"`
+		UploadedAt     *time.Time     `db:"uploaded_at"     json:"uploaded_at,omitempty"`
+		Healthy        *bool          `db:"healthy"         json:"-"`
+		IntegrityError *string        `db:"integrity_error" json:"-"`
+		SourceID       *uuid.UUID     `db:"source_id"       json:"-"`
+		StartSample    *int64         `db:"start_sample"    json:"-"`
+		EndSample      *int64         `db:"end_sample"      json:"-"`
 	}
 
 	CreateTrackFileResponse struct {
@@ -81,6 +94,14 @@ type (
 	}
 )
 
+// UploadTrackFileRequest создание файла трека вместе с его содержимым. Метаданные передаются так же, как в
+// CreateTrackFileRequest, а S3Key, Size и Checksum вычисляются сервисом при сохранении Body.
+type UploadTrackFileRequest struct {
+	CreateTrackFileRequest
+
+	Body io.Reader `json:"-"`
+}
+
 type (
 	GetTrackFileRequest struct {
 		ID      *uuid.UUID `db:"id"       json:"id,omitempty"       path:"id"`
@@ -106,20 +127,28 @@ type (
 	}
 )
 
+// UpdateTrackFileRequest правка метаданных файла трека. Объект, размер и контрольная сумма задаются только при
+// сохранении содержимого (UploadTrackFile и обработка загрузок) и правке не подлежат.
 type UpdateTrackFileRequest struct {
-	ID         *uuid.UUID     `db:"id"       json:"-" path:"id"`
-	TrackID    *uuid.UUID     `db:"track_id" json:"-" path:"track_id"`
+	ID         *uuid.UUID     `db:"id"          json:"-" path:"id"`
+	TrackID    *uuid.UUID     `db:"track_id"    json:"-" path:"track_id"`
 	Filename   *string        `db:"filename"    json:"filename,omitempty"`
-	S3Key      *string        `db:"s3_key"      json:"s3_key,omitempty"`
 	Mime       *string        `db:"mime"        json:"mime,omitempty"`
 	Format     *Format        `db:"format"      json:"format,omitempty"`
 	Codec      *Codec         `db:"codec"       json:"codec,omitempty"`
 	Bitrate    *int           `db:"bitrate"     json:"bitrate,omitempty"`
 	SampleRate *int           `db:"sample_rate" json:"sample_rate,omitempty"`
 	Channels   *int           `db:"channels"    json:"channels,omitempty"`
-	Size       *int64         `db:"size"        json:"size,omitempty"`
-	Duration   *time.Duration `db:"duration" json:"duration,omitempty"`
-	Checksum   *string        `db:"checksum"    json:"checksum,omitempty"`
+	Duration   *time.Duration `db:"duration"    json:"duration,omitempty"`
+}
+
+// StreamTrackRequest выбор файла трека для проигрывания. Если format и codec не заданы, файл подбирается по
+// заголовку Accept клиента.
+type StreamTrackRequest struct {
+	TrackID *uuid.UUID `json:"-" path:"id"`
+	Format  *Format    `json:"-" query:"format"`
+	Codec   *Codec     `json:"-" query:"codec"`
+	Accept  string     `json:"-"`
 }
 
 type DeleteTrackFileRequest struct {
@@ -17,6 +17,7 @@ var (
 		"m4a",
 		"webm",
 		"aac",
+		"aiff",
 	)
 
 	codecSet = validatron.NewSet(
@@ -58,6 +59,12 @@ var (
 )
 
 func (r *CreateTrackFileRequest) Validate() error {
+	// Границы трека в образе диска задаются обе сразу.
+	var start int64
+	if r.StartSample != nil {
+		start = *r.StartSample
+	}
+
 	return validation.ValidateStruct(r,
 		validation.Field(&r.TrackID, validation.Required),
 		validation.Field(&r.Filename, validation.Required),
@@ -69,8 +76,26 @@ func (r *CreateTrackFileRequest) Validate() error {
 		validation.Field(&r.Channels, validation.Required, validation.Min(1)),
 		validation.Field(&r.Size, validation.Required, validation.Min(1)),
 		validation.Field(&r.Duration, validation.Required),
-		validation.Field(&r.Checksum, validation.Required),
+		validation.Field(&r.Checksum, validation.Required, checksumRule),
 		validation.Field(&r.UploadedAt, validation.Required),
+		validation.Field(&r.StartSample, validation.When(r.EndSample != nil, validation.NotNil, validation.Min(int64(0)))),
+		validation.Field(&r.EndSample, validation.When(r.StartSample != nil, validation.NotNil, validation.Min(start+1))),
+	)
+}
+
+func (r *UploadTrackFileRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.Filename, validation.Required),
+		validation.Field(&r.Mime, validation.Required),
+		validation.Field(&r.Format, validation.Required, validatron.InStringsPtr(formatSet, "format")),
+		validation.Field(&r.Codec, validation.Required, validatron.InStringsPtr(codecSet, "codec")),
+		validation.Field(&r.Bitrate, validation.Required, validation.Min(1)),
+		validation.Field(&r.SampleRate, validation.Required, validation.Min(1)),
+		validation.Field(&r.Channels, validation.Required, validation.Min(1)),
+		validation.Field(&r.Duration, validation.Required),
+		validation.Field(&r.Checksum, validation.When(r.Checksum != nil, checksumRule)),
+		validation.Field(&r.Body, validation.When(r.Checksum == nil, validation.NotNil)),
 	)
 }
 
@@ -100,7 +125,14 @@ func (r *UpdateTrackFileRequest) Validate() error {
 		validation.Field(&r.Bitrate, validation.When(r.Bitrate != nil, validation.Min(1))),
 		validation.Field(&r.SampleRate, validation.When(r.SampleRate != nil, validation.Min(1))),
 		validation.Field(&r.Channels, validation.When(r.Channels != nil, validation.Min(1))),
-		validation.Field(&r.Size, validation.When(r.Size != nil, validation.Min(int64(1)))),
+	)
+}
+
+func (r *StreamTrackRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.Format, validation.When(r.Format != nil, validatron.InStringsPtr(formatSet, "format"))),
+		validation.Field(&r.Codec, validation.When(r.Codec != nil, validatron.InStringsPtr(codecSet, "codec"))),
 	)
 }
 
@@ -26,11 +26,19 @@ type Track struct {
 	CreatedAt   *time.Time     `db:"created_at"  json:"createdAt,omitempty"`
 	UpdatedAt   *time.Time     `db:"updated_at"  json:"updatedAt,omitempty"`
 	UploadedAt  *time.Time     `db:"uploaded_at" json:"uploadedAt,omitempty"`
+
+	// Громкость берётся из анализа самого раннего проанализированного файла трека (см. track_file_loudness).
+	IntegratedLoudness  *float64 `db:"integrated_loudness"   json:"integratedLoudness,omitempty"`
+	LoudnessRange       *float64 `db:"loudness_range"        json:"loudnessRange,omitempty"`
+	TruePeak            *float64 `db:"true_peak"             json:"truePeak,omitempty"`
+	ReplayGainTrackGain *float64 `db:"replaygain_track_gain" json:"replayGainTrackGain,omitempty"`
+	ReplayGainTrackPeak *float64 `db:"replaygain_track_peak" json:"replayGainTrackPeak,omitempty"`
 }
 
 type (
+	// CreateTrackRequest UploaderID заполняет сервис: трек принадлежит тому, кто его создал.
 	CreateTrackRequest struct {
-		UploaderID  *uuid.UUID     `db:"uploader_id" json:"uploader_id,omitempty"`
+		UploaderID  *uuid.UUID     `db:"uploader_id" json:"-"`
 		Title       *string        `db:"title"       json:"title,omitempty"`
 		Subtitle    *string        `db:"subtitle"    json:"subtitle,omitempty"`
 		Description *string        `db:"description" json:"description,omitempty"`
@@ -55,13 +63,17 @@ type (
 )
 
 type (
+	// ListTracksRequest ViewerID и ViewerIsAdmin заполняет сервис: в список попадают публичные треки и все треки
+	// самого зрителя, а администратору — все треки.
 	ListTracksRequest struct {
-		Limit      *int        `db:"limit"       query:"limit"`
-		Offset     *int        `db:"offset"      query:"offset"`
-		UploaderID *uuid.UUID  `db:"uploader_id" query:"uploader_id"`
-		Visibility *Visibility `db:"visibility"  query:"visibility"`
-		SortField  *string     `db:"sort_field"  query:"sort_field"`
-		SortOrder  *string     `db:"sort_order"  query:"sort_order"`
+		Limit         *int        `db:"limit"           query:"limit"`
+		Offset        *int        `db:"offset"          query:"offset"`
+		UploaderID    *uuid.UUID  `db:"uploader_id"     query:"uploader_id"`
+		Visibility    *Visibility `db:"visibility"      query:"visibility"`
+		SortField     *string     `db:"sort_field"      query:"sort_field"`
+		SortOrder     *string     `db:"sort_order"      query:"sort_order"`
+		ViewerID      *uuid.UUID  `db:"viewer_id"       json:"-"`
+		ViewerIsAdmin *bool       `db:"viewer_is_admin" json:"-"`
 	}
 
 	ListTracksResponse struct {
@@ -80,3 +92,9 @@ type UpdateTrackRequest struct {
 type DeleteTrackRequest struct {
 	ID *uuid.UUID `db:"id" json:"-" path:"id"`
 }
+
+// SetTrackGenresRequest добавляет треку жанры по именам; жанры, которых ещё нет в справочнике, создаются.
+type SetTrackGenresRequest struct {
+	TrackID *uuid.UUID `db:"track_id" json:"-" path:"id"`
+	Genres  []string   `db:"genres"   json:"genres,omitempty"`
+}
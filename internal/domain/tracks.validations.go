@@ -62,3 +62,10 @@ func (r *DeleteTrackRequest) Validate() error {
 		validation.Field(&r.ID, validation.Required),
 	)
 }
+
+func (r *SetTrackGenresRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.Genres, validation.Required, validation.Each(validation.Required)),
+	)
+}
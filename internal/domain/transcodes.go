@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Rendition производная копия трека, которую сервер получает из lossless-оригинала. Bitrate в кбит/с задаётся
+// только для lossy-кодеков; у PCM он определяется исходником.
+type Rendition struct {
+	Format  Format
+	Codec   Codec
+	Bitrate int
+}
+
+func (r Rendition) String() string {
+	if r.Bitrate == 0 {
+		return string(r.Codec)
+	}
+
+	return fmt.Sprintf("%s:%d", r.Codec, r.Bitrate)
+}
+
+// ClaimTranscodeRequest захват следующей пары (оригинал, копия) из Renditions, для которой копии ещё нет.
+// Транскодирование, не завершившееся за StaleAfter, считается брошенным и захватывается повторно.
+type ClaimTranscodeRequest struct {
+	StaleAfter *time.Duration
+	Renditions []Rendition
+}
+
+// TranscodeTask захваченный оригинал и копия, которую из него нужно получить.
+type TranscodeTask struct {
+	TrackFile
+
+	TargetFormat  *Format `db:"target_format"`
+	TargetCodec   *Codec  `db:"target_codec"`
+	TargetBitrate *int    `db:"target_bitrate"`
+}
+
+// CompleteTranscodeRequest регистрация готовой копии: TrackFile записывается в track_files со ссылкой на
+// оригинал SourceID.
+type CompleteTranscodeRequest struct {
+	SourceID  *uuid.UUID
+	Rendition Rendition
+	TrackFile CreateTrackFileRequest
+}
+
+// FailTranscodeRequest сохранение причины, по которой копию получить не удалось; повторно она не заказывается.
+type FailTranscodeRequest struct {
+	SourceID  *uuid.UUID
+	Rendition Rendition
+	Error     *string
+}
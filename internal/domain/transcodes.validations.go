@@ -0,0 +1,18 @@
+package domain
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func (r Rendition) Validate() error {
+	lossless := r.Codec == CodecWAV || r.Codec == CodecFLAC
+
+	return validation.ValidateStruct(&r,
+		validation.Field(&r.Format, validation.Required),
+		validation.Field(&r.Codec, validation.Required),
+		validation.Field(&r.Bitrate,
+			validation.When(lossless, validation.Empty),
+			validation.When(!lossless, validation.Required, validation.Min(8), validation.Max(512)),
+		),
+	)
+}
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"io"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,6 +10,7 @@ import (
 type UploadStatus string
 
 const (
+	UploadStatusUploading  UploadStatus = "uploading"
 	UploadStatusPending    UploadStatus = "pending"
 	UploadStatusProcessing UploadStatus = "processing"
 	UploadStatusDone       UploadStatus = "done"
@@ -16,13 +18,119 @@ const (
 )
 
 type Upload struct {
-	ID        *uuid.UUID    `db:"id"`
-	OwnerID   *uuid.UUID    `db:"owner_id"`
-	Filename  *string       `db:"filename"`
-	S3Key     *string       `db:"s3_key"`
-	Mime      *string       `db:"mime"`
-	Size      *int64        `db:"size"`
-	Status    *UploadStatus `db:"status"`
-	CreatedAt *time.Time    `db:"created_at"`
-	UpdatedAt *time.Time    `db:"updated_at"`
+	ID        *uuid.UUID    `db:"id"         json:"id,omitempty"`
+	OwnerID   *uuid.UUID    `db:"owner_id"   json:"owner_id,omitempty"`
+	Filename  *string       `db:"filename"   json:"filename,omitempty"`
+	S3Key     *string       `db:"s3_key"     json:"s3_key,omitempty"`
+	Mime      *string       `db:"mime"       json:"mime,omitempty"`
+	Size      *int64        `db:"size"       json:"size,omitempty"`
+	Checksum  *string       `db:"checksum"   json:"checksum,omitempty"`
+	Offset    *int64        `db:"upload_offset" json:"offset,omitempty"`
+	Status    *UploadStatus `db:"status"     json:"status,omitempty"`
+	Error     *string       `db:"error"      json:"error,omitempty"`
+	TrackID   *uuid.UUID    `db:"track_id"   json:"track_id,omitempty"`
+	CreatedAt *time.Time    `db:"created_at" json:"created_at,omitempty"`
+	UpdatedAt *time.Time    `db:"updated_at" json:"updated_at,omitempty"`
+}
+
+type (
+	// CreateUploadRequest приём аудиофайла на обработку. Body читается сервисом до конца и сохраняется в хранилище.
+	// Если клиент передал Checksum (SHA-256) содержимого, которое уже есть в его файлах треков или загрузках, Body
+	// можно не передавать: загрузка сошлётся на имеющийся объект. OwnerID заполняет сервис.
+	CreateUploadRequest struct {
+		OwnerID  *uuid.UUID `db:"owner_id" json:"-"`
+		Filename *string    `db:"filename" json:"filename,omitempty" query:"filename"`
+		Mime     *string    `db:"mime"     json:"mime,omitempty"     query:"mime"`
+		Checksum *string    `db:"checksum" json:"checksum,omitempty" query:"checksum"`
+		S3Key    *string    `db:"s3_key"   json:"-"`
+		Size     *int64     `db:"size"     json:"-"`
+
+		Body io.Reader `json:"-"`
+	}
+
+	CreateUploadResponse struct {
+		ID *uuid.UUID `json:"id,omitempty"`
+	}
+)
+
+type (
+	GetUploadRequest struct {
+		ID *uuid.UUID `db:"id" json:"id,omitempty" path:"id"`
+	}
+
+	GetUploadResponse struct {
+		Upload *Upload `json:"upload,omitempty"`
+	}
+)
+
+type (
+	ListUploadsRequest struct {
+		OwnerID *uuid.UUID    `db:"owner_id" query:"owner_id"`
+		Status  *UploadStatus `db:"status"   query:"status"`
+		Limit   *int          `db:"limit"    query:"limit"`
+		Offset  *int          `db:"offset"   query:"offset"`
+	}
+
+	ListUploadsResponse struct {
+		Uploads []*Upload `json:"uploads,omitempty"`
+	}
+)
+
+type (
+	// CreateResumableUploadRequest создание возобновляемой (tus) загрузки. Size общий размер файла, который клиент
+	// затем присылает частями через AppendUploadChunkRequest. OwnerID заполняет сервис.
+	CreateResumableUploadRequest struct {
+		OwnerID  *uuid.UUID `db:"owner_id"`
+		Filename *string    `db:"filename"`
+		Mime     *string    `db:"mime"`
+		Size     *int64     `db:"size"`
+	}
+
+	// AppendUploadChunkRequest очередная часть возобновляемой загрузки. Offset должен совпадать с текущим
+	// смещением загрузки, иначе часть отклоняется с ErrUploadOffsetMismatch.
+	AppendUploadChunkRequest struct {
+		ID     *uuid.UUID `db:"id"`
+		Offset *int64     `db:"upload_offset"`
+		Body   io.Reader
+	}
+
+	AppendUploadChunkResponse struct {
+		Offset int64 `json:"offset"`
+		// Completed true, если загрузка получена целиком: части склеит и обработает воркер загрузок.
+		Completed bool `json:"completed"`
+	}
+)
+
+// AdvanceUploadOffsetRequest сдвиг смещения возобновляемой загрузки на Delta байт при условии, что текущее
+// смещение равно Offset.
+type AdvanceUploadOffsetRequest struct {
+	ID     *uuid.UUID `db:"id"`
+	Offset *int64     `db:"upload_offset"`
+	Delta  *int64     `db:"delta"`
+}
+
+// CompleteResumableUploadRequest части возобновляемой загрузки склеены и сохранены объектом S3Key.
+type CompleteResumableUploadRequest struct {
+	ID       *uuid.UUID `db:"id"`
+	S3Key    *string    `db:"s3_key"`
+	Checksum *string    `db:"checksum"`
+}
+
+type DeleteUploadRequest struct {
+	ID *uuid.UUID `db:"id" json:"-" path:"id"`
+}
+
+// UpdateUploadStatusRequest переход загрузки в новый статус. Error заполняется только для UploadStatusFailed,
+// TrackID для UploadStatusDone.
+type UpdateUploadStatusRequest struct {
+	ID      *uuid.UUID    `db:"id"`
+	Status  *UploadStatus `db:"status"`
+	Error   *string       `db:"error"`
+	TrackID *uuid.UUID    `db:"track_id"`
+}
+
+// ClaimUploadRequest захват следующей загрузки из очереди. Загрузки, застрявшие в статусе processing дольше
+// StaleAfter (например, после падения воркера), считаются брошенными и захватываются повторно.
+type ClaimUploadRequest struct {
+	StaleAfter *time.Duration `db:"stale_after"`
 }
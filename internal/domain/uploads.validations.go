@@ -0,0 +1,69 @@
+package domain
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+
+	validatron "github.com/untea/bottom_babruysk/internal/domain/validation"
+)
+
+var (
+	uploadStatusSet = validatron.NewSet(
+		UploadStatusUploading,
+		UploadStatusPending,
+		UploadStatusProcessing,
+		UploadStatusDone,
+		UploadStatusFailed,
+	)
+)
+
+func (r *CreateUploadRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Filename, validation.Required),
+		validation.Field(&r.Mime, validation.Required),
+		validation.Field(&r.Checksum, validation.When(r.Checksum != nil, checksumRule)),
+		validation.Field(&r.Body, validation.When(r.Checksum == nil, validation.NotNil)),
+	)
+}
+
+func (r *CreateResumableUploadRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Filename, validation.Required),
+		validation.Field(&r.Mime, validation.Required),
+		validation.Field(&r.Size, validation.Required, validation.Min(int64(1))),
+	)
+}
+
+func (r *AppendUploadChunkRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.ID, validation.Required),
+		validation.Field(&r.Offset, validation.NotNil, validation.Min(int64(0))),
+		validation.Field(&r.Body, validation.NotNil),
+	)
+}
+
+func (r *DeleteUploadRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.ID, validation.Required),
+	)
+}
+
+func (r *GetUploadRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.ID, validation.Required),
+	)
+}
+
+func (r *ListUploadsRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Limit, validation.Min(1)),
+		validation.Field(&r.Offset, validation.Min(0)),
+		validation.Field(&r.Status, validation.When(r.Status != nil, validatron.InSetPtr(uploadStatusSet))),
+	)
+}
+
+func (r *UpdateUploadStatusRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.ID, validation.Required),
+		validation.Field(&r.Status, validation.Required, validatron.InSetPtr(uploadStatusSet)),
+	)
+}
@@ -17,7 +17,7 @@ const (
 type User struct {
 	ID           *uuid.UUID `db:"id"            json:"id,omitempty"`
 	Email        *string    `db:"email"         json:"email,omitempty"`
-	PasswordHash *string    `db:"password_hash" json:"password_hash,omitempty"`
+	PasswordHash *string    `db:"password_hash" json:"-"`
 	DisplayName  *string    `db:"display_name"  json:"display_name,omitempty"`
 	Role         *Role      `db:"role"          json:"role,omitempty"`
 	CreatedAt    *time.Time `db:"created_at"    json:"created_at,omitempty"`
@@ -25,9 +25,11 @@ type User struct {
 }
 
 type (
+	// CreateUserRequest Password приходит от клиента открытым текстом, а PasswordHash заполняет сервис.
 	CreateUserRequest struct {
 		Email        *string `db:"email"         json:"email,omitempty"`
-		PasswordHash *string `db:"password_hash" json:"password_hash,omitempty"`
+		Password     *string `db:"-"             json:"password,omitempty"`
+		PasswordHash *string `db:"password_hash" json:"-"`
 		DisplayName  *string `db:"display_name"  json:"display_name,omitempty"`
 		Role         *Role   `db:"role"          json:"role,omitempty"`
 	}
@@ -47,6 +49,11 @@ type (
 	}
 )
 
+// GetUserByEmailRequest поиск пользователя для входа: в отличие от GetUserRequest, возвращается и хеш пароля.
+type GetUserByEmailRequest struct {
+	Email *string `db:"email"`
+}
+
 type (
 	ListUsersRequest struct {
 		Limit     *int    `db:"limit"        json:"limit,omitempty"      query:"limit"`
@@ -8,6 +8,9 @@ import (
 )
 
 var (
+	// passwordRule длина пароля в символах. Верхняя граница только защищает хеширование от гигантских строк.
+	passwordRule = validation.RuneLength(8, 128)
+
 	roleSet = validatron.NewSet(RoleUser, RoleAdmin)
 
 	sortableUserFields = validatron.NewSet(
@@ -22,12 +25,18 @@ var (
 func (r *CreateUserRequest) Validate() error {
 	return validation.ValidateStruct(r,
 		validation.Field(&r.Email, validation.Required, is.Email),
-		validation.Field(&r.PasswordHash, validation.Required),
+		validation.Field(&r.Password, validation.Required, passwordRule),
 		validation.Field(&r.DisplayName, validation.Required),
 		validation.Field(&r.Role, validatron.InSetPtr(roleSet)),
 	)
 }
 
+func (r *GetUserByEmailRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.Email, validation.Required, is.Email),
+	)
+}
+
 func (r *GetUserRequest) Validate() error {
 	return validation.ValidateStruct(r,
 		validation.Field(&r.ID, validation.Required),
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WaveformDefaultPoints число точек обзора, если клиент не указал points.
+const WaveformDefaultPoints = 1024
+
+// WaveformPointsLimit больше точек самый подробный уровень обзора не содержит, поэтому и запрашивать больше
+// бессмысленно.
+const WaveformPointsLimit = 8192
+
+type TrackFileWaveform struct {
+	TrackFileID *uuid.UUID `db:"track_file_id" json:"track_file_id,omitempty"`
+	Peaks       []byte     `db:"peaks"         json:"-"`
+	Error       *string    `db:"error"         json:"error,omitempty"`
+	CreatedAt   *time.Time `db:"created_at"    json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `db:"updated_at"    json:"updated_at,omitempty"`
+}
+
+// ClaimWaveformRequest захват следующего файла трека, для которого ещё нет обзора. Расчёт, не завершившийся за
+// StaleAfter, считается брошенным и захватывается повторно.
+type ClaimWaveformRequest struct {
+	StaleAfter *time.Duration `db:"stale_after"`
+}
+
+// SaveWaveformRequest результат расчёта: либо Peaks, либо Error.
+type SaveWaveformRequest struct {
+	TrackFileID *uuid.UUID `db:"track_file_id"`
+	Peaks       []byte     `db:"peaks"`
+	Error       *string    `db:"error"`
+}
+
+type (
+	// GetWaveformRequest обзор формы волны трека, пересчитанный на Points точек.
+	GetWaveformRequest struct {
+		TrackID *uuid.UUID `json:"-" path:"id"`
+		Points  *int       `json:"-" query:"points"`
+	}
+
+	// GetWaveformResponse обзор в духе JSON-формата audiowaveform: Data — пары min, max 16-битных пиков подряд,
+	// по одной на точку.
+	GetWaveformResponse struct {
+		TrackFileID     *uuid.UUID `json:"track_file_id"`
+		SampleRate      int        `json:"sample_rate"`
+		SamplesPerPixel int        `json:"samples_per_pixel"`
+		Bits            int        `json:"bits"`
+		Length          int        `json:"length"`
+		Data            []int16    `json:"data"`
+	}
+)
@@ -0,0 +1,12 @@
+package domain
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+func (r *GetWaveformRequest) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TrackID, validation.Required),
+		validation.Field(&r.Points, validation.When(r.Points != nil, validation.Min(1), validation.Max(WaveformPointsLimit))),
+	)
+}
@@ -0,0 +1,230 @@
+package hls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+const (
+	// SegmentDuration ориентир длины сегмента (рекомендация Apple для VOD). Сегменты режутся по границам фреймов,
+	// поэтому выходят чуть длиннее.
+	SegmentDuration = 6 * time.Second
+
+	indexMagic   = "BBHI"
+	indexVersion = 1
+	// indexSegmentSize размер одного сегмента в двоичном индексе.
+	indexSegmentSize = 8 + 4 + 4 + 8 + 4
+)
+
+var errIndexInvalid = errors.New("invalid hls index")
+
+// Segment один сегмент: непрерывный диапазон байтов исходного файла, начинающийся с заголовка фрейма.
+type Segment struct {
+	Offset int64
+	Size   int64
+	Frames int
+	// StartSample номер первого сэмпла сегмента от начала потока, по нему считаются метки времени.
+	StartSample uint64
+	Samples     int
+}
+
+// Duration длительность сегмента.
+func (s Segment) Duration(sampleRate int) time.Duration {
+	return time.Duration(float64(s.Samples) / float64(sampleRate) * float64(time.Second))
+}
+
+// Index разбиение сжатого файла (MP3 или AAC в ADTS) на сегменты. Сам файл не перепаковывается: сегмент
+// MPEG-TS собирается из своего диапазона байтов при запросе (см. WriteSegment).
+type Index struct {
+	Stream   audio.ElementaryStream
+	Segments []Segment
+}
+
+// BuildIndex читает поток до конца и режет его на сегменты не короче SegmentDuration.
+func BuildIndex(r io.Reader, codec domain.Codec) (*Index, error) {
+	frames, err := audio.NewFrameReader(r, codec)
+	if err != nil {
+		return nil, err
+	}
+
+	index := &Index{Stream: frames.Stream()}
+	target := int(SegmentDuration.Seconds() * float64(index.Stream.SampleRate))
+
+	var (
+		segment Segment
+		samples uint64
+	)
+
+	for {
+		frame, err := frames.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if segment.Frames == 0 {
+			segment = Segment{Offset: frame.Offset, StartSample: samples}
+		}
+
+		segment.Size = frame.Offset + int64(len(frame.Data)) - segment.Offset
+		segment.Frames++
+		segment.Samples += frame.Samples
+		samples += uint64(frame.Samples)
+
+		if segment.Samples >= target {
+			index.Segments = append(index.Segments, segment)
+			segment = Segment{}
+		}
+	}
+
+	if segment.Frames > 0 {
+		index.Segments = append(index.Segments, segment)
+	}
+
+	return index, nil
+}
+
+// Duration длительность всего потока.
+func (i *Index) Duration() time.Duration {
+	var samples uint64
+	for _, segment := range i.Segments {
+		samples += uint64(segment.Samples)
+	}
+
+	return time.Duration(float64(samples) / float64(i.Stream.SampleRate) * float64(time.Second))
+}
+
+// TargetDuration значение EXT-X-TARGETDURATION: самый длинный сегмент, округлённый вверх до секунды.
+func (i *Index) TargetDuration() int {
+	var longest time.Duration
+	for _, segment := range i.Segments {
+		longest = max(longest, segment.Duration(i.Stream.SampleRate))
+	}
+
+	return int(math.Ceil(longest.Seconds()))
+}
+
+// Bandwidth пиковый и средний битрейт сегментов в бит/с с учётом накладных расходов MPEG-TS — значения
+// BANDWIDTH и AVERAGE-BANDWIDTH для мастер-плейлиста.
+func (i *Index) Bandwidth() (peak, average int) {
+	var (
+		size     int64
+		duration float64
+	)
+
+	for _, segment := range i.Segments {
+		seconds := segment.Duration(i.Stream.SampleRate).Seconds()
+		if seconds <= 0 {
+			continue
+		}
+
+		segmentSize := segmentSizeBound(segment)
+		peak = max(peak, int(math.Ceil(float64(segmentSize)*8/seconds)))
+		size += segmentSize
+		duration += seconds
+	}
+
+	if duration > 0 {
+		average = int(math.Ceil(float64(size) * 8 / duration))
+	}
+
+	return peak, average
+}
+
+// Codecs значение атрибута CODECS (RFC 6381): MP3 в HLS обозначается как mp4a.40.34.
+func (i *Index) Codecs() string {
+	if i.Stream.Codec == domain.CodecMP3 {
+		return "mp4a.40.34"
+	}
+
+	return fmt.Sprintf("mp4a.40.%d", i.Stream.AudioObjectType)
+}
+
+// MarshalBinary кодирует индекс для хранения: сигнатура "BBHI", версия, параметры потока и сегменты (смещение,
+// размер, число фреймов, первый сэмпл, число сэмплов), все числа little-endian.
+func (i *Index) MarshalBinary() ([]byte, error) {
+	b := make([]byte, 0, 4+1+1+4+1+1+1+4+len(i.Segments)*indexSegmentSize)
+	b = append(b, indexMagic...)
+	b = append(b, indexVersion)
+
+	b = append(b, byte(len(i.Stream.Codec)))
+	b = append(b, i.Stream.Codec...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(i.Stream.SampleRate))
+	b = append(b, byte(i.Stream.Channels), byte(i.Stream.AudioObjectType))
+
+	var mpeg1 byte
+	if i.Stream.MPEG1 {
+		mpeg1 = 1
+	}
+
+	b = append(b, mpeg1)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(i.Segments)))
+
+	for _, segment := range i.Segments {
+		b = binary.LittleEndian.AppendUint64(b, uint64(segment.Offset))
+		b = binary.LittleEndian.AppendUint32(b, uint32(segment.Size))
+		b = binary.LittleEndian.AppendUint32(b, uint32(segment.Frames))
+		b = binary.LittleEndian.AppendUint64(b, segment.StartSample)
+		b = binary.LittleEndian.AppendUint32(b, uint32(segment.Samples))
+	}
+
+	return b, nil
+}
+
+func (i *Index) UnmarshalBinary(b []byte) error {
+	if len(b) < 6 || string(b[:4]) != indexMagic {
+		return errIndexInvalid
+	}
+
+	if b[4] != indexVersion {
+		return fmt.Errorf("%w: version %d", errIndexInvalid, b[4])
+	}
+
+	codecLength := int(b[5])
+	b = b[6:]
+
+	if len(b) < codecLength+4+3+4 {
+		return errIndexInvalid
+	}
+
+	i.Stream = audio.ElementaryStream{
+		Codec:           domain.Codec(b[:codecLength]),
+		SampleRate:      int(binary.LittleEndian.Uint32(b[codecLength:])),
+		Channels:        int(b[codecLength+4]),
+		AudioObjectType: int(b[codecLength+5]),
+		MPEG1:           b[codecLength+6] == 1,
+	}
+
+	count := int(binary.LittleEndian.Uint32(b[codecLength+7:]))
+	b = b[codecLength+11:]
+
+	if i.Stream.SampleRate <= 0 || len(b)/indexSegmentSize < count {
+		return errIndexInvalid
+	}
+
+	i.Segments = make([]Segment, count)
+
+	for n := range i.Segments {
+		i.Segments[n] = Segment{
+			Offset:      int64(binary.LittleEndian.Uint64(b[0:8])),
+			Size:        int64(binary.LittleEndian.Uint32(b[8:12])),
+			Frames:      int(binary.LittleEndian.Uint32(b[12:16])),
+			StartSample: binary.LittleEndian.Uint64(b[16:24]),
+			Samples:     int(binary.LittleEndian.Uint32(b[24:28])),
+		}
+
+		b = b[indexSegmentSize:]
+	}
+
+	return nil
+}
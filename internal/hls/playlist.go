@@ -0,0 +1,69 @@
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+const (
+	// ContentTypePlaylist и ContentTypeSegment MIME-типы плейлиста и сегмента MPEG-TS.
+	ContentTypePlaylist = "application/vnd.apple.mpegurl"
+	ContentTypeSegment  = "video/mp2t"
+
+	// playlistVersion версия протокола: дробные длительности в EXTINF требуют версии не ниже 3.
+	playlistVersion = 3
+)
+
+// Variant одна копия трека в мастер-плейлисте.
+type Variant struct {
+	Index *Index
+	// URI адрес медиа-плейлиста копии относительно мастер-плейлиста.
+	URI string
+}
+
+// WriteMasterPlaylist пишет мастер-плейлист с вариантами по возрастанию битрейта.
+func WriteMasterPlaylist(w io.Writer, variants []Variant) error {
+	type stream struct {
+		variant       Variant
+		peak, average int
+	}
+
+	streams := make([]stream, 0, len(variants))
+	for _, variant := range variants {
+		peak, average := variant.Index.Bandwidth()
+		streams = append(streams, stream{variant: variant, peak: peak, average: average})
+	}
+
+	sort.SliceStable(streams, func(i, j int) bool {
+		return streams[i].peak < streams[j].peak
+	})
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "#EXTM3U\n#EXT-X-VERSION:%d\n#EXT-X-INDEPENDENT-SEGMENTS\n", playlistVersion)
+
+	for _, s := range streams {
+		fmt.Fprintf(bw, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d,CODECS=\"%s\"\n%s\n",
+			s.peak, s.average, s.variant.Index.Codecs(), s.variant.URI)
+	}
+
+	return bw.Flush()
+}
+
+// WriteMediaPlaylist пишет VOD-плейлист копии; segmentURI возвращает адрес сегмента по номеру.
+func WriteMediaPlaylist(w io.Writer, index *Index, segmentURI func(n int) string) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "#EXTM3U\n#EXT-X-VERSION:%d\n#EXT-X-TARGETDURATION:%d\n#EXT-X-MEDIA-SEQUENCE:0\n#EXT-X-PLAYLIST-TYPE:VOD\n#EXT-X-INDEPENDENT-SEGMENTS\n",
+		playlistVersion, index.TargetDuration())
+
+	for n, segment := range index.Segments {
+		fmt.Fprintf(bw, "#EXTINF:%.3f,\n%s\n", segment.Duration(index.Stream.SampleRate).Seconds(), segmentURI(n))
+	}
+
+	fmt.Fprint(bw, "#EXT-X-ENDLIST\n")
+
+	return bw.Flush()
+}
@@ -0,0 +1,311 @@
+package hls
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+const (
+	tsPacketSize  = 188
+	tsPayloadSize = tsPacketSize - 4
+
+	tsPATPID   = 0x0000
+	tsPMTPID   = 0x1000
+	tsAudioPID = 0x0101
+	// tsAudioStreamID stream_id PES для первой звуковой дорожки.
+	tsAudioStreamID = 0xC0
+
+	tsStreamTypeMPEG1Audio = 0x03
+	tsStreamTypeMPEG2Audio = 0x04
+	tsStreamTypeADTS       = 0x0F
+
+	// tsClock частота меток времени MPEG-TS.
+	tsClock = 90000
+	// tsTimestampOffset с какой метки начинается поток (как у ffmpeg), а tsPCRLead — насколько PCR опережает PTS:
+	// декодеру нужно время, чтобы успеть получить фреймы до их показа.
+	tsTimestampOffset = 126000
+	tsPCRLead         = 63000
+
+	// tsPESFrames сколько фреймов упаковывается в один PES, tsPESMaxPayload — предел по размеру (PES_packet_length
+	// у звука обязан помещаться в 16 бит).
+	tsPESFrames     = 8
+	tsPESMaxPayload = 60000
+	// tsPESHeaderSize заголовок PES с одним PTS, tsPCRSize — поле адаптации с PCR в первом пакете PES.
+	tsPESHeaderSize = 14
+	tsPCRSize       = 8
+)
+
+// segmentSizeBound верхняя оценка размера сегмента в MPEG-TS: PAT, PMT и по каждому PES заголовок, PCR и
+// неполный последний пакет.
+func segmentSizeBound(segment Segment) int64 {
+	pes := int64((segment.Frames + tsPESFrames - 1) / tsPESFrames)
+	payload := segment.Size + pes*(tsPESHeaderSize+tsPCRSize)
+	packets := 2 + (payload+tsPayloadSize-1)/tsPayloadSize + pes
+
+	return packets * tsPacketSize
+}
+
+// WriteSegment собирает сегмент MPEG-TS из байтов r — диапазона исходного файла, описанного segment. Каждый
+// сегмент самодостаточен: начинается с PAT и PMT, а первый PES несёт PCR и флаг random access.
+func WriteSegment(w io.Writer, r io.Reader, index *Index, segment Segment) error {
+	frames, err := audio.NewFrameReader(r, index.Stream.Codec)
+	if err != nil {
+		return err
+	}
+
+	if frames.Stream() != index.Stream {
+		return fmt.Errorf("%w: segment stream does not match index", errIndexInvalid)
+	}
+
+	m := &tsMuxer{w: w, streamType: tsStreamType(index.Stream)}
+
+	if err = m.writeTables(); err != nil {
+		return err
+	}
+
+	var (
+		payload   []byte
+		count     int
+		sample    = segment.StartSample
+		pesSample = sample
+	)
+
+	for {
+		frame, err := frames.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if count > 0 && (count == tsPESFrames || len(payload)+len(frame.Data) > tsPESMaxPayload) {
+			if err = m.writePES(payload, timestamp(pesSample, index.Stream.SampleRate)); err != nil {
+				return err
+			}
+
+			payload, count, pesSample = payload[:0], 0, sample
+		}
+
+		payload = append(payload, frame.Data...)
+		count++
+		sample += uint64(frame.Samples)
+	}
+
+	if count > 0 {
+		return m.writePES(payload, timestamp(pesSample, index.Stream.SampleRate))
+	}
+
+	return nil
+}
+
+func tsStreamType(stream audio.ElementaryStream) byte {
+	switch {
+	case stream.Codec == domain.CodecAAC:
+		return tsStreamTypeADTS
+	case stream.MPEG1:
+		return tsStreamTypeMPEG1Audio
+	default:
+		return tsStreamTypeMPEG2Audio
+	}
+}
+
+// timestamp метка времени сэмпла в тактах 90 кГц.
+func timestamp(sample uint64, sampleRate int) uint64 {
+	return tsTimestampOffset + sample*tsClock/uint64(sampleRate)
+}
+
+type tsMuxer struct {
+	w          io.Writer
+	streamType byte
+	continuity map[uint16]byte
+	packet     [tsPacketSize]byte
+	// pcrWritten PCR пишется в первый PES сегмента, вместе с флагом random access.
+	pcrWritten bool
+}
+
+func (m *tsMuxer) writeTables() error {
+	pat := []byte{
+		0x00,       // table_id: program_association_section
+		0xB0, 0x0D, // section_syntax_indicator, section_length
+		0x00, 0x01, // transport_stream_id
+		0xC1,       // version 0, current_next_indicator
+		0x00, 0x00, // section_number, last_section_number
+		0x00, 0x01, // program_number
+		0xE0 | tsPMTPID>>8, tsPMTPID & 0xFF,
+	}
+
+	pmt := []byte{
+		0x02,       // table_id: TS_program_map_section
+		0xB0, 0x12, // section_syntax_indicator, section_length
+		0x00, 0x01, // program_number
+		0xC1,       // version 0, current_next_indicator
+		0x00, 0x00, // section_number, last_section_number
+		0xE0 | tsAudioPID>>8, tsAudioPID & 0xFF, // PCR_PID
+		0xF0, 0x00, // program_info_length
+		m.streamType,
+		0xE0 | tsAudioPID>>8, tsAudioPID & 0xFF, // elementary_PID
+		0xF0, 0x00, // ES_info_length
+	}
+
+	if err := m.writeSection(tsPATPID, pat); err != nil {
+		return err
+	}
+
+	return m.writeSection(tsPMTPID, pmt)
+}
+
+// writeSection пишет таблицу PSI в один пакет: pointer_field, секция с CRC и заполнение 0xFF.
+func (m *tsMuxer) writeSection(pid uint16, section []byte) error {
+	p := m.header(pid, true, false)
+
+	payload := m.packet[4:]
+	payload[0] = 0
+
+	n := 1 + copy(payload[1:], section)
+	binary.BigEndian.PutUint32(payload[n:], crc32MPEG(section))
+	n += 4
+
+	for i := n; i < len(payload); i++ {
+		payload[i] = 0xFF
+	}
+
+	_, err := m.w.Write(p)
+
+	return err
+}
+
+// writePES режет PES с метками времени pts на пакеты. Последний пакет добивается до 188 байт полем адаптации.
+func (m *tsMuxer) writePES(payload []byte, pts uint64) error {
+	pes := make([]byte, 0, tsPESHeaderSize+len(payload))
+	pes = append(pes, 0x00, 0x00, 0x01, tsAudioStreamID)
+	pes = binary.BigEndian.AppendUint16(pes, uint16(3+5+len(payload)))
+	pes = append(pes, 0x80, 0x80, 0x05) // marker bits, PTS_DTS_flags = '10', PES_header_data_length
+	pes = appendTimestamp(pes, 0x2, pts)
+	pes = append(pes, payload...)
+
+	for first := true; len(pes) > 0; first = false {
+		var adaptation []byte
+
+		if first && !m.pcrWritten {
+			adaptation = appendPCR([]byte{0x00, 0x50}, pts-tsPCRLead) // random_access_indicator, PCR_flag
+			m.pcrWritten = true
+		}
+
+		room := tsPayloadSize - len(adaptation)
+		if len(adaptation) == 0 && len(pes) < room {
+			adaptation = []byte{0x00}
+			room--
+		}
+
+		// Длина поля адаптации не включает сам байт длины; если оно есть, недостающее место заполняется 0xFF.
+		if len(adaptation) > 0 {
+			stuffing := max(room-len(pes), 0)
+			if len(adaptation) == 1 && stuffing > 0 {
+				adaptation = append(adaptation, 0x00)
+				stuffing--
+			}
+
+			for range stuffing {
+				adaptation = append(adaptation, 0xFF)
+			}
+
+			adaptation[0] = byte(len(adaptation) - 1)
+			room = tsPayloadSize - len(adaptation)
+		}
+
+		p := m.header(tsAudioPID, first, len(adaptation) > 0)
+		n := 4 + copy(m.packet[4:], adaptation)
+		chunk := min(room, len(pes))
+		copy(m.packet[n:], pes[:chunk])
+		pes = pes[chunk:]
+
+		if _, err := m.w.Write(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// header заполняет заголовок пакета и возвращает весь пакет; счётчик непрерывности у каждого PID свой.
+func (m *tsMuxer) header(pid uint16, unitStart, adaptation bool) []byte {
+	if m.continuity == nil {
+		m.continuity = make(map[uint16]byte)
+	}
+
+	counter := m.continuity[pid]
+	m.continuity[pid] = (counter + 1) & 0x0F
+
+	control := byte(0x10) // только полезная нагрузка
+	if adaptation {
+		control = 0x30
+	}
+
+	m.packet[0] = 0x47
+	m.packet[1] = byte(pid>>8) & 0x1F
+	m.packet[2] = byte(pid)
+	m.packet[3] = control | counter
+
+	if unitStart {
+		m.packet[1] |= 0x40
+	}
+
+	return m.packet[:]
+}
+
+// appendTimestamp 33-битная метка PTS/DTS в пяти байтах с маркерными битами; prefix — старшие 4 бита.
+func appendTimestamp(b []byte, prefix byte, ts uint64) []byte {
+	return append(b,
+		prefix<<4|byte(ts>>29)&0x0E|0x01,
+		byte(ts>>22),
+		byte(ts>>14)|0x01,
+		byte(ts>>7),
+		byte(ts<<1)|0x01,
+	)
+}
+
+// appendPCR PCR из 33-битной базы (90 кГц) с нулевым расширением.
+func appendPCR(b []byte, base uint64) []byte {
+	return append(b,
+		byte(base>>25),
+		byte(base>>17),
+		byte(base>>9),
+		byte(base>>1),
+		byte(base<<7)|0x7E,
+		0x00,
+	)
+}
+
+var crc32MPEGTable = func() (table [256]uint32) {
+	for i := range table {
+		crc := uint32(i) << 24
+		for range 8 {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04C11DB7
+			} else {
+				crc <<= 1
+			}
+		}
+
+		table[i] = crc
+	}
+
+	return table
+}()
+
+// crc32MPEG CRC-32/MPEG-2 секций PSI: полином 0x04C11DB7 без отражения, начальное значение 0xFFFFFFFF.
+func crc32MPEG(b []byte) uint32 {
+	crc := uint32(0xFFFFFFFF)
+	for _, v := range b {
+		crc = crc<<8 ^ crc32MPEGTable[byte(crc>>24)^v]
+	}
+
+	return crc
+}
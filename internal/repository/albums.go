@@ -41,7 +41,9 @@ func (r *AlbumsRepository) CreateAlbum(ctx context.Context, request domain.Creat
 
 func (r *AlbumsRepository) GetAlbum(ctx context.Context, request domain.GetAlbumRequest) (*domain.GetAlbumResponse, error) {
 	const getAlbumSQL = `
-		select id, owner_id, title, description, release_date, created_at, updated_at
+		select
+			id, owner_id, title, description, release_date, created_at, updated_at,
+			integrated_loudness, loudness_range, true_peak, replaygain_album_gain, replaygain_album_peak
 		from albums
 		where id = $1;
 	`
@@ -70,7 +72,8 @@ func (r *AlbumsRepository) ListAlbums(ctx context.Context, request domain.ListAl
 				greatest(coalesce($4, 0), 0)                  as offset_val
 		)
 		select
-			a.id, a.owner_id, a.title, a.description, a.release_date, a.created_at, a.updated_at
+			a.id, a.owner_id, a.title, a.description, a.release_date, a.created_at, a.updated_at,
+			a.integrated_loudness, a.loudness_range, a.true_peak, a.replaygain_album_gain, a.replaygain_album_peak
 		from albums a, params p
 		order by
 			case when p.sort_field = 'title'        and p.sort_order = 'asc'  then a.title        end nulls last,
@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type BlobsRepository struct {
+	db *postgres.Client
+}
+
+func NewBlobsRepository(db *postgres.Client) *BlobsRepository {
+	return &BlobsRepository{db: db}
+}
+
+// AcquireBlob регистрирует содержимое или, если оно уже зарегистрировано, возвращает имеющуюся запись. В обоих
+// случаях updated_at сдвигается, и объект не будет собран, пока на него не успеет сослаться вызывающая сторона.
+func (r *BlobsRepository) AcquireBlob(ctx context.Context, request domain.AcquireBlobRequest) (*domain.Blob, error) {
+	const acquireBlobSQL = `
+		insert into blobs (checksum,
+		                   s3_key,
+		                   size,
+		                   mime)
+		values ($1,
+		        $2,
+		        $3,
+		        $4)
+		on conflict (checksum) do update
+		set updated_at = now()
+		returning
+			s3_key,
+			checksum,
+			size,
+			mime,
+			ref_count,
+			stored_at,
+			created_at,
+			updated_at;
+	`
+
+	arguments := []any{
+		request.Checksum,
+		request.S3Key,
+		request.Size,
+		request.Mime,
+	}
+
+	return postgres.FetchOne[domain.Blob](ctx, r.db, acquireBlobSQL, arguments...)
+}
+
+// TouchBlob находит сохранённое содержимое по контрольной сумме и, как AcquireBlob, откладывает его сборку. Если
+// содержимого нет, оно ещё не записано целиком или на него не ссылается ReferencedBy, возвращается
+// postgres.ErrNotFound.
+func (r *BlobsRepository) TouchBlob(ctx context.Context, request domain.GetBlobRequest) (*domain.Blob, error) {
+	const touchBlobSQL = `
+		update blobs as b
+		set updated_at = now()
+		where b.checksum = $1
+		  and b.stored_at is not null
+		  and ($2::uuid is null
+		       or exists (select 1
+		                  from track_files as tf
+		                  join tracks as t on t.id = tf.track_id
+		                  where tf.s3_key = b.s3_key
+		                    and t.uploader_id = $2)
+		       or exists (select 1
+		                  from uploads as u
+		                  where u.s3_key = b.s3_key
+		                    and u.owner_id = $2))
+		returning
+			s3_key,
+			checksum,
+			size,
+			mime,
+			ref_count,
+			stored_at,
+			created_at,
+			updated_at;
+	`
+
+	arguments := []any{
+		request.Checksum,
+		request.ReferencedBy,
+	}
+
+	return postgres.FetchOne[domain.Blob](ctx, r.db, touchBlobSQL, arguments...)
+}
+
+// GetBlob находит сохранённое содержимое по контрольной сумме так же, как TouchBlob, но не откладывает его сборку.
+func (r *BlobsRepository) GetBlob(ctx context.Context, request domain.GetBlobRequest) (*domain.Blob, error) {
+	const getBlobSQL = `
+		select
+			b.s3_key,
+			b.checksum,
+			b.size,
+			b.mime,
+			b.ref_count,
+			b.stored_at,
+			b.created_at,
+			b.updated_at
+		from blobs as b
+		where b.checksum = $1
+		  and b.stored_at is not null
+		  and ($2::uuid is null
+		       or exists (select 1
+		                  from track_files as tf
+		                  join tracks as t on t.id = tf.track_id
+		                  where tf.s3_key = b.s3_key
+		                    and t.uploader_id = $2)
+		       or exists (select 1
+		                  from uploads as u
+		                  where u.s3_key = b.s3_key
+		                    and u.owner_id = $2));
+	`
+
+	arguments := []any{
+		request.Checksum,
+		request.ReferencedBy,
+	}
+
+	return postgres.FetchOne[domain.Blob](ctx, r.db, getBlobSQL, arguments...)
+}
+
+func (r *BlobsRepository) MarkBlobStored(ctx context.Context, request domain.MarkBlobStoredRequest) error {
+	const markBlobStoredSQL = `
+		update blobs
+		set
+			stored_at  = now(),
+			updated_at = now()
+		where s3_key = $1;
+	`
+
+	arguments := []any{
+		request.S3Key,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, markBlobStoredSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteUnreferencedBlob удаляет и возвращает запись самого давно забытого объекта без ссылок. Сам объект в
+// хранилище удаляет вызывающая сторона: после удаления записи то же содержимое регистрируется заново под новым
+// ключом, так что удаление старого объекта никому не помешает. Если собирать нечего, возвращается
+// postgres.ErrNotFound.
+func (r *BlobsRepository) DeleteUnreferencedBlob(ctx context.Context, request domain.DeleteUnreferencedBlobRequest) (*domain.Blob, error) {
+	const deleteUnreferencedBlobSQL = `
+		delete from blobs
+		where s3_key = (
+			select s3_key
+			from blobs
+			where ref_count = 0
+			  and updated_at < now() - $1::interval
+			order by updated_at
+			limit 1
+			for update skip locked
+		)
+		  and ref_count = 0
+		returning
+			s3_key,
+			checksum,
+			size,
+			mime,
+			ref_count,
+			stored_at,
+			created_at,
+			updated_at;
+	`
+
+	arguments := []any{
+		request.GracePeriod,
+	}
+
+	return postgres.FetchOne[domain.Blob](ctx, r.db, deleteUnreferencedBlobSQL, arguments...)
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type FingerprintsRepository struct {
+	db *postgres.Client
+}
+
+func NewFingerprintsRepository(db *postgres.Client) *FingerprintsRepository {
+	return &FingerprintsRepository{db: db}
+}
+
+// ClaimFingerprint захватывает самый старый исходный файл трека, который умеем декодировать и у которого ещё нет
+// отпечатка. Копии, полученные транскодированием (source_id не NULL), звучат так же, как исходный файл, поэтому
+// отпечаток им не нужен.
+func (r *FingerprintsRepository) ClaimFingerprint(ctx context.Context, request domain.ClaimFingerprintRequest) (*domain.TrackFile, error) {
+	const claimFingerprintSQL = `
+		with candidate as (
+			select tf.id
+			from track_files as tf
+			left join track_file_fingerprints as f on f.track_file_id = tf.id
+			where tf.format in ('flac'::format, 'wav'::format)
+			  and tf.s3_key is not null
+			  and tf.source_id is null
+			  and tf.healthy is not false
+			  and (f.track_file_id is null
+			       or (f.fingerprint is null and f.error is null and f.updated_at < now() - $1::interval))
+			order by tf.created_at
+			limit 1
+			for update of tf skip locked
+		), claimed as (
+			insert into track_file_fingerprints (track_file_id)
+			select id from candidate
+			on conflict (track_file_id) do update
+			set updated_at = now()
+			returning track_file_id
+		)
+		select
+			tf.id,
+			tf.track_id,
+			tf.filename,
+			tf.s3_key,
+			tf.mime,
+			tf.format,
+			tf.codec,
+			tf.bitrate,
+			tf.sample_rate,
+			tf.channels,
+			tf.size,
+			tf.duration,
+			tf.checksum,
+			tf.healthy,
+			tf.integrity_error,
+			tf.source_id,
+			tf.start_sample,
+			tf.end_sample,
+			tf.created_at,
+			tf.updated_at,
+			tf.uploaded_at
+		from track_files as tf
+		join claimed as c on c.track_file_id = tf.id;
+	`
+
+	arguments := []any{
+		request.StaleAfter,
+	}
+
+	return postgres.FetchOne[domain.TrackFile](ctx, r.db, claimFingerprintSQL, arguments...)
+}
+
+func (r *FingerprintsRepository) SaveFingerprint(ctx context.Context, request domain.SaveFingerprintRequest) error {
+	const saveFingerprintSQL = `
+		update track_file_fingerprints
+		set
+			fingerprint = $2,
+			lookup_keys = $3,
+			error       = $4,
+			updated_at  = now()
+		where track_file_id = $1;
+	`
+
+	arguments := []any{
+		request.TrackFileID,
+		request.Fingerprint,
+		request.LookupKeys,
+		request.Error,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, saveFingerprintSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *FingerprintsRepository) ListFingerprints(ctx context.Context, request domain.ListFingerprintsRequest) (*domain.ListFingerprintsResponse, error) {
+	const listFingerprintsSQL = `
+		select
+			f.track_file_id,
+			tf.track_id,
+			tf.duration,
+			f.fingerprint,
+			f.error,
+			f.created_at,
+			f.updated_at
+		from track_file_fingerprints as f
+		join track_files as tf on tf.id = f.track_file_id
+		where f.fingerprint is not null
+		  and ($1::uuid is null or tf.track_id = $1)
+		  and ($2::uuid is null or tf.track_id <> $2)
+		  and ($3::interval is null or tf.duration >= $3)
+		  and ($4::interval is null or tf.duration <= $4)
+		  and ($5::integer[] is null or f.lookup_keys && $5)
+		order by tf.track_id, tf.created_at;
+	`
+
+	arguments := []any{
+		request.TrackID,
+		request.ExcludeTrackID,
+		request.MinDuration,
+		request.MaxDuration,
+		request.LookupKeys,
+	}
+
+	fingerprints, err := postgres.FetchMany[domain.TrackFileFingerprint](ctx, r.db, listFingerprintsSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListFingerprintsResponse{
+		Fingerprints: fingerprints,
+	}, nil
+}
+
+// MergeTracks в одной транзакции переносит на трек лайки, элементы плейлистов и позиции в альбомах его дубликатов
+// и удаляет дубликаты. Если трек уже есть в плейлисте или на том же диске альбома, что и дубликат, остаётся его
+// собственная запись; из нескольких дубликатов в одном плейлисте или на одном диске переносится стоящий раньше.
+// Если трека или какого-то из дубликатов нет, ничего не меняется и возвращается postgres.ErrNotFound.
+func (r *FingerprintsRepository) MergeTracks(ctx context.Context, request domain.MergeTracksRequest) (*domain.MergeTracksResponse, error) {
+	const lockTracksSQL = `
+		select id
+		from tracks
+		where id = $1 or id = any($2::uuid[])
+		for update;
+	`
+
+	const mergeLikesSQL = `
+		insert into track_likes (user_id, track_id, created_at)
+		select user_id, $1, min(created_at)
+		from track_likes
+		where track_id = any($2::uuid[])
+		group by user_id
+		on conflict (user_id, track_id) do update
+		set created_at = least(track_likes.created_at, excluded.created_at);
+	`
+
+	const mergePlaylistItemsSQL = `
+		insert into playlist_items (playlist_id, track_id, position, added_at)
+		select distinct on (playlist_id) playlist_id, $1, position, added_at
+		from playlist_items
+		where track_id = any($2::uuid[])
+		order by playlist_id, position
+		on conflict (playlist_id, track_id) do nothing;
+	`
+
+	const mergeAlbumTracksSQL = `
+		with chosen as (
+			select distinct on (at.album_id, at.disc_number) at.album_id, at.disc_number, at.position
+			from album_tracks as at
+			where at.track_id = any($2::uuid[])
+			  and not exists (
+				select 1
+				from album_tracks as o
+				where o.album_id = at.album_id and o.disc_number = at.disc_number and o.track_id = $1
+			  )
+			order by at.album_id, at.disc_number, at.position
+		)
+		update album_tracks as at
+		set track_id = $1
+		from chosen as c
+		where at.album_id = c.album_id and at.disc_number = c.disc_number and at.position = c.position;
+	`
+
+	const deleteTracksSQL = `
+		delete from tracks where id = any($1::uuid[]);
+	`
+
+	response := &domain.MergeTracksResponse{
+		TrackID: request.TrackID,
+	}
+
+	arguments := []any{
+		request.TrackID,
+		request.DuplicateIDs,
+	}
+
+	err := r.db.InTransaction(ctx, func(tx postgres.Driver) error {
+		locked, err := postgres.FetchMany[domain.Track](ctx, tx, lockTracksSQL, arguments...)
+		if err != nil {
+			return err
+		}
+
+		if len(locked) != len(request.DuplicateIDs)+1 {
+			return postgres.ErrNotFound
+		}
+
+		for _, mergeSQL := range []string{mergeLikesSQL, mergePlaylistItemsSQL, mergeAlbumTracksSQL} {
+			if _, err = postgres.ExecAffected(ctx, tx, mergeSQL, arguments...); err != nil {
+				return err
+			}
+		}
+
+		affected, err := postgres.ExecAffected(ctx, tx, deleteTracksSQL, request.DuplicateIDs)
+		if err != nil {
+			return err
+		}
+
+		response.Merged = int(affected)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type HLSRepository struct {
+	db *postgres.Client
+}
+
+func NewHLSRepository(db *postgres.Client) *HLSRepository {
+	return &HLSRepository{db: db}
+}
+
+// ClaimHLSIndex захватывает самый старый файл MP3 или AAC (ADTS), который ещё не разбит на сегменты, так же как
+// ClaimWaveform. Повреждённые файлы пропускаются.
+func (r *HLSRepository) ClaimHLSIndex(ctx context.Context, request domain.ClaimHLSIndexRequest) (*domain.TrackFile, error) {
+	const claimHLSIndexSQL = `
+		with candidate as (
+			select tf.id
+			from track_files as tf
+			left join track_file_hls_indexes as h on h.track_file_id = tf.id
+			where ((tf.format = 'mp3'::format and tf.codec = 'mp3'::codec)
+			       or (tf.format = 'aac'::format and tf.codec = 'aac'::codec))
+			  and tf.s3_key is not null
+			  and tf.healthy is not false
+			  and (h.track_file_id is null
+			       or (h.segments is null and h.error is null and h.updated_at < now() - $1::interval))
+			order by tf.created_at
+			limit 1
+			for update of tf skip locked
+		), claimed as (
+			insert into track_file_hls_indexes (track_file_id)
+			select id from candidate
+			on conflict (track_file_id) do update
+			set updated_at = now()
+			returning track_file_id
+		)
+		select
+			tf.id,
+			tf.track_id,
+			tf.filename,
+			tf.s3_key,
+			tf.mime,
+			tf.format,
+			tf.codec,
+			tf.bitrate,
+			tf.sample_rate,
+			tf.channels,
+			tf.size,
+			tf.duration,
+			tf.checksum,
+			tf.healthy,
+			tf.integrity_error,
+			tf.source_id,
+			tf.start_sample,
+			tf.end_sample,
+			tf.created_at,
+			tf.updated_at,
+			tf.uploaded_at
+		from track_files as tf
+		join claimed as c on c.track_file_id = tf.id;
+	`
+
+	arguments := []any{
+		request.StaleAfter,
+	}
+
+	return postgres.FetchOne[domain.TrackFile](ctx, r.db, claimHLSIndexSQL, arguments...)
+}
+
+func (r *HLSRepository) SaveHLSIndex(ctx context.Context, request domain.SaveHLSIndexRequest) error {
+	const saveHLSIndexSQL = `
+		update track_file_hls_indexes
+		set
+			segments   = $2,
+			error      = $3,
+			updated_at = now()
+		where track_file_id = $1;
+	`
+
+	arguments := []any{
+		request.TrackFileID,
+		request.Segments,
+		request.Error,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, saveHLSIndexSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListHLSVariants возвращает разбитые на сегменты копии трека по возрастанию битрейта.
+func (r *HLSRepository) ListHLSVariants(ctx context.Context, request domain.ListHLSVariantsRequest) (*domain.ListHLSVariantsResponse, error) {
+	const listHLSVariantsSQL = `
+		select
+			tf.id as track_file_id,
+			tf.s3_key,
+			tf.codec,
+			tf.bitrate,
+			h.segments
+		from track_files as tf
+		join track_file_hls_indexes as h on h.track_file_id = tf.id
+		where tf.track_id = $1
+		  and ($2::uuid is null or tf.id = $2)
+		  and tf.s3_key is not null
+		  and h.segments is not null
+		order by tf.bitrate nulls last, tf.created_at;
+	`
+
+	arguments := []any{
+		request.TrackID,
+		request.TrackFileID,
+	}
+
+	variants, err := postgres.FetchMany[domain.HLSVariant](ctx, r.db, listHLSVariantsSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListHLSVariantsResponse{Variants: variants}, nil
+}
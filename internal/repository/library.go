@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type LibraryRepository struct {
+	db *postgres.Client
+}
+
+func NewLibraryRepository(db *postgres.Client) *LibraryRepository {
+	return &LibraryRepository{db: db}
+}
+
+// ImportTrack в одной транзакции находит или создаёт исполнителей и альбом, создаёт трек с файлом и связывает их.
+// Исполнители ищутся по имени без учёта регистра, альбом — по названию и первому исполнителю альбома.
+func (r *LibraryRepository) ImportTrack(ctx context.Context, request domain.ImportTrackRequest) (*domain.ImportTrackResponse, error) {
+	var response *domain.ImportTrackResponse
+
+	err := r.db.InTransaction(ctx, func(tx postgres.Driver) (err error) {
+		response, err = importTrack(ctx, tx, request)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ImportTracks импортирует треки по порядку в одной транзакции, как ImportTrack каждый. Альбом, созданный для
+// первого трека, находят и следующие.
+func (r *LibraryRepository) ImportTracks(ctx context.Context, request domain.ImportTracksRequest) (*domain.ImportTracksResponse, error) {
+	response := &domain.ImportTracksResponse{}
+
+	err := r.db.InTransaction(ctx, func(tx postgres.Driver) error {
+		for _, track := range request.Tracks {
+			imported, err := importTrack(ctx, tx, track)
+			if err != nil {
+				return err
+			}
+
+			response.Tracks = append(response.Tracks, imported)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func importTrack(ctx context.Context, tx postgres.Driver, request domain.ImportTrackRequest) (*domain.ImportTrackResponse, error) {
+	response := &domain.ImportTrackResponse{}
+
+	artistIDs, err := upsertArtists(ctx, tx, request.Artists)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Album != nil {
+		albumArtistIDs, err := upsertArtists(ctx, tx, []string{*request.AlbumArtist})
+		if err != nil {
+			return nil, err
+		}
+
+		response.AlbumID, err = upsertAlbum(ctx, tx, request, albumArtistIDs[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	track, err := createTrack(ctx, tx, domain.CreateTrackRequest{
+		UploaderID:  request.OwnerID,
+		Title:       request.Title,
+		Subtitle:    utils.Ptr(""),
+		Description: utils.Ptr(""),
+		Duration:    request.Duration,
+		Visibility:  request.Visibility,
+		UploadedAt:  request.UploadedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.TrackID = track.ID
+
+	if err = linkTrackArtists(ctx, tx, *track.ID, artistIDs); err != nil {
+		return nil, err
+	}
+
+	if response.AlbumID != nil {
+		if err = linkAlbumTrack(ctx, tx, *response.AlbumID, *track.ID, request); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(request.Genres) > 0 {
+		err = setTrackGenres(ctx, tx, domain.SetTrackGenresRequest{TrackID: track.ID, Genres: request.Genres})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	trackFile, err := createTrackFile(ctx, tx, domain.CreateTrackFileRequest{
+		TrackID:     track.ID,
+		Filename:    request.Filename,
+		S3Key:       request.S3Key,
+		Mime:        request.Mime,
+		Format:      request.Format,
+		Codec:       request.Codec,
+		Bitrate:     request.Bitrate,
+		SampleRate:  request.SampleRate,
+		Channels:    request.Channels,
+		Size:        request.Size,
+		Duration:    request.Duration,
+		Checksum:    request.Checksum,
+		UploadedAt:  request.UploadedAt,
+		StartSample: request.StartSample,
+		EndSample:   request.EndSample,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	response.TrackFileID = trackFile.ID
+
+	return response, nil
+}
+
+func (r *LibraryRepository) FindTrackFileByChecksum(ctx context.Context, request domain.FindTrackFileByChecksumRequest) (*domain.FindTrackFileByChecksumResponse, error) {
+	const findTrackFileByChecksumSQL = `
+		select
+		    id,
+		    track_id,
+		    filename,
+		    s3_key,
+		    mime,
+		    format,
+		    codec,
+		    bitrate,
+		    sample_rate,
+		    channels,
+		    size,
+		    duration,
+		    checksum,
+		    healthy,
+		    integrity_error,
+		    source_id,
+		    start_sample,
+		    end_sample,
+		    created_at,
+		    updated_at,
+		    uploaded_at
+		from track_files
+		where checksum = $1
+		order by created_at
+		limit 1;
+	`
+
+	arguments := []any{
+		request.Checksum,
+	}
+
+	trackFile, err := postgres.FetchOne[domain.TrackFile](ctx, r.db, findTrackFileByChecksumSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.FindTrackFileByChecksumResponse{
+		TrackFile: trackFile,
+	}, nil
+}
+
+// upsertArtists возвращает идентификаторы исполнителей в порядке names, создавая отсутствующих. Повторы имён
+// (без учёта регистра) схлопываются в первое вхождение.
+func upsertArtists(ctx context.Context, driver postgres.Driver, names []string) ([]uuid.UUID, error) {
+	const upsertArtistsSQL = `
+		with input as (
+			select distinct on (lower(name)) name, ord
+			from unnest($1::text[]) with ordinality as n(name, ord)
+			order by lower(name), ord
+		), existing as (
+			select distinct on (lower(a.name)) a.id, lower(a.name) as key
+			from artists as a
+			join input as i on lower(a.name) = lower(i.name)
+			order by lower(a.name), a.created_at
+		), inserted as (
+			insert into artists (name, bio)
+			select i.name, ''
+			from input as i
+			where not exists (select 1 from existing as e where e.key = lower(i.name))
+			returning id, lower(name) as key
+		)
+		select a.id
+		from (select id, key from existing union all select id, key from inserted) as a
+		join input as i on a.key = lower(i.name)
+		order by i.ord;
+	`
+
+	arguments := []any{
+		names,
+	}
+
+	artists, err := postgres.FetchMany[domain.Artist](ctx, driver, upsertArtistsSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, 0, len(artists))
+	for _, artist := range artists {
+		ids = append(ids, *artist.ID)
+	}
+
+	return ids, nil
+}
+
+// upsertAlbum находит альбом с тем же названием (без учёта регистра) и тем же первым исполнителем или создаёт его.
+func upsertAlbum(ctx context.Context, driver postgres.Driver, request domain.ImportTrackRequest, artistID uuid.UUID) (*uuid.UUID, error) {
+	const upsertAlbumSQL = `
+		with existing as (
+			select a.id
+			from albums as a
+			join album_artists as aa on aa.album_id = a.id and aa.ord = 0
+			where lower(a.title) = lower($1) and aa.artist_id = $2
+			order by a.created_at
+			limit 1
+		), inserted as (
+			insert into albums (owner_id, title, description, release_date)
+			select $3, $1, '', $4
+			where not exists (select 1 from existing)
+			returning id
+		), linked as (
+			insert into album_artists (album_id, artist_id, ord)
+			select id, $2, 0 from inserted
+		)
+		select id from existing
+		union all
+		select id from inserted;
+	`
+
+	arguments := []any{
+		request.Album,
+		artistID,
+		request.OwnerID,
+		request.ReleaseDate,
+	}
+
+	album, err := postgres.FetchOne[domain.Album](ctx, driver, upsertAlbumSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return album.ID, nil
+}
+
+func linkTrackArtists(ctx context.Context, driver postgres.Driver, trackID uuid.UUID, artistIDs []uuid.UUID) error {
+	const linkTrackArtistsSQL = `
+		insert into track_artists (track_id, artist_id, ord)
+		select $1, a.artist_id, a.ord - 1
+		from unnest($2::uuid[]) with ordinality as a(artist_id, ord)
+		on conflict (track_id, artist_id) do nothing;
+	`
+
+	arguments := []any{
+		trackID,
+		artistIDs,
+	}
+
+	_, err := postgres.ExecAffected(ctx, driver, linkTrackArtistsSQL, arguments...)
+
+	return err
+}
+
+// linkAlbumTrack ставит трек на его позицию в альбоме, а если позиция не известна или уже занята — в конец диска.
+func linkAlbumTrack(ctx context.Context, driver postgres.Driver, albumID, trackID uuid.UUID, request domain.ImportTrackRequest) error {
+	const linkAlbumTrackSQL = `
+		with target as (
+			select $1::uuid as album_id, coalesce($2::integer, 1) as disc_number, $3::integer as position
+		), next as (
+			select coalesce(max(at.position), 0) + 1 as position
+			from album_tracks as at, target as t
+			where at.album_id = t.album_id and at.disc_number = t.disc_number
+		)
+		insert into album_tracks (album_id, disc_number, position, track_id)
+		select
+			t.album_id,
+			t.disc_number,
+			case
+				when t.position is null or exists (
+					select 1
+					from album_tracks as at
+					where at.album_id = t.album_id and at.disc_number = t.disc_number and at.position = t.position
+				) then n.position
+				else t.position
+			end,
+			$4
+		from target as t, next as n
+		on conflict do nothing;
+	`
+
+	arguments := []any{
+		albumID,
+		request.DiscNumber,
+		request.Position,
+		trackID,
+	}
+
+	_, err := postgres.ExecAffected(ctx, driver, linkAlbumTrackSQL, arguments...)
+
+	return err
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type LoudnessRepository struct {
+	db *postgres.Client
+}
+
+func NewLoudnessRepository(db *postgres.Client) *LoudnessRepository {
+	return &LoudnessRepository{db: db}
+}
+
+// ClaimLoudness захватывает самый старый файл трека, который умеем декодировать и громкость которого ещё не
+// измерена: заводит для него пустую строку в track_file_loudness и возвращает сам файл. Повреждённые файлы
+// пропускаются.
+func (r *LoudnessRepository) ClaimLoudness(ctx context.Context, request domain.ClaimLoudnessRequest) (*domain.TrackFile, error) {
+	const claimLoudnessSQL = `
+		with candidate as (
+			select tf.id
+			from track_files as tf
+			left join track_file_loudness as l on l.track_file_id = tf.id
+			where tf.format in ('flac'::format, 'wav'::format)
+			  and tf.s3_key is not null
+			  and tf.healthy is not false
+			  and (l.track_file_id is null
+			       or (l.histogram is null and l.error is null and l.updated_at < now() - $1::interval))
+			order by tf.created_at
+			limit 1
+			for update of tf skip locked
+		), claimed as (
+			insert into track_file_loudness (track_file_id)
+			select id from candidate
+			on conflict (track_file_id) do update
+			set updated_at = now()
+			returning track_file_id
+		)
+		select
+			tf.id,
+			tf.track_id,
+			tf.filename,
+			tf.s3_key,
+			tf.mime,
+			tf.format,
+			tf.codec,
+			tf.bitrate,
+			tf.sample_rate,
+			tf.channels,
+			tf.size,
+			tf.duration,
+			tf.checksum,
+			tf.healthy,
+			tf.integrity_error,
+			tf.source_id,
+			tf.start_sample,
+			tf.end_sample,
+			tf.created_at,
+			tf.updated_at,
+			tf.uploaded_at
+		from track_files as tf
+		join claimed as c on c.track_file_id = tf.id;
+	`
+
+	arguments := []any{
+		request.StaleAfter,
+	}
+
+	return postgres.FetchOne[domain.TrackFile](ctx, r.db, claimLoudnessSQL, arguments...)
+}
+
+func (r *LoudnessRepository) SaveLoudness(ctx context.Context, request domain.SaveLoudnessRequest) error {
+	const saveLoudnessSQL = `
+		update track_file_loudness
+		set
+			integrated_loudness   = $2,
+			loudness_range        = $3,
+			true_peak             = $4,
+			replaygain_track_gain = $5,
+			replaygain_track_peak = $6,
+			histogram             = $7,
+			error                 = $8,
+			updated_at            = now()
+		where track_file_id = $1;
+	`
+
+	arguments := []any{
+		request.TrackFileID,
+		request.IntegratedLoudness,
+		request.LoudnessRange,
+		request.TruePeak,
+		request.ReplayGainTrackGain,
+		request.ReplayGainTrackPeak,
+		request.Histogram,
+		request.Error,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, saveLoudnessSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+// ListAlbumLoudnessSources возвращает измерения всех уже проанализированных треков всех альбомов, в которые входит
+// трек. Для каждого трека берётся самый ранний проанализированный файл — так же, как в карточке трека.
+func (r *LoudnessRepository) ListAlbumLoudnessSources(ctx context.Context, request domain.ListAlbumLoudnessSourcesRequest) (*domain.ListAlbumLoudnessSourcesResponse, error) {
+	const listAlbumLoudnessSourcesSQL = `
+		select
+			at.album_id,
+			at.track_id,
+			l.true_peak,
+			l.replaygain_track_peak,
+			l.histogram
+		from album_tracks as self
+		join album_tracks as at on at.album_id = self.album_id
+		join lateral (
+			select
+				fl.true_peak,
+				fl.replaygain_track_peak,
+				fl.histogram
+			from track_file_loudness as fl
+			join track_files as tf on tf.id = fl.track_file_id
+			where tf.track_id = at.track_id and fl.histogram is not null
+			order by tf.created_at
+			limit 1
+		) as l on true
+		where self.track_id = $1
+		order by at.album_id, at.disc_number, at.position;
+	`
+
+	arguments := []any{
+		request.TrackID,
+	}
+
+	sources, err := postgres.FetchMany[domain.AlbumLoudnessSource](ctx, r.db, listAlbumLoudnessSourcesSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListAlbumLoudnessSourcesResponse{Sources: sources}, nil
+}
+
+func (r *LoudnessRepository) UpdateAlbumLoudness(ctx context.Context, request domain.UpdateAlbumLoudnessRequest) error {
+	const updateAlbumLoudnessSQL = `
+		update albums
+		set
+			integrated_loudness   = $2,
+			loudness_range        = $3,
+			true_peak             = $4,
+			replaygain_album_gain = $5,
+			replaygain_album_peak = $6
+		where id = $1;
+	`
+
+	arguments := []any{
+		request.AlbumID,
+		request.IntegratedLoudness,
+		request.LoudnessRange,
+		request.TruePeak,
+		request.ReplayGainAlbumGain,
+		request.ReplayGainAlbumPeak,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, updateAlbumLoudnessSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
@@ -82,7 +82,9 @@ func (r *PlaylistsRepository) ListPlaylists(ctx context.Context, request domain.
 				coalesce(nullif(lower($3), ''), 'created_at') as sort_field,
 				coalesce(nullif(lower($4), ''), 'desc')       as sort_order,
 				greatest(coalesce($5, 50), 1)                 as limit_val,
-				greatest(coalesce($6, 0), 0)                  as offset_val
+				greatest(coalesce($6, 0), 0)                  as offset_val,
+				$7::uuid                                      as viewer_id,
+				coalesce($8::boolean, false)                  as viewer_is_admin
 		)
 		select
 			p.id, 
@@ -96,6 +98,7 @@ func (r *PlaylistsRepository) ListPlaylists(ctx context.Context, request domain.
 		where
 			(par.owner_filter is null or p.owner_id = par.owner_filter)
 			and (par.visibility_filter is null or p.visibility = par.visibility_filter)
+			and (par.viewer_is_admin or p.visibility = 'public'::visibility or p.owner_id = par.viewer_id)
 		order by
 			case when par.sort_field = 'title'      and par.sort_order = 'asc'  then p.title      end nulls last,
 			case when par.sort_field = 'title'      and par.sort_order = 'desc' then p.title      end desc nulls last,
@@ -118,6 +121,8 @@ func (r *PlaylistsRepository) ListPlaylists(ctx context.Context, request domain.
 		request.SortOrder,
 		request.Limit,
 		request.Offset,
+		request.ViewerID,
+		request.ViewerIsAdmin,
 	}
 
 	playlists, err := postgres.FetchMany[domain.Playlist](ctx, r.db, listPlaylistSQL, arguments...)
@@ -54,6 +54,24 @@ func (c *Client) Driver() Driver {
 	return c.driver
 }
 
+// InTransaction выполняет fn в транзакции: запросы через переданный Driver идут в неё. Если fn вернула ошибку,
+// транзакция откатывается, иначе фиксируется.
+func (c *Client) InTransaction(ctx context.Context, fn func(Driver) error) error {
+	tx, err := c.driver.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	// После успешного Commit откат ничего не делает.
+	defer tx.Rollback(context.WithoutCancel(ctx))
+
+	if err = fn(&PgxTx{tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
 func (c *Client) Query(ctx context.Context, sqlQuery string, arguments ...any) (pgx.Rows, error) {
 	return c.driver.Query(ctx, sqlQuery, arguments...)
 }
@@ -61,3 +79,7 @@ func (c *Client) Query(ctx context.Context, sqlQuery string, arguments ...any) (
 func (c *Client) Exec(ctx context.Context, sqlQuery string, arguments ...any) (pgconn.CommandTag, error) {
 	return c.driver.Exec(ctx, sqlQuery, arguments...)
 }
+
+func (c *Client) Begin(ctx context.Context) (pgx.Tx, error) {
+	return c.driver.Begin(ctx)
+}
@@ -5,12 +5,20 @@ import (
 	"errors"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 var (
 	ErrNotFound = errors.New("not found")
 )
 
+// IsUniqueViolation сообщает, что запрос нарушил ограничение уникальности (SQLSTATE 23505).
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 // FetchOne выполняет запрос и возвращает одну модель. Если строка не найдена - возвращает ErrNotFound.
 func FetchOne[Model any](ctx context.Context, driver Driver, sqlQuery string, arguments ...any) (*Model, error) {
 	rows, err := driver.Query(ctx, sqlQuery, arguments...)
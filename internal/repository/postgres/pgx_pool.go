@@ -25,3 +25,27 @@ func (d *PgxPool) Query(ctx context.Context, sql string, arguments ...any) (pgx.
 func (d *PgxPool) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
 	return d.pool.Exec(ctx, sql, arguments...)
 }
+
+func (d *PgxPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.pool.Begin(ctx)
+}
+
+// PgxTx Driver поверх открытой транзакции. Close ничего не делает: транзакцию завершает Client.InTransaction.
+type PgxTx struct {
+	tx pgx.Tx
+}
+
+func (d *PgxTx) Close() {}
+
+func (d *PgxTx) Query(ctx context.Context, sql string, arguments ...any) (pgx.Rows, error) {
+	return d.tx.Query(ctx, sql, arguments...)
+}
+
+func (d *PgxTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	return d.tx.Exec(ctx, sql, arguments...)
+}
+
+// Begin внутри транзакции открывает savepoint.
+func (d *PgxTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return d.tx.Begin(ctx)
+}
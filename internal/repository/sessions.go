@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type SessionsRepository struct {
+	db *postgres.Client
+}
+
+func NewSessionsRepository(db *postgres.Client) *SessionsRepository {
+	return &SessionsRepository{db: db}
+}
+
+func (r *SessionsRepository) CreateSession(ctx context.Context, request domain.CreateSessionRequest) (*domain.Session, error) {
+	const createSessionSQL = `
+		insert into sessions (user_id,
+		                      refresh_token_hash,
+		                      user_agent,
+		                      expires_at)
+		values ($1,
+		        $2,
+		        $3,
+		        now() + $4::interval)
+		returning
+			id,
+			user_id,
+			user_agent,
+			created_at,
+			refreshed_at,
+			expires_at,
+			revoked_at;
+	`
+
+	arguments := []any{
+		request.UserID,
+		request.RefreshTokenHash,
+		request.UserAgent,
+		request.TTL,
+	}
+
+	return postgres.FetchOne[domain.Session](ctx, r.db, createSessionSQL, arguments...)
+}
+
+// RotateSession меняет действующий refresh-токен действующей сессии на новый, запоминая старый как заменённый. Если
+// токен не действующий (заменён, сессия отозвана или истекла, или такого токена не было), возвращается
+// postgres.ErrNotFound. Из двух одновременных обменов одного токена успешен только один.
+func (r *SessionsRepository) RotateSession(ctx context.Context, request domain.RotateSessionRequest) (*domain.Session, error) {
+	const rotateSessionSQL = `
+		with rotated as (
+			update sessions
+			set
+				refresh_token_hash = $2,
+				refreshed_at       = now(),
+				expires_at         = now() + $3::interval
+			where refresh_token_hash = $1
+			  and revoked_at is null
+			  and expires_at > now()
+			returning
+				id,
+				user_id,
+				user_agent,
+				created_at,
+				refreshed_at,
+				expires_at,
+				revoked_at
+		), history as (
+			insert into session_rotated_tokens (token_hash, session_id)
+			select $1, id from rotated
+		)
+		select
+			id,
+			user_id,
+			user_agent,
+			created_at,
+			refreshed_at,
+			expires_at,
+			revoked_at
+		from rotated;
+	`
+
+	arguments := []any{
+		request.RefreshTokenHash,
+		request.NewRefreshTokenHash,
+		request.TTL,
+	}
+
+	return postgres.FetchOne[domain.Session](ctx, r.db, rotateSessionSQL, arguments...)
+}
+
+// RevokeSessionByRotatedToken отзывает сессию, которой принадлежал уже заменённый refresh-токен, и возвращает её
+// (в том числе если она уже была отозвана). Если токен никогда не заменялся, возвращается postgres.ErrNotFound.
+func (r *SessionsRepository) RevokeSessionByRotatedToken(ctx context.Context, request domain.RevokeSessionByRotatedTokenRequest) (*domain.Session, error) {
+	const revokeSessionByRotatedTokenSQL = `
+		update sessions as s
+		set revoked_at = coalesce(s.revoked_at, now())
+		from session_rotated_tokens as t
+		where t.token_hash = $1
+		  and s.id = t.session_id
+		returning
+			s.id,
+			s.user_id,
+			s.user_agent,
+			s.created_at,
+			s.refreshed_at,
+			s.expires_at,
+			s.revoked_at;
+	`
+
+	arguments := []any{
+		request.RefreshTokenHash,
+	}
+
+	return postgres.FetchOne[domain.Session](ctx, r.db, revokeSessionByRotatedTokenSQL, arguments...)
+}
+
+// GetPrincipal возвращает пользователя действующей сессии с его текущей ролью. Отозванная или истёкшая сессия —
+// postgres.ErrNotFound.
+func (r *SessionsRepository) GetPrincipal(ctx context.Context, request domain.GetPrincipalRequest) (*domain.Principal, error) {
+	const getPrincipalSQL = `
+		select
+			s.id as session_id,
+			s.user_id,
+			u.role
+		from sessions as s
+		join users as u on u.id = s.user_id
+		where s.id = $1
+		  and s.user_id = $2
+		  and s.revoked_at is null
+		  and s.expires_at > now();
+	`
+
+	arguments := []any{
+		request.SessionID,
+		request.UserID,
+	}
+
+	return postgres.FetchOne[domain.Principal](ctx, r.db, getPrincipalSQL, arguments...)
+}
+
+// ListSessions действующие сессии пользователя, последние использованные первыми.
+func (r *SessionsRepository) ListSessions(ctx context.Context, request domain.ListSessionsRequest) (*domain.ListSessionsResponse, error) {
+	const listSessionsSQL = `
+		select
+			id,
+			user_id,
+			user_agent,
+			created_at,
+			refreshed_at,
+			expires_at,
+			revoked_at
+		from sessions
+		where user_id = $1
+		  and revoked_at is null
+		  and expires_at > now()
+		order by coalesce(refreshed_at, created_at) desc;
+	`
+
+	arguments := []any{
+		request.UserID,
+	}
+
+	sessions, err := postgres.FetchMany[domain.Session](ctx, r.db, listSessionsSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListSessionsResponse{
+		Sessions: sessions,
+	}, nil
+}
+
+func (r *SessionsRepository) RevokeSession(ctx context.Context, request domain.RevokeSessionRequest) error {
+	const revokeSessionSQL = `
+		update sessions
+		set revoked_at = now()
+		where id = $1
+		  and user_id = $2
+		  and revoked_at is null;
+	`
+
+	arguments := []any{
+		request.ID,
+		request.UserID,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, revokeSessionSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
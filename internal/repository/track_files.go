@@ -16,6 +16,11 @@ func NewTrackFilesRepository(db *postgres.Client) *TrackFilesRepository {
 }
 
 func (r *TrackFilesRepository) CreateTrackFile(ctx context.Context, request domain.CreateTrackFileRequest) (*domain.CreateTrackFileResponse, error) {
+	return createTrackFile(ctx, r.db, request)
+}
+
+// createTrackFile вынесен из CreateTrackFile, чтобы его можно было выполнить в транзакции (см. LibraryRepository).
+func createTrackFile(ctx context.Context, driver postgres.Driver, request domain.CreateTrackFileRequest) (*domain.CreateTrackFileResponse, error) {
 	const createTrackFileSQL = `
 		insert into track_files (track_id, 
 		                         filename, 
@@ -29,7 +34,12 @@ func (r *TrackFilesRepository) CreateTrackFile(ctx context.Context, request doma
 		                         size, 
 		                         duration, 
 		                         checksum, 
-		                         uploaded_at) 
+		                         uploaded_at,
+		                         healthy,
+		                         integrity_error,
+		                         source_id,
+		                         start_sample,
+		                         end_sample) 
 		values ($1, 
 		        $2, 
 		        $3, 
@@ -42,7 +52,12 @@ func (r *TrackFilesRepository) CreateTrackFile(ctx context.Context, request doma
 		        $10, 
 		        $11,
 		        $12, 
-		        $13)
+		        $13,
+		        $14,
+		        $15,
+		        $16,
+		        $17,
+		        $18)
 		
 		returning id;
 	`
@@ -61,9 +76,14 @@ func (r *TrackFilesRepository) CreateTrackFile(ctx context.Context, request doma
 		request.Duration,
 		request.Checksum,
 		request.UploadedAt,
+		request.Healthy,
+		request.IntegrityError,
+		request.SourceID,
+		request.StartSample,
+		request.EndSample,
 	}
 
-	trackFile, err := postgres.FetchOne[domain.TrackFile](ctx, r.db, createTrackFileSQL, arguments...)
+	trackFile, err := postgres.FetchOne[domain.TrackFile](ctx, driver, createTrackFileSQL, arguments...)
 	if err != nil {
 
 		return nil, err
@@ -91,6 +111,11 @@ func (r *TrackFilesRepository) GetTrackFile(ctx context.Context, request domain.
 		    size, 
 		    duration, 
 		    checksum,
+		    healthy,
+		    integrity_error,
+		    source_id,
+		    start_sample,
+		    end_sample,
 		    created_at, 
 		    updated_at, 
 		    uploaded_at
@@ -99,6 +124,7 @@ func (r *TrackFilesRepository) GetTrackFile(ctx context.Context, request domain.
 	`
 
 	arguments := []any{
+		request.ID,
 		request.TrackID,
 	}
 
@@ -136,6 +162,11 @@ func (r *TrackFilesRepository) ListTrackFiles(ctx context.Context, request domai
 			tf.size, 
 			tf.duration, 
 			tf.checksum,
+			tf.healthy,
+			tf.integrity_error,
+			tf.source_id,
+			tf.start_sample,
+			tf.end_sample,
 			tf.created_at, 
 			tf.updated_at, 
 			tf.uploaded_at
@@ -203,16 +234,13 @@ func (r *TrackFilesRepository) UpdateTrackFile(ctx context.Context, request doma
 		update track_files
 		set
 			filename    = coalesce($3, filename),
-			s3_key      = coalesce($4, s3_key),
-			mime        = coalesce($5, mime),
-			format      = coalesce($6::format, format),
-			codec       = coalesce($7::codec, codec),
-			bitrate     = coalesce($8, bitrate),
-			sample_rate = coalesce($9, sample_rate),
-			channels    = coalesce($10, channels),
-			size        = coalesce($11, size),
-			duration    = coalesce($12, duration),
-			checksum    = coalesce($13, checksum),
+			mime        = coalesce($4, mime),
+			format      = coalesce($5::format, format),
+			codec       = coalesce($6::codec, codec),
+			bitrate     = coalesce($7, bitrate),
+			sample_rate = coalesce($8, sample_rate),
+			channels    = coalesce($9, channels),
+			duration    = coalesce($10, duration),
 			updated_at  = now()
 		where id = $1 and track_id = $2;
 	`
@@ -221,16 +249,13 @@ func (r *TrackFilesRepository) UpdateTrackFile(ctx context.Context, request doma
 		request.ID,
 		request.TrackID,
 		request.Filename,
-		request.S3Key,
 		request.Mime,
 		request.Format,
 		request.Codec,
 		request.Bitrate,
 		request.SampleRate,
 		request.Channels,
-		request.Size,
 		request.Duration,
-		request.Checksum,
 	}
 
 	_, err := postgres.FetchOne[domain.TrackFile](ctx, r.db, updateTrackFilesSQL, arguments...)
@@ -16,6 +16,11 @@ func NewTracksRepository(db *postgres.Client) *TracksRepository {
 }
 
 func (r *TracksRepository) CreateTrack(ctx context.Context, request domain.CreateTrackRequest) (*domain.CreateTrackResponse, error) {
+	return createTrack(ctx, r.db, request)
+}
+
+// createTrack вынесен из CreateTrack, чтобы его можно было выполнить в транзакции (см. LibraryRepository).
+func createTrack(ctx context.Context, driver postgres.Driver, request domain.CreateTrackRequest) (*domain.CreateTrackResponse, error) {
 	const createTracksQL = `
 		insert into tracks (uploader_id, 
 		                    title, 
@@ -44,7 +49,7 @@ func (r *TracksRepository) CreateTrack(ctx context.Context, request domain.Creat
 		request.UploadedAt,
 	}
 
-	track, err := postgres.FetchOne[domain.Track](ctx, r.db, createTracksQL, arguments...)
+	track, err := postgres.FetchOne[domain.Track](ctx, driver, createTracksQL, arguments...)
 	if err != nil {
 		return nil, err
 	}
@@ -57,18 +62,36 @@ func (r *TracksRepository) CreateTrack(ctx context.Context, request domain.Creat
 func (r *TracksRepository) GetTrack(ctx context.Context, request domain.GetTrackRequest) (*domain.GetTrackResponse, error) {
 	const getTracksQL = `
 		select 
-			id, 
-			uploader_id, 
-			title, 
-			subtitle, 
-			description, 
-			duration, 
-			visibility, 
-			created_at, 
-			updated_at, 
-			uploaded_at
-		from tracks
-		where id = $1;
+			t.id, 
+			t.uploader_id, 
+			t.title, 
+			t.subtitle, 
+			t.description, 
+			t.duration, 
+			t.visibility, 
+			t.created_at, 
+			t.updated_at, 
+			t.uploaded_at,
+			l.integrated_loudness,
+			l.loudness_range,
+			l.true_peak,
+			l.replaygain_track_gain,
+			l.replaygain_track_peak
+		from tracks as t
+		left join lateral (
+			select
+				fl.integrated_loudness,
+				fl.loudness_range,
+				fl.true_peak,
+				fl.replaygain_track_gain,
+				fl.replaygain_track_peak
+			from track_file_loudness as fl
+			join track_files as tf on tf.id = fl.track_file_id
+			where tf.track_id = t.id and fl.histogram is not null
+			order by tf.created_at
+			limit 1
+		) as l on true
+		where t.id = $1;
 	`
 
 	arguments := []any{
@@ -94,7 +117,9 @@ func (r *TracksRepository) ListTracks(ctx context.Context, request domain.ListTr
 				coalesce(nullif(lower($3), ''), 'created_at') as sort_field,
 				coalesce(nullif(lower($4), ''), 'desc')       as sort_order,
 				greatest(coalesce($5, 50), 1)                 as limit_val,
-				greatest(coalesce($6, 0), 0)                  as offset_val
+				greatest(coalesce($6, 0), 0)                  as offset_val,
+				$7::uuid                                      as viewer_id,
+				coalesce($8::boolean, false)                  as viewer_is_admin
 		)
 		select
 			t.id, 
@@ -106,11 +131,31 @@ func (r *TracksRepository) ListTracks(ctx context.Context, request domain.ListTr
 			t.visibility,
 			t.created_at, 
 			t.updated_at, 
-			t.uploaded_at
-		from tracks as t, params as p
+			t.uploaded_at,
+			l.integrated_loudness,
+			l.loudness_range,
+			l.true_peak,
+			l.replaygain_track_gain,
+			l.replaygain_track_peak
+		from tracks as t
+		cross join params as p
+		left join lateral (
+			select
+				fl.integrated_loudness,
+				fl.loudness_range,
+				fl.true_peak,
+				fl.replaygain_track_gain,
+				fl.replaygain_track_peak
+			from track_file_loudness as fl
+			join track_files as tf on tf.id = fl.track_file_id
+			where tf.track_id = t.id and fl.histogram is not null
+			order by tf.created_at
+			limit 1
+		) as l on true
 		where
 			(p.uploader_filter is null or t.uploader_id = p.uploader_filter)
 			and (p.visibility_filter is null or t.visibility = p.visibility_filter)
+			and (p.viewer_is_admin or t.visibility = 'public'::visibility or t.uploader_id = p.viewer_id)
 		order by
 			case when p.sort_field = 'title'       and p.sort_order = 'asc'  then t.title       end nulls last,
 			case when p.sort_field = 'title'       and p.sort_order = 'desc' then t.title       end desc nulls last,
@@ -136,6 +181,8 @@ func (r *TracksRepository) ListTracks(ctx context.Context, request domain.ListTr
 		request.SortOrder,
 		request.Limit,
 		request.Offset,
+		request.ViewerID,
+		request.ViewerIsAdmin,
 	}
 
 	tracks, err := postgres.FetchMany[domain.Track](ctx, r.db, getListTracksSQL, arguments...)
@@ -196,3 +243,40 @@ func (r *TracksRepository) DeleteTrack(ctx context.Context, request domain.Delet
 
 	return nil
 }
+
+func (r *TracksRepository) SetTrackGenres(ctx context.Context, request domain.SetTrackGenresRequest) error {
+	return setTrackGenres(ctx, r.db, request)
+}
+
+// setTrackGenres вынесен из SetTrackGenres по той же причине, что и createTrack.
+func setTrackGenres(ctx context.Context, driver postgres.Driver, request domain.SetTrackGenresRequest) error {
+	const setTrackGenresSQL = `
+		with names as (
+			select distinct name from unnest($2::text[]) as name
+		),
+		created as (
+			insert into genres (name)
+			select name from names
+			on conflict (name) do nothing
+			returning id
+		),
+		existing as (
+			select g.id from genres as g join names as n on n.name = g.name
+		)
+		insert into track_genres (track_id, genre_id)
+		select $1, id from (select id from created union select id from existing) as genre_ids
+		on conflict do nothing;
+	`
+
+	arguments := []any{
+		request.TrackID,
+		request.Genres,
+	}
+
+	_, err := postgres.ExecAffected(ctx, driver, setTrackGenresSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
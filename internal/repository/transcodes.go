@@ -0,0 +1,186 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type TranscodesRepository struct {
+	db *postgres.Client
+}
+
+func NewTranscodesRepository(db *postgres.Client) *TranscodesRepository {
+	return &TranscodesRepository{db: db}
+}
+
+// ClaimTranscode захватывает следующую копию из request.Renditions, которой ещё нет у трека: заводит для неё
+// задание в track_file_transcodes и возвращает оригинал вместе с тем, что из него получить. Оригиналом трека
+// считается самый ранний исправный FLAC/WAV среди загруженных файлов. Копия не заказывается, если у трека уже есть
+// файл того же формата, кодека и битрейта (для PCM — просто того же формата), в том числе загруженный.
+func (r *TranscodesRepository) ClaimTranscode(ctx context.Context, request domain.ClaimTranscodeRequest) (*domain.TranscodeTask, error) {
+	const claimTranscodeSQL = `
+		with renditions as (
+			select r.format::format as format, r.codec::codec as codec, r.bitrate
+			from unnest($2::text[], $3::text[], $4::integer[]) as r(format, codec, bitrate)
+		), masters as (
+			select distinct on (tf.track_id) tf.id, tf.track_id, tf.created_at
+			from track_files as tf
+			where tf.format in ('flac'::format, 'wav'::format)
+			  and tf.s3_key is not null
+			  and tf.healthy is not false
+			  and tf.source_id is null
+			order by tf.track_id, tf.created_at
+		), candidate as (
+			select m.id as source_id, r.format, r.codec, r.bitrate
+			from masters as m
+			cross join renditions as r
+			left join track_file_transcodes as j
+			       on j.source_id = m.id and j.format = r.format and j.codec = r.codec and j.bitrate = r.bitrate
+			where (j.source_id is null
+			       or (j.track_file_id is null and j.error is null and j.updated_at < now() - $1::interval))
+			  and not exists (
+				select 1
+				from track_files as o
+				where o.track_id = m.track_id
+				  and o.format = r.format
+				  and o.codec = r.codec
+				  and (r.bitrate = 0 or o.bitrate = r.bitrate)
+			  )
+			order by m.created_at, r.bitrate desc
+			limit 1
+		), claimed as (
+			insert into track_file_transcodes as j (source_id, format, codec, bitrate)
+			select source_id, format, codec, bitrate from candidate
+			on conflict (source_id, format, codec, bitrate) do update
+			set updated_at = now()
+			where j.track_file_id is null and j.error is null and j.updated_at < now() - $1::interval
+			returning j.source_id, j.format, j.codec, j.bitrate
+		)
+		select
+			tf.id,
+			tf.track_id,
+			tf.filename,
+			tf.s3_key,
+			tf.mime,
+			tf.format,
+			tf.codec,
+			tf.bitrate,
+			tf.sample_rate,
+			tf.channels,
+			tf.size,
+			tf.duration,
+			tf.checksum,
+			tf.healthy,
+			tf.integrity_error,
+			tf.source_id,
+			tf.start_sample,
+			tf.end_sample,
+			tf.created_at,
+			tf.updated_at,
+			tf.uploaded_at,
+			c.format  as target_format,
+			c.codec   as target_codec,
+			c.bitrate as target_bitrate
+		from track_files as tf
+		join claimed as c on c.source_id = tf.id;
+	`
+
+	formats := make([]string, 0, len(request.Renditions))
+	codecs := make([]string, 0, len(request.Renditions))
+	bitrates := make([]int, 0, len(request.Renditions))
+
+	for _, rendition := range request.Renditions {
+		formats = append(formats, string(rendition.Format))
+		codecs = append(codecs, string(rendition.Codec))
+		bitrates = append(bitrates, rendition.Bitrate)
+	}
+
+	arguments := []any{
+		request.StaleAfter,
+		formats,
+		codecs,
+		bitrates,
+	}
+
+	return postgres.FetchOne[domain.TranscodeTask](ctx, r.db, claimTranscodeSQL, arguments...)
+}
+
+// CompleteTranscode в одной транзакции регистрирует копию в track_files и закрывает задание ссылкой на неё.
+func (r *TranscodesRepository) CompleteTranscode(ctx context.Context, request domain.CompleteTranscodeRequest) (*domain.CreateTrackFileResponse, error) {
+	const completeTranscodeSQL = `
+		update track_file_transcodes
+		set
+			track_file_id = $5,
+			updated_at    = now()
+		where source_id = $1 and format = $2 and codec = $3 and bitrate = $4;
+	`
+
+	var response *domain.CreateTrackFileResponse
+
+	err := r.db.InTransaction(ctx, func(tx postgres.Driver) error {
+		trackFile := request.TrackFile
+		trackFile.SourceID = request.SourceID
+
+		var err error
+
+		response, err = createTrackFile(ctx, tx, trackFile)
+		if err != nil {
+			return err
+		}
+
+		arguments := []any{
+			request.SourceID,
+			request.Rendition.Format,
+			request.Rendition.Codec,
+			request.Rendition.Bitrate,
+			response.ID,
+		}
+
+		affected, err := postgres.ExecAffected(ctx, tx, completeTranscodeSQL, arguments...)
+		if err != nil {
+			return err
+		}
+
+		if affected == 0 {
+			return postgres.ErrNotFound
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+func (r *TranscodesRepository) FailTranscode(ctx context.Context, request domain.FailTranscodeRequest) error {
+	const failTranscodeSQL = `
+		update track_file_transcodes
+		set
+			error      = $5,
+			updated_at = now()
+		where source_id = $1 and format = $2 and codec = $3 and bitrate = $4;
+	`
+
+	arguments := []any{
+		request.SourceID,
+		request.Rendition.Format,
+		request.Rendition.Codec,
+		request.Rendition.Bitrate,
+		request.Error,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, failTranscodeSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type UploadsRepository struct {
+	db *postgres.Client
+}
+
+func NewUploadsRepository(db *postgres.Client) *UploadsRepository {
+	return &UploadsRepository{db: db}
+}
+
+func (r *UploadsRepository) CreateUpload(ctx context.Context, request domain.CreateUploadRequest) (*domain.CreateUploadResponse, error) {
+	const createUploadSQL = `
+		insert into uploads (owner_id,
+		                     filename,
+		                     s3_key,
+		                     mime,
+		                     size,
+		                     checksum)
+		values ($1,
+		        $2,
+		        $3,
+		        $4,
+		        $5,
+		        $6)
+		returning id;
+	`
+
+	arguments := []any{
+		request.OwnerID,
+		request.Filename,
+		request.S3Key,
+		request.Mime,
+		request.Size,
+		request.Checksum,
+	}
+
+	upload, err := postgres.FetchOne[domain.Upload](ctx, r.db, createUploadSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.CreateUploadResponse{
+		ID: upload.ID,
+	}, nil
+}
+
+func (r *UploadsRepository) GetUpload(ctx context.Context, request domain.GetUploadRequest) (*domain.GetUploadResponse, error) {
+	const getUploadSQL = `
+		select
+			id,
+			owner_id,
+			filename,
+			s3_key,
+			mime,
+			size,
+			checksum,
+			upload_offset,
+			status,
+			error,
+			track_id,
+			created_at,
+			updated_at
+		from uploads
+		where id = $1;
+	`
+
+	arguments := []any{
+		request.ID,
+	}
+
+	upload, err := postgres.FetchOne[domain.Upload](ctx, r.db, getUploadSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GetUploadResponse{
+		Upload: upload,
+	}, nil
+}
+
+func (r *UploadsRepository) ListUploads(ctx context.Context, request domain.ListUploadsRequest) (*domain.ListUploadsResponse, error) {
+	const listUploadsSQL = `
+		with params as (
+			select
+				$1::uuid                      as owner_filter,
+				$2::upload_status             as status_filter,
+				greatest(coalesce($3, 50), 1) as limit_val,
+				greatest(coalesce($4, 0), 0)  as offset_val
+		)
+		select
+			u.id,
+			u.owner_id,
+			u.filename,
+			u.s3_key,
+			u.mime,
+			u.size,
+			u.checksum,
+			u.upload_offset,
+			u.status,
+			u.error,
+			u.track_id,
+			u.created_at,
+			u.updated_at
+		from uploads as u, params as p
+		where
+			(p.owner_filter is null or u.owner_id = p.owner_filter)
+			and (p.status_filter is null or u.status = p.status_filter)
+		order by u.created_at desc
+		limit (select limit_val from params)
+		offset (select offset_val from params);
+	`
+
+	arguments := []any{
+		request.OwnerID,
+		request.Status,
+		request.Limit,
+		request.Offset,
+	}
+
+	uploads, err := postgres.FetchMany[domain.Upload](ctx, r.db, listUploadsSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.ListUploadsResponse{
+		Uploads: uploads,
+	}, nil
+}
+
+// CreateResumableUpload создаёт запись возобновляемой загрузки в статусе uploading. В очередь обработки она попадёт
+// только после получения всех байт.
+func (r *UploadsRepository) CreateResumableUpload(ctx context.Context, request domain.CreateResumableUploadRequest) (*domain.CreateUploadResponse, error) {
+	const createResumableUploadSQL = `
+		insert into uploads (owner_id,
+		                     filename,
+		                     mime,
+		                     size,
+		                     status)
+		values ($1,
+		        $2,
+		        $3,
+		        $4,
+		        'uploading'::upload_status)
+		returning id;
+	`
+
+	arguments := []any{
+		request.OwnerID,
+		request.Filename,
+		request.Mime,
+		request.Size,
+	}
+
+	upload, err := postgres.FetchOne[domain.Upload](ctx, r.db, createResumableUploadSQL, arguments...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.CreateUploadResponse{
+		ID: upload.ID,
+	}, nil
+}
+
+// AdvanceUploadOffset сдвигает смещение загрузки, только если оно всё ещё равно ожидаемому. Так две параллельные
+// PATCH-части с одинаковым Upload-Offset не могут обе быть засчитаны. При несовпадении возвращается
+// postgres.ErrNotFound.
+func (r *UploadsRepository) AdvanceUploadOffset(ctx context.Context, request domain.AdvanceUploadOffsetRequest) (*domain.Upload, error) {
+	const advanceUploadOffsetSQL = `
+		update uploads
+		set
+			upload_offset = upload_offset + $3,
+			updated_at    = now()
+		where id = $1
+		  and upload_offset = $2
+		  and status = 'uploading'::upload_status
+		  and upload_offset + $3 <= size
+		returning
+			id,
+			owner_id,
+			filename,
+			s3_key,
+			mime,
+			size,
+			checksum,
+			upload_offset,
+			status,
+			error,
+			track_id,
+			created_at,
+			updated_at;
+	`
+
+	arguments := []any{
+		request.ID,
+		request.Offset,
+		request.Delta,
+	}
+
+	return postgres.FetchOne[domain.Upload](ctx, r.db, advanceUploadOffsetSQL, arguments...)
+}
+
+// CompleteResumableUpload привязывает полностью полученную загрузку, которую обрабатывает воркер, к объекту с её
+// содержимым. Если загрузка уже не в статусе processing, возвращается postgres.ErrNotFound.
+func (r *UploadsRepository) CompleteResumableUpload(ctx context.Context, request domain.CompleteResumableUploadRequest) error {
+	const completeResumableUploadSQL = `
+		update uploads
+		set
+			s3_key     = $2,
+			checksum   = $3,
+			updated_at = now()
+		where id = $1
+		  and status = 'processing'::upload_status;
+	`
+
+	arguments := []any{
+		request.ID,
+		request.S3Key,
+		request.Checksum,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, completeResumableUploadSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *UploadsRepository) DeleteUpload(ctx context.Context, request domain.DeleteUploadRequest) error {
+	const deleteUploadSQL = `
+		delete from uploads where id = $1;
+	`
+
+	arguments := []any{
+		request.ID,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, deleteUploadSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+// ClaimUpload атомарно переводит самую старую ожидающую загрузку (или возобновляемую, полученную целиком) в статус
+// processing и возвращает её. Благодаря for update skip locked несколько воркеров никогда не получат одну и ту же
+// загрузку. Если очередь пуста, возвращается postgres.ErrNotFound.
+func (r *UploadsRepository) ClaimUpload(ctx context.Context, request domain.ClaimUploadRequest) (*domain.Upload, error) {
+	const claimUploadSQL = `
+		update uploads
+		set
+			status     = 'processing'::upload_status,
+			error      = null,
+			updated_at = now()
+		where id = (
+			select id
+			from uploads
+			where status = 'pending'::upload_status
+			   or (status = 'uploading'::upload_status and upload_offset = size)
+			   or (status = 'processing'::upload_status and updated_at < now() - $1::interval)
+			order by created_at
+			limit 1
+			for update skip locked
+		)
+		returning
+			id,
+			owner_id,
+			filename,
+			s3_key,
+			mime,
+			size,
+			checksum,
+			upload_offset,
+			status,
+			error,
+			track_id,
+			created_at,
+			updated_at;
+	`
+
+	arguments := []any{
+		request.StaleAfter,
+	}
+
+	return postgres.FetchOne[domain.Upload](ctx, r.db, claimUploadSQL, arguments...)
+}
+
+func (r *UploadsRepository) UpdateUploadStatus(ctx context.Context, request domain.UpdateUploadStatusRequest) error {
+	const updateUploadStatusSQL = `
+		update uploads
+		set
+			status     = $2::upload_status,
+			error      = $3,
+			track_id   = coalesce($4, track_id),
+			updated_at = now()
+		where id = $1;
+	`
+
+	arguments := []any{
+		request.ID,
+		request.Status,
+		request.Error,
+		request.TrackID,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, updateUploadStatusSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
@@ -50,7 +50,6 @@ func (r *UsersRepository) GetUser(ctx context.Context, request domain.GetUserReq
 		select 
 		    id, 
 		    email, 
-		    password_hash, 
 		    display_name, 
 		    role, 
 		    created_at 
@@ -72,6 +71,28 @@ func (r *UsersRepository) GetUser(ctx context.Context, request domain.GetUserReq
 	}, nil
 }
 
+// GetUserByEmail единственный запрос, возвращающий password_hash: он нужен только для проверки пароля при входе.
+func (r *UsersRepository) GetUserByEmail(ctx context.Context, request domain.GetUserByEmailRequest) (*domain.User, error) {
+	const getUserByEmailSQL = `
+		select
+			id,
+			email,
+			password_hash,
+			display_name,
+			role,
+			created_at,
+			updated_at
+		from users
+		where email = $1;
+	`
+
+	arguments := []any{
+		request.Email,
+	}
+
+	return postgres.FetchOne[domain.User](ctx, r.db, getUserByEmailSQL, arguments...)
+}
+
 func (r *UsersRepository) ListUsers(ctx context.Context, request domain.ListUsersRequest) (*domain.ListUsersResponse, error) {
 	const getListUsersSQL = `
 		with params as (
@@ -85,7 +106,6 @@ func (r *UsersRepository) ListUsers(ctx context.Context, request domain.ListUser
 		select
 			u.id, 
 			u.email, 
-			u.password_hash, 
 			u.display_name, 
 			u.role, 
 			u.created_at, 
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+)
+
+type WaveformsRepository struct {
+	db *postgres.Client
+}
+
+func NewWaveformsRepository(db *postgres.Client) *WaveformsRepository {
+	return &WaveformsRepository{db: db}
+}
+
+// ClaimWaveform захватывает самый старый файл трека, который умеем декодировать и у которого ещё нет обзора:
+// заводит для него пустую строку в track_file_waveforms и возвращает сам файл. Повреждённые файлы пропускаются.
+func (r *WaveformsRepository) ClaimWaveform(ctx context.Context, request domain.ClaimWaveformRequest) (*domain.TrackFile, error) {
+	const claimWaveformSQL = `
+		with candidate as (
+			select tf.id
+			from track_files as tf
+			left join track_file_waveforms as w on w.track_file_id = tf.id
+			where tf.format in ('flac'::format, 'wav'::format)
+			  and tf.s3_key is not null
+			  and tf.healthy is not false
+			  and (w.track_file_id is null
+			       or (w.peaks is null and w.error is null and w.updated_at < now() - $1::interval))
+			order by tf.created_at
+			limit 1
+			for update of tf skip locked
+		), claimed as (
+			insert into track_file_waveforms (track_file_id)
+			select id from candidate
+			on conflict (track_file_id) do update
+			set updated_at = now()
+			returning track_file_id
+		)
+		select
+			tf.id,
+			tf.track_id,
+			tf.filename,
+			tf.s3_key,
+			tf.mime,
+			tf.format,
+			tf.codec,
+			tf.bitrate,
+			tf.sample_rate,
+			tf.channels,
+			tf.size,
+			tf.duration,
+			tf.checksum,
+			tf.healthy,
+			tf.integrity_error,
+			tf.source_id,
+			tf.start_sample,
+			tf.end_sample,
+			tf.created_at,
+			tf.updated_at,
+			tf.uploaded_at
+		from track_files as tf
+		join claimed as c on c.track_file_id = tf.id;
+	`
+
+	arguments := []any{
+		request.StaleAfter,
+	}
+
+	return postgres.FetchOne[domain.TrackFile](ctx, r.db, claimWaveformSQL, arguments...)
+}
+
+func (r *WaveformsRepository) SaveWaveform(ctx context.Context, request domain.SaveWaveformRequest) error {
+	const saveWaveformSQL = `
+		update track_file_waveforms
+		set
+			peaks      = $2,
+			error      = $3,
+			updated_at = now()
+		where track_file_id = $1;
+	`
+
+	arguments := []any{
+		request.TrackFileID,
+		request.Peaks,
+		request.Error,
+	}
+
+	affected, err := postgres.ExecAffected(ctx, r.db, saveWaveformSQL, arguments...)
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return postgres.ErrNotFound
+	}
+
+	return nil
+}
+
+// GetTrackWaveform возвращает готовый обзор трека. Если готовы обзоры нескольких файлов, берётся обзор самого
+// раннего: форма волны у копий одного трека одна и та же.
+func (r *WaveformsRepository) GetTrackWaveform(ctx context.Context, request domain.GetWaveformRequest) (*domain.TrackFileWaveform, error) {
+	const getTrackWaveformSQL = `
+		select
+			w.track_file_id,
+			w.peaks,
+			w.error,
+			w.created_at,
+			w.updated_at
+		from track_file_waveforms as w
+		join track_files as tf on tf.id = w.track_file_id
+		where tf.track_id = $1 and w.peaks is not null
+		order by tf.created_at
+		limit 1;
+	`
+
+	arguments := []any{
+		request.TrackID,
+	}
+
+	return postgres.FetchOne[domain.TrackFileWaveform](ctx, r.db, getTrackWaveformSQL, arguments...)
+}
@@ -14,8 +14,16 @@ func NewAlbumsService(repository Albums) *AlbumsService {
 	return &AlbumsService{repository: repository}
 }
 
+// CreateAlbum создаёт альбом, владельцем которого становится вызывающий пользователь.
 func (s *AlbumsService) CreateAlbum(ctx context.Context, request domain.CreateAlbumRequest) (*domain.CreateAlbumResponse, error) {
-	err := request.Validate()
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.OwnerID = &principal.UserID
+
+	err = request.Validate()
 	if err != nil {
 		return nil, err
 	}
@@ -47,6 +55,10 @@ func (s *AlbumsService) UpdateAlbum(ctx context.Context, request domain.UpdateAl
 		return err
 	}
 
+	if err = requireAlbumOwner(ctx, s.repository, request.ID); err != nil {
+		return err
+	}
+
 	return s.repository.UpdateAlbum(ctx, request)
 }
 
@@ -56,5 +68,9 @@ func (s *AlbumsService) DeleteAlbum(ctx context.Context, request domain.DeleteAl
 		return err
 	}
 
+	if err = requireAlbumOwner(ctx, s.repository, request.ID); err != nil {
+		return err
+	}
+
 	return s.repository.DeleteAlbum(ctx, request)
 }
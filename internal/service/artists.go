@@ -20,6 +20,10 @@ func (s *ArtistsService) CreateArtist(ctx context.Context, request domain.Create
 		return nil, err
 	}
 
+	if _, err = principalFrom(ctx); err != nil {
+		return nil, err
+	}
+
 	return s.repository.CreateArtist(ctx, request)
 }
 
@@ -41,12 +45,17 @@ func (s *ArtistsService) ListArtists(ctx context.Context, request domain.ListArt
 	return s.repository.ListArtists(ctx, request)
 }
 
+// UpdateArtist исполнители общие для всего каталога, поэтому менять и удалять их может только администратор.
 func (s *ArtistsService) UpdateArtist(ctx context.Context, request domain.UpdateArtistRequest) error {
 	err := request.Validate()
 	if err != nil {
 		return err
 	}
 
+	if _, err = requireAdmin(ctx); err != nil {
+		return err
+	}
+
 	return s.repository.UpdateArtist(ctx, request)
 }
 
@@ -56,5 +65,9 @@ func (s *ArtistsService) DeleteArtist(ctx context.Context, request domain.Delete
 		return err
 	}
 
+	if _, err = requireAdmin(ctx); err != nil {
+		return err
+	}
+
 	return s.repository.DeleteArtist(ctx, request)
 }
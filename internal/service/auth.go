@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/auth"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// dummyPasswordHash с ним сверяется пароль, когда пользователя с таким email нет: иначе по времени ответа было бы
+// видно, какие email зарегистрированы. Параметры argon2id те же, что у настоящих хешей.
+const dummyPasswordHash = "$argon2id$v=19$m=65536,t=3,p=2$STRGFH/pm+VtN4HgyCYWIg$/45azL522Exupm3V0fX0+v0Cws4UfdcztblQjze7Sps"
+
+// Вход выдаёт пару токенов сессии. Access-токен — JWT, подписанный сервером, с идентификаторами пользователя и
+// сессии; он короткоживущий, но при каждом запросе сессия всё равно проверяется по БД, поэтому выход и отзыв
+// действуют сразу, а роль берётся актуальная. Refresh-токен непрозрачен, хранится только его хеш, и при каждом
+// обмене он заменяется новым. Предъявление уже заменённого токена значит, что его копия у кого-то ещё: сессия
+// отзывается целиком, и войти заново придётся обоим.
+
+type AuthService struct {
+	users      Users
+	sessions   Sessions
+	tokens     *auth.TokenSigner
+	refreshTTL time.Duration
+}
+
+func NewAuthService(users Users, sessions Sessions, tokens *auth.TokenSigner, refreshTTL time.Duration) *AuthService {
+	return &AuthService{
+		users:      users,
+		sessions:   sessions,
+		tokens:     tokens,
+		refreshTTL: refreshTTL,
+	}
+}
+
+// Register создаёт пользователя с ролью domain.RoleUser. Занятый email — domain.ErrEmailTaken.
+func (s *AuthService) Register(ctx context.Context, request domain.RegisterRequest) (*domain.RegisterResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := createUser(ctx, s.users, domain.CreateUserRequest{
+		Email:       request.Email,
+		Password:    request.Password,
+		DisplayName: request.DisplayName,
+		Role:        utils.Ptr(domain.RoleUser),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetUser(ctx, domain.GetUserRequest{ID: created.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.RegisterResponse{User: user.User}, nil
+}
+
+// Login проверяет email и пароль и открывает новую сессию. Неизвестный email и неверный пароль неразличимы: оба
+// дают domain.ErrInvalidCredentials.
+func (s *AuthService) Login(ctx context.Context, request domain.LoginRequest) (*domain.LoginResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.authenticate(ctx, *request.Email, *request.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.sessions.CreateSession(ctx, domain.CreateSessionRequest{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.Ptr(auth.HashRefreshToken(refreshToken)),
+		UserAgent:        utils.PtrIfNonZero(utils.ValueOrZero(request.UserAgent)),
+		TTL:              utils.Ptr(s.refreshTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(session, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResponse{User: user, Tokens: tokens}, nil
+}
+
+// Refresh меняет refresh-токен на новую пару токенов и продлевает сессию. Повторно предъявленный старый токен
+// отзывает сессию и даёт domain.ErrRefreshTokenReused.
+func (s *AuthService) Refresh(ctx context.Context, request domain.RefreshRequest) (*domain.RefreshResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	presented := auth.HashRefreshToken(*request.RefreshToken)
+
+	session, err := s.sessions.RotateSession(ctx, domain.RotateSessionRequest{
+		RefreshTokenHash:    utils.Ptr(presented),
+		NewRefreshTokenHash: utils.Ptr(auth.HashRefreshToken(refreshToken)),
+		TTL:                 utils.Ptr(s.refreshTTL),
+	})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return nil, s.rejectRefreshToken(ctx, presented)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issueTokens(session, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.RefreshResponse{Tokens: tokens}, nil
+}
+
+// Logout отзывает сессию, в которой выдан access-токен запроса.
+func (s *AuthService) Logout(ctx context.Context, _ domain.LogoutRequest) error {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeSession(ctx, domain.RevokeSessionRequest{
+		ID:     &principal.SessionID,
+		UserID: &principal.UserID,
+	})
+}
+
+// ListSessions действующие сессии вызывающего пользователя; текущая помечена Current.
+func (s *AuthService) ListSessions(ctx context.Context, request domain.ListSessionsRequest) (*domain.ListSessionsResponse, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.UserID = &principal.UserID
+
+	err = request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := s.sessions.ListSessions(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, session := range response.Sessions {
+		session.Current = utils.Ptr(*session.ID == principal.SessionID)
+	}
+
+	return response, nil
+}
+
+// RevokeSession отзывает одну из сессий вызывающего пользователя. Чужая сессия неотличима от несуществующей.
+func (s *AuthService) RevokeSession(ctx context.Context, request domain.RevokeSessionRequest) error {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return err
+	}
+
+	request.UserID = &principal.UserID
+
+	err = request.Validate()
+	if err != nil {
+		return err
+	}
+
+	return s.sessions.RevokeSession(ctx, request)
+}
+
+// Authenticate проверяет access-токен и возвращает principal его действующей сессии. Недействительный токен,
+// отозванная или истёкшая сессия — domain.ErrInvalidToken.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*domain.Principal, error) {
+	claims, err := s.tokens.Verify(token, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", domain.ErrInvalidToken, err)
+	}
+
+	principal, err := s.sessions.GetPrincipal(ctx, domain.GetPrincipalRequest{
+		SessionID: &claims.SessionID,
+		UserID:    &claims.UserID,
+	})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return nil, fmt.Errorf("%w: session is no longer active", domain.ErrInvalidToken)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return principal, nil
+}
+
+// rejectRefreshToken объясняет, почему токен не обменялся: если он уже заменялся, его сессия отзывается.
+func (s *AuthService) rejectRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := s.sessions.RevokeSessionByRotatedToken(ctx, domain.RevokeSessionByRotatedTokenRequest{
+		RefreshTokenHash: utils.Ptr(tokenHash),
+	})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return domain.ErrInvalidToken
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return domain.ErrRefreshTokenReused
+}
+
+func (s *AuthService) issueTokens(session *domain.Session, refreshToken string) (*domain.Tokens, error) {
+	accessToken, expiresAt, err := s.tokens.Sign(*session.UserID, *session.ID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("sign access token: %w", err)
+	}
+
+	return &domain.Tokens{
+		AccessToken:           utils.Ptr(accessToken),
+		AccessTokenExpiresAt:  utils.Ptr(expiresAt.UTC()),
+		RefreshToken:          utils.Ptr(refreshToken),
+		RefreshTokenExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// authenticate возвращает пользователя с этими email и паролем (без хеша пароля) или domain.ErrInvalidCredentials.
+func (s *AuthService) authenticate(ctx context.Context, email, password string) (*domain.User, error) {
+	user, err := s.users.GetUserByEmail(ctx, domain.GetUserByEmailRequest{Email: &email})
+	if err != nil && !errors.Is(err, postgres.ErrNotFound) {
+		return nil, err
+	}
+
+	hash := dummyPasswordHash
+	if user != nil {
+		hash = utils.ValueOrZero(user.PasswordHash)
+	}
+
+	// Хеш, записанный до того, как пароли стали хешироваться на сервере, не разбирается: войти с ним нельзя.
+	valid, err := auth.VerifyPassword(hash, password)
+	if user == nil || err != nil || !valid {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	user.PasswordHash = nil
+
+	return user, nil
+}
+
+// createUser хеширует пароль запроса и создаёт пользователя; общая часть UsersService.CreateUser и
+// AuthService.Register.
+func createUser(ctx context.Context, users Users, request domain.CreateUserRequest) (*domain.CreateUserResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := auth.HashPassword(*request.Password)
+	if err != nil {
+		return nil, fmt.Errorf("hash password: %w", err)
+	}
+
+	request.PasswordHash = utils.Ptr(hash)
+
+	response, err := users.CreateUser(ctx, request)
+	if postgres.IsUniqueViolation(err) {
+		return nil, domain.ErrEmailTaken
+	}
+
+	return response, err
+}
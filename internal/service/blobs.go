@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// Содержимое файлов хранится по SHA-256: одинаковые байты, сколько бы раз их ни загрузили, лежат в хранилище одним
+// объектом, на который ссылаются все track_files и uploads с ними. Число ссылок в blobs.ref_count ведут триггеры
+// БД, а объекты без ссылок через период ожидания удаляет фоновый ProcessNextUnreferencedBlob, поэтому сервисы сами
+// объекты не удаляют: достаточно удалить (или не создать) ссылку.
+
+type BlobsService struct {
+	repository Blobs
+	blobStore  storage.BlobStore
+}
+
+func NewBlobsService(repository Blobs, blobStore storage.BlobStore) *BlobsService {
+	return &BlobsService{
+		repository: repository,
+		blobStore:  blobStore,
+	}
+}
+
+// GetBlob сообщает, есть ли у вызывающего уже сохранённое содержимое с такой контрольной суммой. Если да, он может
+// создать загрузку или файл трека, передав только checksum. Чужое содержимое неотличимо от несуществующего: иначе
+// по контрольной сумме можно было бы узнать, что на сервере лежит конкретный файл.
+func (s *BlobsService) GetBlob(ctx context.Context, request domain.GetBlobRequest) (*domain.GetBlobResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	request.ReferencedBy, err = blobReferrer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := s.repository.GetBlob(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GetBlobResponse{Blob: blob}, nil
+}
+
+// ProcessNextUnreferencedBlob удаляет один объект, на который никто не ссылается дольше gracePeriod. Возвращает
+// false, если таких объектов нет.
+func (s *BlobsService) ProcessNextUnreferencedBlob(ctx context.Context, gracePeriod time.Duration) (bool, error) {
+	blob, err := s.repository.DeleteUnreferencedBlob(ctx, domain.DeleteUnreferencedBlobRequest{GracePeriod: &gracePeriod})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("delete unreferenced blob: %w", err)
+	}
+
+	// Запись уже удалена, и другого шанса удалить объект не будет.
+	if err = s.blobStore.Delete(context.WithoutCancel(ctx), *blob.S3Key); err != nil {
+		return true, fmt.Errorf("delete blob object %s: %w", *blob.S3Key, err)
+	}
+
+	return true, nil
+}
+
+// contentStore сохранение содержимого по контрольной сумме, общее для всех сервисов, которые пишут файлы.
+type contentStore struct {
+	repository Blobs
+	blobStore  storage.BlobStore
+}
+
+// store сохраняет body и возвращает объект с его содержимым; если такое содержимое уже есть, повторно оно не
+// записывается. Без body содержимое ищется по checksum среди того, на что вызывающий уже ссылается (см.
+// blobReferrer): если его нет, возвращается domain.ErrNotFound, и клиенту придётся передать файл. Переданный body
+// всегда хешируется, а при несовпадении с checksum возвращается domain.ErrChecksumMismatch.
+func (s contentStore) store(ctx context.Context, body io.Reader, mime string, checksum *string) (*domain.Blob, error) {
+	if body == nil {
+		if checksum == nil {
+			return nil, errors.New("no content and no checksum to look it up by")
+		}
+
+		referrer, err := blobReferrer(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		blob, err := s.repository.TouchBlob(ctx, domain.GetBlobRequest{Checksum: checksum, ReferencedBy: referrer})
+		if errors.Is(err, postgres.ErrNotFound) {
+			return nil, fmt.Errorf("content %s: %w", *checksum, domain.ErrNotFound)
+		}
+
+		return blob, err
+	}
+
+	content, size, sum, err := spoolContent(body)
+	if content != nil {
+		defer func() {
+			_ = content.Close()
+			_ = os.Remove(content.Name())
+		}()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != nil && *checksum != sum {
+		return nil, fmt.Errorf("%w: expected %s, got %s", domain.ErrChecksumMismatch, *checksum, sum)
+	}
+
+	blob, err := s.repository.AcquireBlob(ctx, domain.AcquireBlobRequest{
+		Checksum: utils.Ptr(sum),
+		S3Key:    utils.Ptr(blobKey(sum)),
+		Size:     utils.Ptr(size),
+		Mime:     utils.Ptr(mime),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if blob.StoredAt != nil {
+		return blob, nil
+	}
+
+	// Объект ещё не записан: его пишет параллельный запрос или предыдущая запись не удалась. Одинаковые байты под
+	// одним ключом можно записать сколько угодно раз.
+	if _, err = s.blobStore.Put(ctx, *blob.S3Key, content, size, mime); err != nil {
+		return nil, fmt.Errorf("store content: %w", err)
+	}
+
+	if err = s.repository.MarkBlobStored(ctx, domain.MarkBlobStoredRequest{S3Key: blob.S3Key}); err != nil {
+		return nil, err
+	}
+
+	blob.StoredAt = utils.Ptr(time.Now().UTC())
+
+	return blob, nil
+}
+
+// blobReferrer чьими ссылками ограничен поиск содержимого по контрольной сумме: знать SHA-256 недостаточно, чтобы
+// получить файл, иначе его можно было бы прикрепить к своему треку и прослушать. Пользователь находит только
+// содержимое своих файлов треков и загрузок, администратор — любое (nil).
+func blobReferrer(ctx context.Context) (*uuid.UUID, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal.IsAdmin() {
+		return nil, nil
+	}
+
+	return &principal.UserID, nil
+}
+
+// blobKey ключ объекта вида blobs/<2 hex>/<sha256>/<uuid>. Случайный хвост отличает объект, записанный после
+// сборки прежнего объекта с тем же содержимым, от прежнего: удаление старого не заденет новый.
+func blobKey(checksum string) string {
+	return path.Join("blobs", checksum[:2], checksum, uuid.NewString())
+}
+
+// spoolContent сохраняет r во временный файл, попутно считая SHA-256: ключ объекта зависит от содержимого, поэтому
+// в хранилище оно пишется только после того, как прочитано целиком.
+func spoolContent(r io.Reader) (*os.File, int64, string, error) {
+	tmp, err := os.CreateTemp("", "blob-*")
+	if err != nil {
+		return nil, 0, "", fmt.Errorf("create temp file: %w", err)
+	}
+
+	hash := sha256.New()
+
+	size, err := io.Copy(io.MultiWriter(tmp, hash), r)
+	if err != nil {
+		return tmp, 0, "", fmt.Errorf("read content: %w", err)
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return tmp, 0, "", fmt.Errorf("rewind temp file: %w", err)
+	}
+
+	return tmp, size, hex.EncodeToString(hash.Sum(nil)), nil
+}
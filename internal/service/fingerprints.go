@@ -0,0 +1,249 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/auth"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type FingerprintsService struct {
+	repository Fingerprints
+	tracks     Tracks
+	blobStore  storage.BlobStore
+}
+
+func NewFingerprintsService(repository Fingerprints, tracks Tracks, blobStore storage.BlobStore) *FingerprintsService {
+	return &FingerprintsService{
+		repository: repository,
+		tracks:     tracks,
+		blobStore:  blobStore,
+	}
+}
+
+// ProcessNextFingerprint считает отпечаток следующего исходного файла без него. Возвращает false, если таких файлов
+// нет. Ошибка расчёта сохраняется в track_file_fingerprints.error, и файл больше не берётся; если же расчёт прерван
+// отменой ctx, файл будет взят повторно через staleAfter.
+func (s *FingerprintsService) ProcessNextFingerprint(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	trackFile, err := s.repository.ClaimFingerprint(ctx, domain.ClaimFingerprintRequest{StaleAfter: &staleAfter})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("claim fingerprint: %w", err)
+	}
+
+	fingerprint, computeErr := s.computeFingerprint(ctx, trackFile)
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+
+	request := domain.SaveFingerprintRequest{TrackFileID: trackFile.ID}
+
+	if computeErr == nil {
+		request.Fingerprint, computeErr = fingerprint.MarshalBinary()
+	}
+
+	if computeErr != nil {
+		request.Fingerprint = nil
+		request.Error = utils.Ptr(computeErr.Error())
+	} else {
+		request.LookupKeys = fingerprint.LookupKeys()
+	}
+
+	err = s.repository.SaveFingerprint(context.WithoutCancel(ctx), request)
+	if err != nil {
+		return true, fmt.Errorf("save fingerprint of track file %s: %w", trackFile.ID, err)
+	}
+
+	return true, nil
+}
+
+// computeFingerprint декодирует начало файла прямо из хранилища и считает его отпечаток.
+func (s *FingerprintsService) computeFingerprint(ctx context.Context, trackFile *domain.TrackFile) (*audio.Fingerprint, error) {
+	if trackFile.S3Key == nil || trackFile.Format == nil {
+		return nil, errors.New("track file has no stored object")
+	}
+
+	body, err := s.blobStore.Get(ctx, *trackFile.S3Key, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open track file object: %w", err)
+	}
+
+	defer body.Close()
+
+	decoder, err := decodeTrackFile(body, trackFile)
+	if err != nil {
+		return nil, err
+	}
+
+	return audio.ComputeFingerprint(decoder)
+}
+
+// ListDuplicates находит треки, звучащие так же, как трек: сравнивает отпечатки его файлов с отпечатками файлов
+// других треков близкой длительности, у которых есть общие с ними ключи поиска. Сходство дубликата — лучшее
+// сходство среди пар их файлов. Если отпечаток трека ещё не посчитан (или его нельзя посчитать), возвращается
+// domain.ErrNotFound. Дубликаты — это список, поэтому в нём только треки, которые вызывающий увидел бы в ListTracks.
+func (s *FingerprintsService) ListDuplicates(ctx context.Context, request domain.ListDuplicatesRequest) (*domain.ListDuplicatesResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = viewTrack(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, err
+	}
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+
+	own, err := s.repository.ListFingerprints(ctx, domain.ListFingerprintsRequest{TrackID: request.TrackID})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(own.Fingerprints) == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	sources := make([]*audio.Fingerprint, 0, len(own.Fingerprints))
+	lookupKeys := make([]int32, 0)
+	minDuration, maxDuration := time.Duration(math.MaxInt64), time.Duration(0)
+
+	for _, stored := range own.Fingerprints {
+		fingerprint, err := unmarshalFingerprint(stored)
+		if err != nil {
+			return nil, err
+		}
+
+		sources = append(sources, fingerprint)
+		lookupKeys = append(lookupKeys, fingerprint.LookupKeys()...)
+
+		minDuration = min(minDuration, utils.ValueOrZero(stored.Duration))
+		maxDuration = max(maxDuration, utils.ValueOrZero(stored.Duration))
+	}
+
+	// Кандидаты отбираются в базе по длительности и общим ключам поиска, так что сравниваются не все отпечатки
+	// близкой длительности, а только те, у которых совпадает хотя бы один субфингерпринт.
+	candidates, err := s.repository.ListFingerprints(ctx, domain.ListFingerprintsRequest{
+		ExcludeTrackID: request.TrackID,
+		MinDuration:    utils.Ptr(minDuration - domain.DuplicateDurationTolerance),
+		MaxDuration:    utils.Ptr(maxDuration + domain.DuplicateDurationTolerance),
+		LookupKeys:     lookupKeys,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := domain.DuplicateDefaultThreshold
+	if request.Threshold != nil {
+		threshold = *request.Threshold
+	}
+
+	similarities := make(map[uuid.UUID]float64)
+
+	for _, stored := range candidates.Fingerprints {
+		candidate, err := unmarshalFingerprint(stored)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, source := range sources {
+			if similarity := source.Similarity(candidate); similarity >= threshold {
+				similarities[*stored.TrackID] = max(similarities[*stored.TrackID], similarity)
+			}
+		}
+	}
+
+	trackIDs := make([]uuid.UUID, 0, len(similarities))
+	for trackID := range similarities {
+		trackIDs = append(trackIDs, trackID)
+	}
+
+	slices.SortFunc(trackIDs, func(a, b uuid.UUID) int {
+		return cmp.Or(cmp.Compare(similarities[b], similarities[a]), cmp.Compare(a.String(), b.String()))
+	})
+
+	limit := domain.DuplicateDefaultLimit
+	if request.Limit != nil {
+		limit = *request.Limit
+	}
+
+	response := &domain.ListDuplicatesResponse{Duplicates: []*domain.Duplicate{}}
+
+	for _, trackID := range trackIDs {
+		if len(response.Duplicates) == limit {
+			break
+		}
+
+		track, err := s.tracks.GetTrack(ctx, domain.GetTrackRequest{ID: utils.Ptr(trackID)})
+		if errors.Is(err, postgres.ErrNotFound) {
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !canList(principal, track.Track.UploaderID, track.Track.Visibility) {
+			continue
+		}
+
+		response.Duplicates = append(response.Duplicates, &domain.Duplicate{
+			Track:      track.Track,
+			Similarity: similarities[trackID],
+		})
+	}
+
+	return response, nil
+}
+
+// MergeTracks сливает дубликаты в трек (см. FingerprintsRepository.MergeTracks); это доступно только
+// администратору. Объекты файлов дубликатов, на которые больше ничего не ссылается, удалит
+// BlobsService.ProcessNextUnreferencedBlob.
+func (s *FingerprintsService) MergeTracks(ctx context.Context, request domain.MergeTracksRequest) (*domain.MergeTracksResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
+	// Повторы убираются: иначе число найденных треков не сойдётся с числом запрошенных.
+	seen := make(map[uuid.UUID]bool, len(request.DuplicateIDs))
+	duplicateIDs := make([]uuid.UUID, 0, len(request.DuplicateIDs))
+
+	for _, id := range request.DuplicateIDs {
+		if !seen[id] {
+			seen[id] = true
+			duplicateIDs = append(duplicateIDs, id)
+		}
+	}
+
+	request.DuplicateIDs = duplicateIDs
+
+	return s.repository.MergeTracks(ctx, request)
+}
+
+func unmarshalFingerprint(stored *domain.TrackFileFingerprint) (*audio.Fingerprint, error) {
+	var fingerprint audio.Fingerprint
+	if err := fingerprint.UnmarshalBinary(stored.Fingerprint); err != nil {
+		return nil, fmt.Errorf("decode fingerprint of track file %s: %w", stored.TrackFileID, err)
+	}
+
+	return &fingerprint, nil
+}
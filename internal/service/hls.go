@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/hls"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type HLSService struct {
+	repository HLS
+	tracks     Tracks
+	blobStore  storage.BlobStore
+}
+
+func NewHLSService(repository HLS, tracks Tracks, blobStore storage.BlobStore) *HLSService {
+	return &HLSService{
+		repository: repository,
+		tracks:     tracks,
+		blobStore:  blobStore,
+	}
+}
+
+// ProcessNextHLSIndex разбивает на сегменты следующий сжатый файл трека. Возвращает false, если таких файлов нет.
+// Ошибка разбиения сохраняется в track_file_hls_indexes.error, и файл больше не берётся; если же разбиение
+// прервано отменой ctx, файл будет взят повторно через staleAfter.
+func (s *HLSService) ProcessNextHLSIndex(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	trackFile, err := s.repository.ClaimHLSIndex(ctx, domain.ClaimHLSIndexRequest{StaleAfter: &staleAfter})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("claim hls index: %w", err)
+	}
+
+	segments, indexErr := s.buildIndex(ctx, trackFile)
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+
+	request := domain.SaveHLSIndexRequest{
+		TrackFileID: trackFile.ID,
+		Segments:    segments,
+	}
+
+	if indexErr != nil {
+		request.Error = utils.Ptr(indexErr.Error())
+	}
+
+	err = s.repository.SaveHLSIndex(context.WithoutCancel(ctx), request)
+	if err != nil {
+		return true, fmt.Errorf("save hls index of track file %s: %w", trackFile.ID, err)
+	}
+
+	return true, nil
+}
+
+// buildIndex читает файл прямо из хранилища и возвращает разбиение в двоичном виде.
+func (s *HLSService) buildIndex(ctx context.Context, trackFile *domain.TrackFile) ([]byte, error) {
+	if trackFile.S3Key == nil || trackFile.Codec == nil {
+		return nil, errors.New("track file has no stored object")
+	}
+
+	body, err := s.blobStore.Get(ctx, *trackFile.S3Key, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open track file object: %w", err)
+	}
+
+	defer body.Close()
+
+	index, err := hls.BuildIndex(body, *trackFile.Codec)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(index.Segments) == 0 {
+		return nil, errors.New("track file has no audio frames")
+	}
+
+	return index.MarshalBinary()
+}
+
+// GetMasterPlaylist мастер-плейлист со всеми разбитыми на сегменты копиями трека.
+func (s *HLSService) GetMasterPlaylist(ctx context.Context, request domain.GetHLSMasterPlaylistRequest) (*domain.GetHLSResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.listVariants(ctx, request.TrackID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	playlist := make([]hls.Variant, 0, len(variants))
+	for _, variant := range variants {
+		playlist = append(playlist, hls.Variant{
+			Index: variant.index,
+			URI:   variant.TrackFileID.String() + "/playlist.m3u8",
+		})
+	}
+
+	var body bytes.Buffer
+	if err = hls.WriteMasterPlaylist(&body, playlist); err != nil {
+		return nil, err
+	}
+
+	return &domain.GetHLSResponse{ContentType: hls.ContentTypePlaylist, Body: body.Bytes()}, nil
+}
+
+// GetMediaPlaylist медиа-плейлист одной копии; сегменты адресуются относительно него.
+func (s *HLSService) GetMediaPlaylist(ctx context.Context, request domain.GetHLSMediaPlaylistRequest) (*domain.GetHLSResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.listVariants(ctx, request.TrackID, request.TrackFileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+
+	err = hls.WriteMediaPlaylist(&body, variants[0].index, func(n int) string {
+		return strconv.Itoa(n) + ".ts"
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GetHLSResponse{ContentType: hls.ContentTypePlaylist, Body: body.Bytes()}, nil
+}
+
+// GetSegment собирает сегмент MPEG-TS из его диапазона байтов в исходном файле.
+func (s *HLSService) GetSegment(ctx context.Context, request domain.GetHLSSegmentRequest) (*domain.GetHLSResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	variants, err := s.listVariants(ctx, request.TrackID, request.TrackFileID)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := variants[0]
+	if *request.Segment >= len(variant.index.Segments) {
+		return nil, fmt.Errorf("segment %d of track file %s: %w", *request.Segment, variant.TrackFileID, domain.ErrNotFound)
+	}
+
+	segment := variant.index.Segments[*request.Segment]
+
+	source, err := s.blobStore.Get(ctx, *variant.S3Key, segment.Offset, segment.Size)
+	if err != nil {
+		return nil, fmt.Errorf("open track file object: %w", err)
+	}
+
+	defer source.Close()
+
+	var body bytes.Buffer
+	if err = hls.WriteSegment(&body, source, variant.index, segment); err != nil {
+		return nil, fmt.Errorf("segment %d of track file %s: %w", *request.Segment, variant.TrackFileID, err)
+	}
+
+	return &domain.GetHLSResponse{ContentType: hls.ContentTypeSegment, Body: body.Bytes()}, nil
+}
+
+type hlsVariant struct {
+	*domain.HLSVariant
+	index *hls.Index
+}
+
+// listVariants возвращает готовые копии трека с разобранными индексами, если вызывающему виден сам трек.
+func (s *HLSService) listVariants(ctx context.Context, trackID, trackFileID *uuid.UUID) ([]hlsVariant, error) {
+	if _, err := viewTrack(ctx, s.tracks, trackID); err != nil {
+		return nil, err
+	}
+
+	response, err := s.repository.ListHLSVariants(ctx, domain.ListHLSVariantsRequest{
+		TrackID:     trackID,
+		TrackFileID: trackFileID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Variants) == 0 {
+		return nil, fmt.Errorf("track %s has no hls renditions: %w", trackID, domain.ErrNotFound)
+	}
+
+	variants := make([]hlsVariant, 0, len(response.Variants))
+
+	for _, variant := range response.Variants {
+		index := &hls.Index{}
+		if err = index.UnmarshalBinary(variant.Segments); err != nil {
+			return nil, fmt.Errorf("decode hls index of track file %s: %w", variant.TrackFileID, err)
+		}
+
+		variants = append(variants, hlsVariant{HLSVariant: variant, index: index})
+	}
+
+	return variants, nil
+}
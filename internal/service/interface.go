@@ -9,11 +9,21 @@ import (
 type Users interface {
 	CreateUser(context.Context, domain.CreateUserRequest) (*domain.CreateUserResponse, error)
 	GetUser(context.Context, domain.GetUserRequest) (*domain.GetUserResponse, error)
+	GetUserByEmail(context.Context, domain.GetUserByEmailRequest) (*domain.User, error)
 	ListUsers(context.Context, domain.ListUsersRequest) (*domain.ListUsersResponse, error)
 	UpdateUser(context.Context, domain.UpdateUserRequest) error
 	DeleteUser(context.Context, domain.DeleteUserRequest) error
 }
 
+type Sessions interface {
+	CreateSession(context.Context, domain.CreateSessionRequest) (*domain.Session, error)
+	RotateSession(context.Context, domain.RotateSessionRequest) (*domain.Session, error)
+	RevokeSessionByRotatedToken(context.Context, domain.RevokeSessionByRotatedTokenRequest) (*domain.Session, error)
+	GetPrincipal(context.Context, domain.GetPrincipalRequest) (*domain.Principal, error)
+	ListSessions(context.Context, domain.ListSessionsRequest) (*domain.ListSessionsResponse, error)
+	RevokeSession(context.Context, domain.RevokeSessionRequest) error
+}
+
 type Albums interface {
 	CreateAlbum(context.Context, domain.CreateAlbumRequest) (*domain.CreateAlbumResponse, error)
 	GetAlbum(context.Context, domain.GetAlbumRequest) (*domain.GetAlbumResponse, error)
@@ -28,6 +38,7 @@ type Tracks interface {
 	ListTracks(context.Context, domain.ListTracksRequest) (*domain.ListTracksResponse, error)
 	UpdateTrack(context.Context, domain.UpdateTrackRequest) error
 	DeleteTrack(context.Context, domain.DeleteTrackRequest) error
+	SetTrackGenres(context.Context, domain.SetTrackGenresRequest) error
 }
 
 type Playlists interface {
@@ -53,3 +64,61 @@ type TrackFiles interface {
 	UpdateTrackFile(context.Context, domain.UpdateTrackFileRequest) error
 	DeleteTrackFile(context.Context, domain.DeleteTrackFileRequest) error
 }
+
+type Uploads interface {
+	CreateUpload(context.Context, domain.CreateUploadRequest) (*domain.CreateUploadResponse, error)
+	GetUpload(context.Context, domain.GetUploadRequest) (*domain.GetUploadResponse, error)
+	ListUploads(context.Context, domain.ListUploadsRequest) (*domain.ListUploadsResponse, error)
+	CreateResumableUpload(context.Context, domain.CreateResumableUploadRequest) (*domain.CreateUploadResponse, error)
+	AdvanceUploadOffset(context.Context, domain.AdvanceUploadOffsetRequest) (*domain.Upload, error)
+	CompleteResumableUpload(context.Context, domain.CompleteResumableUploadRequest) error
+	DeleteUpload(context.Context, domain.DeleteUploadRequest) error
+	ClaimUpload(context.Context, domain.ClaimUploadRequest) (*domain.Upload, error)
+	UpdateUploadStatus(context.Context, domain.UpdateUploadStatusRequest) error
+}
+
+type Library interface {
+	ImportTrack(context.Context, domain.ImportTrackRequest) (*domain.ImportTrackResponse, error)
+	ImportTracks(context.Context, domain.ImportTracksRequest) (*domain.ImportTracksResponse, error)
+	FindTrackFileByChecksum(context.Context, domain.FindTrackFileByChecksumRequest) (*domain.FindTrackFileByChecksumResponse, error)
+}
+
+type Waveforms interface {
+	ClaimWaveform(context.Context, domain.ClaimWaveformRequest) (*domain.TrackFile, error)
+	SaveWaveform(context.Context, domain.SaveWaveformRequest) error
+	GetTrackWaveform(context.Context, domain.GetWaveformRequest) (*domain.TrackFileWaveform, error)
+}
+
+type Loudness interface {
+	ClaimLoudness(context.Context, domain.ClaimLoudnessRequest) (*domain.TrackFile, error)
+	SaveLoudness(context.Context, domain.SaveLoudnessRequest) error
+	ListAlbumLoudnessSources(context.Context, domain.ListAlbumLoudnessSourcesRequest) (*domain.ListAlbumLoudnessSourcesResponse, error)
+	UpdateAlbumLoudness(context.Context, domain.UpdateAlbumLoudnessRequest) error
+}
+
+type Transcodes interface {
+	ClaimTranscode(context.Context, domain.ClaimTranscodeRequest) (*domain.TranscodeTask, error)
+	CompleteTranscode(context.Context, domain.CompleteTranscodeRequest) (*domain.CreateTrackFileResponse, error)
+	FailTranscode(context.Context, domain.FailTranscodeRequest) error
+}
+
+type HLS interface {
+	ClaimHLSIndex(context.Context, domain.ClaimHLSIndexRequest) (*domain.TrackFile, error)
+	SaveHLSIndex(context.Context, domain.SaveHLSIndexRequest) error
+	ListHLSVariants(context.Context, domain.ListHLSVariantsRequest) (*domain.ListHLSVariantsResponse, error)
+}
+
+type Fingerprints interface {
+	ClaimFingerprint(context.Context, domain.ClaimFingerprintRequest) (*domain.TrackFile, error)
+	SaveFingerprint(context.Context, domain.SaveFingerprintRequest) error
+	ListFingerprints(context.Context, domain.ListFingerprintsRequest) (*domain.ListFingerprintsResponse, error)
+	MergeTracks(context.Context, domain.MergeTracksRequest) (*domain.MergeTracksResponse, error)
+}
+
+type Blobs interface {
+	AcquireBlob(context.Context, domain.AcquireBlobRequest) (*domain.Blob, error)
+	TouchBlob(context.Context, domain.GetBlobRequest) (*domain.Blob, error)
+	GetBlob(context.Context, domain.GetBlobRequest) (*domain.Blob, error)
+	MarkBlobStored(context.Context, domain.MarkBlobStoredRequest) error
+	DeleteUnreferencedBlob(context.Context, domain.DeleteUnreferencedBlobRequest) (*domain.Blob, error)
+}
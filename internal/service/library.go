@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// unknownReleaseDate дата выпуска альбома, у которого в тегах нет даты: release_date в albums обязателен.
+var unknownReleaseDate = time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type LibraryService struct {
+	repository Library
+	content    contentStore
+}
+
+func NewLibraryService(repository Library, blobs Blobs, blobStore storage.BlobStore) *LibraryService {
+	return &LibraryService{
+		repository: repository,
+		content:    contentStore{repository: blobs, blobStore: blobStore},
+	}
+}
+
+// IsImported проверяет, сохранён ли уже файл с такой контрольной суммой.
+func (s *LibraryService) IsImported(ctx context.Context, request domain.FindTrackFileByChecksumRequest) (bool, error) {
+	err := request.Validate()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.repository.FindTrackFileByChecksum(ctx, request)
+
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, postgres.ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// ImportTrack сохраняет Body в хранилище (сверяя его с Checksum) и создаёт трек со всеми связями. Файл, контрольная сумма которого уже
+// есть в track_files, повторно не импортируется: возвращается domain.ErrAlreadyImported.
+func (s *LibraryService) ImportTrack(ctx context.Context, request domain.ImportTrackRequest) (*domain.ImportTrackResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := s.IsImported(ctx, domain.FindTrackFileByChecksumRequest{Checksum: request.Checksum})
+	if err != nil {
+		return nil, err
+	}
+
+	if imported {
+		return nil, domain.ErrAlreadyImported
+	}
+
+	blob, err := s.content.store(ctx, request.Body, *request.Mime, request.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("store track file: %w", err)
+	}
+
+	request.S3Key = blob.S3Key
+	request.Size = blob.Size
+	request.UploadedAt = utils.Ptr(time.Now().UTC())
+
+	return s.repository.ImportTrack(ctx, request)
+}
+
+// ImportCueSheet сохраняет образ диска в хранилище один раз и в одной транзакции создаёт по треку на каждый трек
+// разметки, все со ссылкой на общий объект. Как и в ImportTrack, повторный импорт образа с той же контрольной
+// суммой возвращает domain.ErrAlreadyImported.
+func (s *LibraryService) ImportCueSheet(ctx context.Context, request domain.ImportCueSheetRequest) (*domain.ImportCueSheetResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	imported, err := s.IsImported(ctx, domain.FindTrackFileByChecksumRequest{Checksum: request.Checksum})
+	if err != nil {
+		return nil, err
+	}
+
+	if imported {
+		return nil, domain.ErrAlreadyImported
+	}
+
+	blob, err := s.content.store(ctx, request.Body, *request.Mime, request.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("store track file: %w", err)
+	}
+
+	uploadedAt := time.Now().UTC()
+
+	tracks := make([]domain.ImportTrackRequest, 0, len(request.Tracks))
+	for _, track := range request.Tracks {
+		tracks = append(tracks, domain.ImportTrackRequest{
+			OwnerID:     request.OwnerID,
+			Title:       track.Title,
+			Artists:     track.Artists,
+			Album:       request.Album,
+			AlbumArtist: request.AlbumArtist,
+			ReleaseDate: request.ReleaseDate,
+			DiscNumber:  request.DiscNumber,
+			Position:    track.Position,
+			Genres:      request.Genres,
+			Visibility:  request.Visibility,
+			Filename:    request.Filename,
+			S3Key:       blob.S3Key,
+			Mime:        request.Mime,
+			Format:      request.Format,
+			Codec:       request.Codec,
+			Bitrate:     request.Bitrate,
+			SampleRate:  request.SampleRate,
+			Channels:    request.Channels,
+			Size:        blob.Size,
+			Duration:    track.Duration,
+			Checksum:    request.Checksum,
+			UploadedAt:  utils.Ptr(uploadedAt),
+			StartSample: track.StartSample,
+			EndSample:   track.EndSample,
+		})
+	}
+
+	tracksResponse, err := s.repository.ImportTracks(ctx, domain.ImportTracksRequest{Tracks: tracks})
+	if err != nil {
+		return nil, err
+	}
+
+	response := &domain.ImportCueSheetResponse{Tracks: tracksResponse.Tracks}
+	if len(tracksResponse.Tracks) > 0 {
+		response.AlbumID = tracksResponse.Tracks[0].AlbumID
+	}
+
+	return response, nil
+}
+
+// ImportTrackRequestFromMetadata собирает запрос импорта из разобранного файла и его тегов. Название без тега
+// берётся из имени файла, исполнитель альбома — из первого исполнителя трека. Body, OwnerID и Visibility
+// заполняет вызывающая сторона.
+func ImportTrackRequestFromMetadata(meta *audio.TrackFileMetadata, checksum string) domain.ImportTrackRequest {
+	tag := func(key string) string {
+		return strings.TrimSpace(meta.Tags[key])
+	}
+
+	title := tag("title")
+	if title == "" {
+		title = strings.TrimSuffix(meta.Filename, path.Ext(meta.Filename))
+	}
+
+	request := domain.ImportTrackRequest{
+		Title:      utils.Ptr(title),
+		Artists:    audio.SplitTag(tag("artist")),
+		DiscNumber: parseTagNumber(tag("discnumber")),
+		Position:   parseTagNumber(tag("tracknumber")),
+		Genres:     audio.SplitTag(tag("genre")),
+		Filename:   utils.Ptr(meta.Filename),
+		Mime:       utils.Ptr(meta.Mime),
+		Format:     utils.Ptr(meta.Format),
+		Codec:      utils.Ptr(meta.Codec),
+		Bitrate:    utils.Ptr(meta.Bitrate),
+		SampleRate: utils.Ptr(meta.SampleRate),
+		Channels:   utils.Ptr(meta.Channels),
+		Size:       utils.Ptr(meta.Size),
+		Duration:   utils.Ptr(meta.Duration),
+		Checksum:   utils.Ptr(checksum),
+	}
+
+	if album := tag("album"); album != "" {
+		request.Album = utils.Ptr(album)
+
+		albumArtist := tag("albumartist")
+		if albumArtist == "" && len(request.Artists) > 0 {
+			albumArtist = request.Artists[0]
+		}
+
+		if albumArtist != "" {
+			request.AlbumArtist = utils.Ptr(albumArtist)
+		}
+
+		releaseDate := unknownReleaseDate
+		if date, ok := parseTagDate(tag("date")); ok {
+			releaseDate = date
+		}
+
+		request.ReleaseDate = utils.Ptr(releaseDate)
+	}
+
+	return request
+}
+
+// ImportCueSheetRequestFromMetadata собирает запрос импорта образа диска по его разметке. Поля альбома берутся из
+// cue, а без них — из тегов самого файла; трек без TITLE называется "Track NN", без PERFORMER — по исполнителю
+// альбома. Body, OwnerID и Visibility заполняет вызывающая сторона.
+func ImportCueSheetRequestFromMetadata(meta *audio.TrackFileMetadata, cueSheet *audio.CueSheet, checksum string) domain.ImportCueSheetRequest {
+	tag := func(key string) string {
+		return strings.TrimSpace(meta.Tags[key])
+	}
+
+	firstOf := func(values ...string) string {
+		for _, value := range values {
+			if value = strings.TrimSpace(value); value != "" {
+				return value
+			}
+		}
+
+		return ""
+	}
+
+	album := firstOf(cueSheet.Title, tag("album"), strings.TrimSuffix(meta.Filename, path.Ext(meta.Filename)))
+	performer := firstOf(cueSheet.Performer, tag("albumartist"), tag("artist"))
+
+	releaseDate := unknownReleaseDate
+	if date, ok := parseTagDate(firstOf(cueSheet.Date, tag("date"))); ok {
+		releaseDate = date
+	}
+
+	discNumber := parseTagNumber(tag("discnumber"))
+	if cueSheet.DiscNumber > 0 {
+		discNumber = utils.Ptr(cueSheet.DiscNumber)
+	}
+
+	request := domain.ImportCueSheetRequest{
+		Album:       utils.Ptr(album),
+		AlbumArtist: utils.PtrIfNonZero(performer),
+		ReleaseDate: utils.Ptr(releaseDate),
+		DiscNumber:  discNumber,
+		Genres:      audio.SplitTag(firstOf(cueSheet.Genre, tag("genre"))),
+		Filename:    utils.Ptr(meta.Filename),
+		Mime:        utils.Ptr(meta.Mime),
+		Format:      utils.Ptr(meta.Format),
+		Codec:       utils.Ptr(meta.Codec),
+		Bitrate:     utils.Ptr(meta.Bitrate),
+		SampleRate:  utils.Ptr(meta.SampleRate),
+		Channels:    utils.Ptr(meta.Channels),
+		Size:        utils.Ptr(meta.Size),
+		Checksum:    utils.Ptr(checksum),
+	}
+
+	for _, span := range cueSheet.Spans(meta.TotalSamples) {
+		title := firstOf(span.Track.Title, fmt.Sprintf("Track %02d", span.Track.Number))
+		samples := span.End - span.Start
+
+		request.Tracks = append(request.Tracks, domain.ImportCueTrack{
+			Title:       utils.Ptr(title),
+			Artists:     audio.SplitTag(firstOf(span.Track.Performer, performer)),
+			Position:    utils.Ptr(span.Track.Number),
+			StartSample: utils.Ptr(int64(span.Start)),
+			EndSample:   utils.Ptr(int64(span.End)),
+			Duration:    utils.Ptr(time.Duration(samples) * time.Second / time.Duration(meta.SampleRate)),
+		})
+	}
+
+	return request
+}
+
+// parseTagNumber разбирает номер трека или диска вида "3" или "3/12". Нулевой и нечисловой номер — nil.
+func parseTagNumber(value string) *int {
+	value, _, _ = strings.Cut(value, "/")
+
+	number, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || number <= 0 {
+		return nil
+	}
+
+	return utils.Ptr(number)
+}
+
+// parseTagDate разбирает дату из тегов: полную, год-месяц или только год, в том числе с хвостом времени
+// ("2001-05-17T10:00:00").
+func parseTagDate(value string) (time.Time, bool) {
+	for _, layout := range []string{time.DateOnly, "2006-01", "2006"} {
+		if len(value) < len(layout) {
+			continue
+		}
+
+		if date, err := time.Parse(layout, value[:len(layout)]); err == nil {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
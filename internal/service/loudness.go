@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type LoudnessService struct {
+	repository Loudness
+	blobStore  storage.BlobStore
+}
+
+func NewLoudnessService(repository Loudness, blobStore storage.BlobStore) *LoudnessService {
+	return &LoudnessService{
+		repository: repository,
+		blobStore:  blobStore,
+	}
+}
+
+// ProcessNextLoudness измеряет громкость следующего неизмеренного файла и пересчитывает громкость альбомов, в
+// которые входит его трек. Возвращает false, если таких файлов нет. Ошибки анализа и отмена ctx обрабатываются
+// так же, как в WaveformsService.ProcessNextWaveform.
+func (s *LoudnessService) ProcessNextLoudness(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	trackFile, err := s.repository.ClaimLoudness(ctx, domain.ClaimLoudnessRequest{StaleAfter: &staleAfter})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("claim loudness: %w", err)
+	}
+
+	request, analyzeErr := s.analyzeLoudness(ctx, trackFile)
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+
+	if analyzeErr != nil {
+		request = domain.SaveLoudnessRequest{Error: utils.Ptr(analyzeErr.Error())}
+	}
+
+	request.TrackFileID = trackFile.ID
+
+	ctx = context.WithoutCancel(ctx)
+
+	err = s.repository.SaveLoudness(ctx, request)
+	if err != nil {
+		return true, fmt.Errorf("save loudness of track file %s: %w", trackFile.ID, err)
+	}
+
+	if analyzeErr != nil {
+		return true, nil
+	}
+
+	err = s.updateAlbumsLoudness(ctx, trackFile.TrackID)
+	if err != nil {
+		return true, fmt.Errorf("update albums loudness of track %s: %w", trackFile.TrackID, err)
+	}
+
+	return true, nil
+}
+
+// analyzeLoudness декодирует файл прямо из хранилища и измеряет его громкость.
+func (s *LoudnessService) analyzeLoudness(ctx context.Context, trackFile *domain.TrackFile) (domain.SaveLoudnessRequest, error) {
+	if trackFile.S3Key == nil || trackFile.Format == nil {
+		return domain.SaveLoudnessRequest{}, errors.New("track file has no stored object")
+	}
+
+	body, err := s.blobStore.Get(ctx, *trackFile.S3Key, 0, 0)
+	if err != nil {
+		return domain.SaveLoudnessRequest{}, fmt.Errorf("open track file object: %w", err)
+	}
+
+	defer body.Close()
+
+	decoder, err := decodeTrackFile(body, trackFile)
+	if err != nil {
+		return domain.SaveLoudnessRequest{}, err
+	}
+
+	loudness, err := audio.AnalyzeLoudness(decoder)
+	if err != nil {
+		return domain.SaveLoudnessRequest{}, err
+	}
+
+	histogram, err := loudness.Histogram.MarshalBinary()
+	if err != nil {
+		return domain.SaveLoudnessRequest{}, err
+	}
+
+	return domain.SaveLoudnessRequest{
+		IntegratedLoudness:  finiteOrNil(loudness.Integrated),
+		LoudnessRange:       utils.Ptr(loudness.Range),
+		TruePeak:            finiteOrNil(loudness.TruePeakDB()),
+		ReplayGainTrackGain: finiteOrNil(audio.ReplayGain(loudness.Integrated)),
+		ReplayGainTrackPeak: utils.Ptr(loudness.TruePeak),
+		Histogram:           histogram,
+	}, nil
+}
+
+// updateAlbumsLoudness пересчитывает громкость всех альбомов с треком trackID. Громкость альбома считается по
+// сложенным гистограммам его проанализированных треков, пики — наибольшие среди треков.
+func (s *LoudnessService) updateAlbumsLoudness(ctx context.Context, trackID *uuid.UUID) error {
+	response, err := s.repository.ListAlbumLoudnessSources(ctx, domain.ListAlbumLoudnessSourcesRequest{TrackID: trackID})
+	if err != nil {
+		return err
+	}
+
+	albums := make(map[uuid.UUID]*albumLoudness)
+	order := make([]uuid.UUID, 0)
+
+	for _, source := range response.Sources {
+		var histogram audio.LoudnessHistogram
+		if err = histogram.UnmarshalBinary(source.Histogram); err != nil {
+			return fmt.Errorf("decode loudness histogram of track %s: %w", source.TrackID, err)
+		}
+
+		album, ok := albums[*source.AlbumID]
+		if !ok {
+			album = &albumLoudness{}
+			albums[*source.AlbumID] = album
+			order = append(order, *source.AlbumID)
+		}
+
+		album.histogram.Merge(&histogram)
+		album.truePeak = maxPtr(album.truePeak, source.TruePeak)
+		album.peak = maxPtr(album.peak, source.ReplayGainTrackPeak)
+	}
+
+	for _, albumID := range order {
+		album := albums[albumID]
+		integrated := album.histogram.Integrated()
+
+		err = s.repository.UpdateAlbumLoudness(ctx, domain.UpdateAlbumLoudnessRequest{
+			AlbumID:             utils.Ptr(albumID),
+			IntegratedLoudness:  finiteOrNil(integrated),
+			LoudnessRange:       utils.Ptr(album.histogram.Range()),
+			TruePeak:            album.truePeak,
+			ReplayGainAlbumGain: finiteOrNil(audio.ReplayGain(integrated)),
+			ReplayGainAlbumPeak: album.peak,
+		})
+		if err != nil {
+			return fmt.Errorf("update loudness of album %s: %w", albumID, err)
+		}
+	}
+
+	return nil
+}
+
+// albumLoudness накопитель измерений треков одного альбома.
+type albumLoudness struct {
+	histogram audio.LoudnessHistogram
+	truePeak  *float64
+	peak      *float64
+}
+
+// finiteOrNil nil вместо бесконечности: у тишины нет ни громкости, ни пика в децибелах.
+func finiteOrNil(v float64) *float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+
+	return &v
+}
+
+func maxPtr(a, b *float64) *float64 {
+	if a == nil {
+		return b
+	}
+
+	if b == nil || *a >= *b {
+		return a
+	}
+
+	return b
+}
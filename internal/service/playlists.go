@@ -14,8 +14,16 @@ func NewPlaylistsService(repository Playlists) *PlaylistsService {
 	return &PlaylistsService{repository: repository}
 }
 
+// CreatePlaylist создаёт плейлист, владельцем которого становится вызывающий пользователь.
 func (s *PlaylistsService) CreatePlaylist(ctx context.Context, request domain.CreatePlaylistRequest) (*domain.CreatePlaylistResponse, error) {
-	err := request.Validate()
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.OwnerID = &principal.UserID
+
+	err = request.Validate()
 	if err != nil {
 		return nil, err
 	}
@@ -23,16 +31,25 @@ func (s *PlaylistsService) CreatePlaylist(ctx context.Context, request domain.Cr
 	return s.repository.CreatePlaylist(ctx, request)
 }
 
+// GetPlaylist плейлист по ID, если вызывающий может его видеть; чужой приватный плейлист — domain.ErrNotFound.
 func (s *PlaylistsService) GetPlaylist(ctx context.Context, request domain.GetPlaylistRequest) (*domain.GetPlaylistResponse, error) {
 	err := request.Validate()
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repository.GetPlaylist(ctx, request)
+	playlist, err := viewPlaylist(ctx, s.repository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GetPlaylistResponse{Playlist: playlist}, nil
 }
 
+// ListPlaylists публичные плейлисты и собственные плейлисты вызывающего; администратор видит все.
 func (s *PlaylistsService) ListPlaylists(ctx context.Context, request domain.ListPlaylistsRequest) (*domain.ListPlaylistsResponse, error) {
+	request.ViewerID, request.ViewerIsAdmin = listViewer(ctx)
+
 	err := request.Validate()
 	if err != nil {
 		return nil, err
@@ -47,6 +64,10 @@ func (s *PlaylistsService) UpdatePlaylist(ctx context.Context, request domain.Up
 		return err
 	}
 
+	if err = requirePlaylistOwner(ctx, s.repository, request.ID); err != nil {
+		return err
+	}
+
 	return s.repository.UpdatePlaylist(ctx, request)
 }
 
@@ -56,5 +77,9 @@ func (s *PlaylistsService) DeletePlaylist(ctx context.Context, request domain.De
 		return err
 	}
 
+	if err = requirePlaylistOwner(ctx, s.repository, request.ID); err != nil {
+		return err
+	}
+
 	return s.repository.DeletePlaylist(ctx, request)
 }
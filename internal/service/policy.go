@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/auth"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// Права проверяются в сервисах, а не в роутере, чтобы одинаково действовать для REST и Connect. Анонимный вызов
+// там, где нужен пользователь, — domain.ErrUnauthenticated, недостаточно прав — domain.ErrPermissionDenied.
+// Контент (треки, альбомы, плейлисты, загрузки) меняет его владелец или администратор, а владельцем созданного
+// становится сам principal, что бы ни пришло в запросе. Роль principal берётся из БД при каждом запросе, так что
+// снятая роль администратора действует сразу. Роль выдаёт только администратор, поэтому первого администратора
+// назначают напрямую в БД.
+//
+// Приватные треки и плейлисты видят только владелец и администратор, скрытые (unlisted) открываются по ID, но не
+// попадают в списки, а публичные видят все, в том числе анонимы. Чужой приватный контент неотличим от
+// несуществующего. Те же правила действуют для файлов, стриминга, HLS, waveform и дубликатов трека.
+
+// principalFrom principal запроса или domain.ErrUnauthenticated, если запрос анонимный.
+func principalFrom(ctx context.Context) (*domain.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	return principal, nil
+}
+
+// requireAdmin пропускает только администраторов.
+func requireAdmin(ctx context.Context) (*domain.Principal, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !principal.IsAdmin() {
+		return nil, domain.ErrPermissionDenied
+	}
+
+	return principal, nil
+}
+
+// requireOwnerOrAdmin пропускает владельца ownerID и администраторов. Ресурс без владельца (его пользователь
+// удалён) меняет только администратор.
+func requireOwnerOrAdmin(ctx context.Context, ownerID *uuid.UUID) (*domain.Principal, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if principal.IsAdmin() || (ownerID != nil && *ownerID == principal.UserID) {
+		return principal, nil
+	}
+
+	return nil, domain.ErrPermissionDenied
+}
+
+// requireTrackOwner пропускает загрузившего трек id и администраторов.
+func requireTrackOwner(ctx context.Context, tracks Tracks, id *uuid.UUID) error {
+	if _, err := principalFrom(ctx); err != nil {
+		return err
+	}
+
+	response, err := tracks.GetTrack(ctx, domain.GetTrackRequest{ID: id})
+	if err != nil {
+		return err
+	}
+
+	_, err = requireOwnerOrAdmin(ctx, response.Track.UploaderID)
+
+	return err
+}
+
+// requireAlbumOwner пропускает создателя альбома id и администраторов.
+func requireAlbumOwner(ctx context.Context, albums Albums, id *uuid.UUID) error {
+	if _, err := principalFrom(ctx); err != nil {
+		return err
+	}
+
+	response, err := albums.GetAlbum(ctx, domain.GetAlbumRequest{ID: id})
+	if err != nil {
+		return err
+	}
+
+	_, err = requireOwnerOrAdmin(ctx, response.Album.OwnerID)
+
+	return err
+}
+
+// requirePlaylistOwner пропускает владельца плейлиста id и администраторов.
+func requirePlaylistOwner(ctx context.Context, playlists Playlists, id *uuid.UUID) error {
+	if _, err := principalFrom(ctx); err != nil {
+		return err
+	}
+
+	response, err := playlists.GetPlaylist(ctx, domain.GetPlaylistRequest{ID: id})
+	if err != nil {
+		return err
+	}
+
+	_, err = requireOwnerOrAdmin(ctx, response.Playlist.OwnerID)
+
+	return err
+}
+
+// canView может ли principal (nil — анонимный запрос) открыть по ID контент владельца ownerID с видимостью
+// visibility.
+func canView(principal *domain.Principal, ownerID *uuid.UUID, visibility *domain.Visibility) bool {
+	switch utils.ValueOrZero(visibility) {
+	case domain.VisibilityPublic, domain.VisibilityUnlisted:
+		return true
+	default:
+		return principal.IsAdmin() || (principal != nil && ownerID != nil && *ownerID == principal.UserID)
+	}
+}
+
+// canList попадает ли контент в списки, которые видит principal: публичный — для всех, а свой — для владельца.
+func canList(principal *domain.Principal, ownerID *uuid.UUID, visibility *domain.Visibility) bool {
+	if utils.ValueOrZero(visibility) == domain.VisibilityPublic {
+		return true
+	}
+
+	return principal.IsAdmin() || (principal != nil && ownerID != nil && *ownerID == principal.UserID)
+}
+
+// listViewer зритель списка для запросов к БД: его ID (nil у анонима) и признак администратора.
+func listViewer(ctx context.Context) (*uuid.UUID, *bool) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, utils.Ptr(false)
+	}
+
+	return &principal.UserID, utils.Ptr(principal.IsAdmin())
+}
+
+// viewTrack трек id, если его можно открыть в этом запросе. Чужой приватный трек неотличим от несуществующего:
+// domain.ErrNotFound.
+func viewTrack(ctx context.Context, tracks Tracks, id *uuid.UUID) (*domain.Track, error) {
+	response, err := tracks.GetTrack(ctx, domain.GetTrackRequest{ID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if !canView(principal, response.Track.UploaderID, response.Track.Visibility) {
+		return nil, fmt.Errorf("track %s: %w", id, domain.ErrNotFound)
+	}
+
+	return response.Track, nil
+}
+
+// viewPlaylist плейлист id, если его можно открыть в этом запросе (см. viewTrack).
+func viewPlaylist(ctx context.Context, playlists Playlists, id *uuid.UUID) (*domain.Playlist, error) {
+	response, err := playlists.GetPlaylist(ctx, domain.GetPlaylistRequest{ID: id})
+	if err != nil {
+		return nil, err
+	}
+
+	principal, _ := auth.PrincipalFromContext(ctx)
+	if !canView(principal, response.Playlist.OwnerID, response.Playlist.Visibility) {
+		return nil, fmt.Errorf("playlist %s: %w", id, domain.ErrNotFound)
+	}
+
+	return response.Playlist, nil
+}
@@ -2,16 +2,33 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"time"
 
+	"github.com/untea/bottom_babruysk/internal/audio"
 	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
 )
 
+// Файлы трека добавляет, меняет и удаляет только владелец трека (или администратор), а видит тот, кому виден сам
+// трек.
+
 type TrackFilesService struct {
 	repository TrackFiles
+	tracks     Tracks
+	blobStore  storage.BlobStore
+	content    contentStore
 }
 
-func NewTrackFilesService(repository TrackFiles) *TrackFilesService {
-	return &TrackFilesService{repository: repository}
+func NewTrackFilesService(repository TrackFiles, tracks Tracks, blobs Blobs, blobStore storage.BlobStore) *TrackFilesService {
+	return &TrackFilesService{
+		repository: repository,
+		tracks:     tracks,
+		blobStore:  blobStore,
+		content:    contentStore{repository: blobs, blobStore: blobStore},
+	}
 }
 
 func (s *TrackFilesService) CreateTrackFile(ctx context.Context, request domain.CreateTrackFileRequest) (*domain.CreateTrackFileResponse, error) {
@@ -20,24 +37,87 @@ func (s *TrackFilesService) CreateTrackFile(ctx context.Context, request domain.
 		return nil, err
 	}
 
+	if err = requireTrackOwner(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, err
+	}
+
 	return s.repository.CreateTrackFile(ctx, request)
 }
 
+// UploadTrackFile сохраняет содержимое файла в хранилище и создаёт запись track_files. Размер и контрольная сумма
+// считаются по фактически полученным байтам. Без Body файл ссылается на содержимое с контрольной суммой Checksum,
+// если оно уже есть у вызывающего.
+func (s *TrackFilesService) UploadTrackFile(ctx context.Context, request domain.UploadTrackFileRequest) (*domain.CreateTrackFileResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if err = requireTrackOwner(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, err
+	}
+
+	blob, err := s.content.store(ctx, request.Body, *request.Mime, request.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("store track file: %w", err)
+	}
+
+	create := request.CreateTrackFileRequest
+	create.S3Key = blob.S3Key
+	create.Size = blob.Size
+	create.Checksum = blob.Checksum
+	create.UploadedAt = utils.Ptr(time.Now().UTC())
+
+	if err = create.Validate(); err != nil {
+		return nil, err
+	}
+
+	return s.repository.CreateTrackFile(ctx, create)
+}
+
 func (s *TrackFilesService) GetTrackFile(ctx context.Context, request domain.GetTrackFileRequest) (*domain.GetTrackFileResponse, error) {
 	err := request.Validate()
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err = viewTrack(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, err
+	}
+
 	return s.repository.GetTrackFile(ctx, request)
 }
 
+// OpenTrackFile открывает содержимое файла трека на чтение с offset; при length <= 0 читается до конца.
+func (s *TrackFilesService) OpenTrackFile(ctx context.Context, request domain.GetTrackFileRequest, offset, length int64) (io.ReadCloser, *domain.TrackFile, error) {
+	response, err := s.GetTrackFile(ctx, request)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trackFile := response.TrackFile
+	if trackFile.S3Key == nil {
+		return nil, nil, fmt.Errorf("track file %s: %w", trackFile.ID, storage.ErrNotFound)
+	}
+
+	body, err := s.blobStore.Get(ctx, *trackFile.S3Key, offset, length)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return body, trackFile, nil
+}
+
 func (s *TrackFilesService) ListTrackFiles(ctx context.Context, request domain.ListTrackFilesRequest) (*domain.ListTrackFilesResponse, error) {
 	err := request.Validate()
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err = viewTrack(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, err
+	}
+
 	return s.repository.ListTrackFiles(ctx, request)
 }
 
@@ -47,14 +127,39 @@ func (s *TrackFilesService) UpdateTrackFile(ctx context.Context, request domain.
 		return err
 	}
 
+	if err = requireTrackOwner(ctx, s.tracks, request.TrackID); err != nil {
+		return err
+	}
+
 	return s.repository.UpdateTrackFile(ctx, request)
 }
 
+// DeleteTrackFile удаляет запись track_files. Объект с содержимым, если на него больше ничего не ссылается,
+// удалит BlobsService.ProcessNextUnreferencedBlob.
 func (s *TrackFilesService) DeleteTrackFile(ctx context.Context, request domain.DeleteTrackFileRequest) error {
 	err := request.Validate()
 	if err != nil {
 		return err
 	}
 
+	if err = requireTrackOwner(ctx, s.tracks, request.TrackID); err != nil {
+		return err
+	}
+
 	return s.repository.DeleteTrackFile(ctx, request)
 }
+
+// decodeTrackFile открывает декодер содержимого файла трека. Для трека, размеченного в образе диска, декодер
+// ограничен его границами: сэмплы до начала трека декодируются и отбрасываются.
+func decodeTrackFile(body io.Reader, trackFile *domain.TrackFile) (audio.PCMDecoder, error) {
+	decoder, err := audio.NewPCMDecoder(body, *trackFile.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	if trackFile.StartSample == nil || trackFile.EndSample == nil {
+		return decoder, nil
+	}
+
+	return audio.NewPCMRange(decoder, uint64(*trackFile.StartSample), uint64(*trackFile.EndSample)), nil
+}
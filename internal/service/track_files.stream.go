@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// StreamTrack выбирает файл трека для проигрывания и открывает его содержимое с произвольным доступом. Явно
+// заданные format/codec фильтруют файлы, а среди оставшихся побеждает файл с наибольшим q из Accept, при равенстве
+// — с большим битрейтом.
+func (s *TrackFilesService) StreamTrack(ctx context.Context, request domain.StreamTrackRequest) (*storage.ObjectReader, *domain.TrackFile, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err = viewTrack(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, nil, err
+	}
+
+	response, err := s.repository.ListTrackFiles(ctx, domain.ListTrackFilesRequest{TrackID: request.TrackID})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var candidates []*domain.TrackFile
+
+	for _, trackFile := range response.TrackFiles {
+		if request.Format != nil && utils.ValueOrZero(trackFile.Format) != *request.Format {
+			continue
+		}
+
+		if request.Codec != nil && utils.ValueOrZero(trackFile.Codec) != *request.Codec {
+			continue
+		}
+
+		// Трек из образа диска отдать как есть нельзя: объект — весь образ. Такие треки звучат через свои копии.
+		if trackFile.S3Key == nil || trackFile.StartSample != nil {
+			continue
+		}
+
+		candidates = append(candidates, trackFile)
+	}
+
+	if len(candidates) == 0 {
+		return nil, nil, fmt.Errorf("track %s has no matching files: %w", request.TrackID, domain.ErrNotFound)
+	}
+
+	trackFile := bestAcceptable(candidates, parseAccept(request.Accept))
+	if trackFile == nil {
+		return nil, nil, fmt.Errorf("track %s: %w", request.TrackID, domain.ErrNotAcceptable)
+	}
+
+	// Размер берём из хранилища: запись в track_files могла быть создана вручную и не совпадать с объектом.
+	object, err := s.blobStore.Stat(ctx, *trackFile.S3Key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return storage.NewObjectReader(ctx, s.blobStore, object.Key, object.Size), trackFile, nil
+}
+
+type mediaRange struct {
+	mimeType string
+	q        float64
+}
+
+// parseAccept разбирает заголовок Accept. Пустой заголовок равносилен "*/*".
+func parseAccept(header string) []mediaRange {
+	if strings.TrimSpace(header) == "" {
+		return []mediaRange{{mimeType: "*/*", q: 1}}
+	}
+
+	var ranges []mediaRange
+
+	for _, part := range strings.Split(header, ",") {
+		mimeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			q, err = strconv.ParseFloat(raw, 64)
+			if err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{mimeType: mimeType, q: q})
+	}
+
+	return ranges
+}
+
+// acceptQuality возвращает q самого специфичного диапазона, подходящего под mimeType (RFC 9110, 12.5.1).
+func acceptQuality(ranges []mediaRange, mimeType string) float64 {
+	mainType, _, _ := strings.Cut(mimeType, "/")
+
+	best, specificity := 0.0, -1
+
+	for _, r := range ranges {
+		var level int
+
+		switch {
+		case r.mimeType == mimeType:
+			level = 2
+		case r.mimeType == mainType+"/*":
+			level = 1
+		case r.mimeType == "*/*":
+			level = 0
+		default:
+			continue
+		}
+
+		if level > specificity {
+			best, specificity = r.q, level
+		}
+	}
+
+	return best
+}
+
+func bestAcceptable(trackFiles []*domain.TrackFile, ranges []mediaRange) *domain.TrackFile {
+	var (
+		best        *domain.TrackFile
+		bestQuality float64
+	)
+
+	for _, trackFile := range trackFiles {
+		mimeType, _, err := mime.ParseMediaType(utils.ValueOrZero(trackFile.Mime))
+		if err != nil {
+			continue
+		}
+
+		q := acceptQuality(ranges, mimeType)
+		if q <= 0 {
+			continue
+		}
+
+		if best == nil || q > bestQuality ||
+			(q == bestQuality && utils.ValueOrZero(trackFile.Bitrate) > utils.ValueOrZero(best.Bitrate)) {
+			best, bestQuality = trackFile, q
+		}
+	}
+
+	return best
+}
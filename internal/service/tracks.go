@@ -14,8 +14,16 @@ func NewTracksService(repository Tracks) *TracksService {
 	return &TracksService{repository: repository}
 }
 
+// CreateTrack создаёт трек, загруженный вызывающим пользователем.
 func (s *TracksService) CreateTrack(ctx context.Context, request domain.CreateTrackRequest) (*domain.CreateTrackResponse, error) {
-	err := request.Validate()
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.UploaderID = &principal.UserID
+
+	err = request.Validate()
 	if err != nil {
 		return nil, err
 	}
@@ -23,16 +31,25 @@ func (s *TracksService) CreateTrack(ctx context.Context, request domain.CreateTr
 	return s.repository.CreateTrack(ctx, request)
 }
 
+// GetTrack трек по ID, если вызывающий может его видеть; чужой приватный трек — domain.ErrNotFound.
 func (s *TracksService) GetTrack(ctx context.Context, request domain.GetTrackRequest) (*domain.GetTrackResponse, error) {
 	err := request.Validate()
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repository.GetTrack(ctx, request)
+	track, err := viewTrack(ctx, s.repository, request.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.GetTrackResponse{Track: track}, nil
 }
 
+// ListTracks публичные треки и собственные треки вызывающего; администратор видит все.
 func (s *TracksService) ListTracks(ctx context.Context, request domain.ListTracksRequest) (*domain.ListTracksResponse, error) {
+	request.ViewerID, request.ViewerIsAdmin = listViewer(ctx)
+
 	err := request.Validate()
 	if err != nil {
 		return nil, err
@@ -47,6 +64,10 @@ func (s *TracksService) UpdateTrack(ctx context.Context, request domain.UpdateTr
 		return err
 	}
 
+	if err = requireTrackOwner(ctx, s.repository, request.ID); err != nil {
+		return err
+	}
+
 	return s.repository.UpdateTrack(ctx, request)
 }
 
@@ -56,5 +77,9 @@ func (s *TracksService) DeleteTrack(ctx context.Context, request domain.DeleteTr
 		return err
 	}
 
+	if err = requireTrackOwner(ctx, s.repository, request.ID); err != nil {
+		return err
+	}
+
 	return s.repository.DeleteTrack(ctx, request)
 }
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/internal/transcode"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type TranscodesService struct {
+	repository Transcodes
+	blobStore  storage.BlobStore
+	content    contentStore
+	transcoder transcode.Transcoder
+	renditions []domain.Rendition
+}
+
+// NewTranscodesService renditions — копии, которые нужно получить для каждого трека; все они должны
+// поддерживаться transcoder.
+func NewTranscodesService(repository Transcodes, blobs Blobs, blobStore storage.BlobStore, transcoder transcode.Transcoder, renditions []domain.Rendition) *TranscodesService {
+	return &TranscodesService{
+		repository: repository,
+		blobStore:  blobStore,
+		content:    contentStore{repository: blobs, blobStore: blobStore},
+		transcoder: transcoder,
+		renditions: renditions,
+	}
+}
+
+// ProcessNextTranscode получает следующую недостающую копию трека и регистрирует её в track_files. Возвращает
+// false, если делать нечего. Ошибка транскодирования сохраняется в задании, и эта копия больше не заказывается;
+// если же работа прервана отменой ctx, задание будет взято повторно через staleAfter.
+func (s *TranscodesService) ProcessNextTranscode(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	if len(s.renditions) == 0 {
+		return false, nil
+	}
+
+	task, err := s.repository.ClaimTranscode(ctx, domain.ClaimTranscodeRequest{
+		StaleAfter: &staleAfter,
+		Renditions: s.renditions,
+	})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("claim transcode: %w", err)
+	}
+
+	rendition := domain.Rendition{
+		Format:  utils.ValueOrZero(task.TargetFormat),
+		Codec:   utils.ValueOrZero(task.TargetCodec),
+		Bitrate: utils.ValueOrZero(task.TargetBitrate),
+	}
+
+	transcodeErr := s.transcode(ctx, task, rendition)
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+
+	if transcodeErr == nil {
+		return true, nil
+	}
+
+	err = s.repository.FailTranscode(context.WithoutCancel(ctx), domain.FailTranscodeRequest{
+		SourceID:  task.ID,
+		Rendition: rendition,
+		Error:     utils.Ptr(transcodeErr.Error()),
+	})
+	if err != nil {
+		return true, fmt.Errorf("save transcode error of track file %s to %s: %w", task.ID, rendition, err)
+	}
+
+	return true, nil
+}
+
+// transcode транскодирует оригинал прямо из хранилища в хранилище, по пути считая размер и контрольную сумму, и
+// регистрирует копию. Если регистрация не удалась, на сохранённое содержимое ничего не ссылается, и его удалит
+// BlobsService.ProcessNextUnreferencedBlob.
+func (s *TranscodesService) transcode(ctx context.Context, task *domain.TranscodeTask, rendition domain.Rendition) error {
+	if task.S3Key == nil || task.Format == nil {
+		return errors.New("track file has no stored object")
+	}
+
+	source, err := s.blobStore.Get(ctx, *task.S3Key, 0, 0)
+	if err != nil {
+		return fmt.Errorf("open track file object: %w", err)
+	}
+
+	defer source.Close()
+
+	decoder, err := decodeTrackFile(source, &task.TrackFile)
+	if err != nil {
+		return err
+	}
+
+	extension, mime := transcode.FileType(rendition)
+	filename := renditionFilename(utils.ValueOrZero(task.Filename), rendition, extension)
+
+	type transcoded struct {
+		result *transcode.Result
+		err    error
+	}
+
+	output, pipe := io.Pipe()
+	done := make(chan transcoded, 1)
+
+	go func() {
+		result, err := s.transcoder.Transcode(ctx, decoder, rendition, pipe)
+		pipe.CloseWithError(err)
+		done <- transcoded{result: result, err: err}
+	}()
+
+	blob, storeErr := s.content.store(ctx, output, mime, nil)
+	// Если сохранение бросило чтение на полпути, транскодер иначе так и ждал бы на записи в pipe.
+	output.CloseWithError(errors.New("store closed"))
+
+	encoded := <-done
+
+	if encoded.err != nil {
+		return encoded.err
+	}
+
+	if storeErr != nil {
+		return fmt.Errorf("store rendition: %w", storeErr)
+	}
+
+	create := domain.CreateTrackFileRequest{
+		TrackID:    task.TrackID,
+		Filename:   utils.Ptr(filename),
+		S3Key:      blob.S3Key,
+		Mime:       utils.Ptr(mime),
+		Format:     utils.Ptr(rendition.Format),
+		Codec:      utils.Ptr(rendition.Codec),
+		Bitrate:    utils.Ptr(encoded.result.Bitrate),
+		SampleRate: utils.Ptr(encoded.result.SampleRate),
+		Channels:   utils.Ptr(encoded.result.Channels),
+		Size:       blob.Size,
+		Duration:   utils.Ptr(encoded.result.Duration),
+		Checksum:   blob.Checksum,
+		UploadedAt: utils.Ptr(time.Now().UTC()),
+	}
+
+	if err = create.Validate(); err != nil {
+		return err
+	}
+
+	_, err = s.repository.CompleteTranscode(ctx, domain.CompleteTranscodeRequest{
+		SourceID:  task.ID,
+		Rendition: rendition,
+		TrackFile: create,
+	})
+
+	return err
+}
+
+// renditionFilename имя копии по имени оригинала: "Song.flac" -> "Song-320k.mp3" (для PCM — "Song.wav").
+func renditionFilename(original string, rendition domain.Rendition, extension string) string {
+	name := strings.TrimSuffix(path.Base(original), path.Ext(original))
+	if name == "" || name == "." || name == "/" {
+		name = "track"
+	}
+
+	if rendition.Bitrate > 0 {
+		name += "-" + strconv.Itoa(rendition.Bitrate) + "k"
+	}
+
+	return name + extension
+}
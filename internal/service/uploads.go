@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type UploadsService struct {
+	repository Uploads
+	tracks     Tracks
+	trackFiles TrackFiles
+	blobStore  storage.BlobStore
+	content    contentStore
+}
+
+func NewUploadsService(repository Uploads, tracks Tracks, trackFiles TrackFiles, blobs Blobs, blobStore storage.BlobStore) *UploadsService {
+	return &UploadsService{
+		repository: repository,
+		tracks:     tracks,
+		trackFiles: trackFiles,
+		blobStore:  blobStore,
+		content:    contentStore{repository: blobs, blobStore: blobStore},
+	}
+}
+
+// CreateUpload сохраняет файл в хранилище и ставит загрузку в очередь со статусом pending. Без Body загрузка
+// ссылается на содержимое с контрольной суммой Checksum, если оно уже есть у вызывающего.
+func (s *UploadsService) CreateUpload(ctx context.Context, request domain.CreateUploadRequest) (*domain.CreateUploadResponse, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.OwnerID = &principal.UserID
+
+	err = request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := s.content.store(ctx, request.Body, *request.Mime, request.Checksum)
+	if err != nil {
+		return nil, fmt.Errorf("store upload: %w", err)
+	}
+
+	request.S3Key = blob.S3Key
+	request.Size = blob.Size
+	request.Checksum = blob.Checksum
+
+	return s.repository.CreateUpload(ctx, request)
+}
+
+// GetUpload загрузку видит её владелец и администратор.
+func (s *UploadsService) GetUpload(ctx context.Context, request domain.GetUploadRequest) (*domain.GetUploadResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = principalFrom(ctx); err != nil {
+		return nil, err
+	}
+
+	response, err := s.repository.GetUpload(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = requireOwnerOrAdmin(ctx, response.Upload.OwnerID); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ListUploads пользователь видит только свои загрузки, администратор — любые.
+func (s *UploadsService) ListUploads(ctx context.Context, request domain.ListUploadsRequest) (*domain.ListUploadsResponse, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !principal.IsAdmin() {
+		request.OwnerID = &principal.UserID
+	}
+
+	err = request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.ListUploads(ctx, request)
+}
+
+// ProcessNextUpload захватывает одну загрузку из очереди и обрабатывает её. Возвращает false, если очередь пуста.
+// Ошибка обработки самой загрузки не возвращается: она записывается в uploads.error со статусом failed.
+func (s *UploadsService) ProcessNextUpload(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	upload, err := s.repository.ClaimUpload(ctx, domain.ClaimUploadRequest{StaleAfter: &staleAfter})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("claim upload: %w", err)
+	}
+
+	trackID, processErr := s.processUpload(ctx, upload)
+	if ctx.Err() != nil {
+		// Прерванная загрузка останется в processing и будет взята повторно через staleAfter.
+		return true, ctx.Err()
+	}
+
+	status := domain.UpdateUploadStatusRequest{
+		ID:      upload.ID,
+		Status:  utils.Ptr(domain.UploadStatusDone),
+		TrackID: trackID,
+	}
+
+	if processErr != nil {
+		status.Status = utils.Ptr(domain.UploadStatusFailed)
+		status.Error = utils.Ptr(processErr.Error())
+	}
+
+	// Статус фиксируем даже если исходный контекст уже отменён, иначе загрузка повиснет в processing.
+	err = s.repository.UpdateUploadStatus(context.WithoutCancel(ctx), status)
+	if err != nil {
+		return true, fmt.Errorf("update upload %s status: %w", upload.ID, err)
+	}
+
+	return true, nil
+}
+
+// processUpload разбирает загруженный файл и создаёт по нему записи tracks и track_files. Если что-то пошло не так
+// после создания трека, трек удаляется вместе с файлами (on delete cascade).
+func (s *UploadsService) processUpload(ctx context.Context, upload *domain.Upload) (*uuid.UUID, error) {
+	// Возобновляемая загрузка, полученная целиком, ещё лежит частями.
+	if upload.S3Key == nil && utils.ValueOrZero(upload.Offset) == utils.ValueOrZero(upload.Size) {
+		if err := s.completeResumableUpload(ctx, upload); err != nil {
+			return nil, fmt.Errorf("complete resumable upload: %w", err)
+		}
+	}
+
+	if upload.S3Key == nil {
+		return nil, errors.New("upload has no stored object")
+	}
+
+	probe, err := s.probeUpload(ctx, upload)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := probe.meta
+
+	now := time.Now().UTC()
+	filename := utils.ValueOrZero(upload.Filename)
+
+	// Название берём из тегов файла, а если их нет — из имени файла без расширения.
+	title := strings.TrimSuffix(filename, path.Ext(filename))
+	if tagged := strings.TrimSpace(meta.Tags["title"]); tagged != "" {
+		title = tagged
+	}
+
+	track, err := s.tracks.CreateTrack(ctx, domain.CreateTrackRequest{
+		UploaderID:  upload.OwnerID,
+		Title:       utils.Ptr(title),
+		Subtitle:    utils.Ptr(""),
+		Description: utils.Ptr(""),
+		Duration:    utils.Ptr(meta.Duration),
+		Visibility:  utils.Ptr(domain.VisibilityPrivate),
+		UploadedAt:  upload.CreatedAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create track: %w", err)
+	}
+
+	// Файл трека ссылается на тот же объект, что и загрузка, без копирования. Объект удаляет только
+	// ProcessNextUnreferencedBlob, когда на него не ссылается ни одна строка track_files и uploads, поэтому удаление
+	// файла трека не трогает содержимое загрузки, и повторная обработка прочитает тот же объект.
+	_, err = s.trackFiles.CreateTrackFile(ctx, domain.CreateTrackFileRequest{
+		TrackID:        track.ID,
+		Filename:       upload.Filename,
+		S3Key:          upload.S3Key,
+		Mime:           utils.Ptr(meta.Mime),
+		Format:         utils.Ptr(meta.Format),
+		Codec:          utils.Ptr(meta.Codec),
+		Bitrate:        utils.Ptr(meta.Bitrate),
+		SampleRate:     utils.Ptr(meta.SampleRate),
+		Channels:       utils.Ptr(meta.Channels),
+		Size:           upload.Size,
+		Duration:       utils.Ptr(meta.Duration),
+		Checksum:       utils.Ptr(probe.checksum),
+		UploadedAt:     utils.Ptr(now),
+		Healthy:        probe.healthy,
+		IntegrityError: probe.integrityError,
+	})
+	if err != nil {
+		deleteErr := s.tracks.DeleteTrack(context.WithoutCancel(ctx), domain.DeleteTrackRequest{ID: track.ID})
+
+		return nil, errors.Join(fmt.Errorf("create track file: %w", err), deleteErr)
+	}
+
+	if genres := audio.SplitTag(meta.Tags["genre"]); len(genres) > 0 {
+		err = s.tracks.SetTrackGenres(ctx, domain.SetTrackGenresRequest{TrackID: track.ID, Genres: genres})
+		if err != nil {
+			deleteErr := s.tracks.DeleteTrack(context.WithoutCancel(ctx), domain.DeleteTrackRequest{ID: track.ID})
+
+			return nil, errors.Join(fmt.Errorf("set track genres: %w", err), deleteErr)
+		}
+	}
+
+	return track.ID, nil
+}
+
+// uploadProbe результат разбора объекта загрузки.
+type uploadProbe struct {
+	meta     *audio.TrackFileMetadata
+	checksum string
+	// healthy nil, если для формата нет проверки целостности.
+	healthy        *bool
+	integrityError *string
+}
+
+// probeUpload разбирает объект загрузки прямо в хранилище через audio.Probe. SHA-256 посчитан ещё при сохранении
+// загрузки; у загрузок, принятых раньше, чем он стал сохраняться, он считается отдельным последовательным проходом
+// по телу объекта. Для FLAC в том же проходе поток полностью декодируется и сверяется с MD5 из STREAMINFO:
+// повреждённый файл не отклоняется, а помечается как нездоровый.
+func (s *UploadsService) probeUpload(ctx context.Context, upload *domain.Upload) (*uploadProbe, error) {
+	object, err := s.blobStore.Stat(ctx, *upload.S3Key)
+	if err != nil {
+		return nil, fmt.Errorf("stat upload object: %w", err)
+	}
+
+	reader := storage.NewObjectReader(ctx, s.blobStore, object.Key, object.Size)
+	defer reader.Close()
+
+	meta, err := audio.Probe(reader, object.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	probe := &uploadProbe{meta: meta, checksum: utils.ValueOrZero(upload.Checksum)}
+	if meta.Format != domain.FormatFLAC && probe.checksum != "" {
+		return probe, nil
+	}
+
+	body, err := s.blobStore.Get(ctx, object.Key, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open upload object: %w", err)
+	}
+
+	defer body.Close()
+
+	hash := sha256.New()
+
+	if meta.Format == domain.FormatFLAC {
+		err = audio.VerifyFlac(io.TeeReader(body, hash))
+
+		switch {
+		case err == nil:
+			probe.healthy = utils.Ptr(true)
+		case errors.Is(err, audio.ErrFlacCorrupt) || errors.Is(err, audio.ErrFlacMD5Mismatch):
+			probe.healthy = utils.Ptr(false)
+			probe.integrityError = utils.Ptr(err.Error())
+		default:
+			return nil, fmt.Errorf("verify flac: %w", err)
+		}
+	}
+
+	if probe.checksum != "" {
+		return probe, nil
+	}
+
+	// Дочитываем то, что не понадобилось проверке (или весь объект, если проверки не было).
+	if _, err = io.Copy(hash, body); err != nil {
+		return nil, fmt.Errorf("read upload object: %w", err)
+	}
+
+	probe.checksum = hex.EncodeToString(hash.Sum(nil))
+
+	return probe, nil
+}
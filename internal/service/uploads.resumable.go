@@ -0,0 +1,275 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// Возобновляемые загрузки хранят каждую принятую часть отдельным объектом tus/<upload_id>/<offset>. Смещение,
+// до которого данные приняты, хранится в uploads.upload_offset. Загрузку, полученную целиком, берёт в работу
+// воркер загрузок: он склеивает части и сохраняет их по контрольной сумме, как содержимое обычной загрузки
+// (uploads.s3_key до этого пуст), удаляет части и дальше обрабатывает загрузку так же, как обычную. Запрос с
+// последней частью только фиксирует её, так что склейка не зависит от того, дождётся ли клиент ответа.
+
+// CreateResumableUpload регистрирует возобновляемую загрузку заранее известного размера.
+func (s *UploadsService) CreateResumableUpload(ctx context.Context, request domain.CreateResumableUploadRequest) (*domain.CreateUploadResponse, error) {
+	principal, err := principalFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request.OwnerID = &principal.UserID
+
+	err = request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.repository.CreateResumableUpload(ctx, request)
+}
+
+// AppendUploadChunk принимает очередную часть возобновляемой загрузки. Если поток оборвался посередине, всё, что
+// успело прийти, сохраняется и засчитывается: клиент продолжит с нового смещения.
+func (s *UploadsService) AppendUploadChunk(ctx context.Context, request domain.AppendUploadChunkRequest) (*domain.AppendUploadChunkResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	upload, err := s.resumableUpload(ctx, *request.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, size := utils.ValueOrZero(upload.Offset), utils.ValueOrZero(upload.Size)
+	if offset != *request.Offset {
+		return nil, fmt.Errorf("%w: expected %d, got %d", domain.ErrUploadOffsetMismatch, offset, *request.Offset)
+	}
+
+	chunk, written, readErr := spoolChunk(request.Body, size-offset)
+	if chunk != nil {
+		defer func() {
+			_ = chunk.Close()
+			_ = os.Remove(chunk.Name())
+		}()
+	}
+
+	if written == 0 {
+		if readErr != nil {
+			return nil, readErr
+		}
+
+		return &domain.AppendUploadChunkResponse{Offset: offset, Completed: offset == size}, nil
+	}
+
+	chunkKey := uploadChunkKey(*upload.ID, offset)
+
+	_, err = s.blobStore.Put(ctx, chunkKey, chunk, written, "application/offset+octet-stream")
+	if err != nil {
+		return nil, fmt.Errorf("store upload chunk: %w", err)
+	}
+
+	upload, err = s.repository.AdvanceUploadOffset(ctx, domain.AdvanceUploadOffsetRequest{
+		ID:     upload.ID,
+		Offset: &offset,
+		Delta:  &written,
+	})
+	if errors.Is(err, postgres.ErrNotFound) {
+		// Параллельная часть с тем же смещением успела раньше.
+		return nil, errors.Join(domain.ErrUploadOffsetMismatch, s.blobStore.Delete(ctx, chunkKey))
+	}
+
+	if err != nil {
+		return nil, errors.Join(err, s.blobStore.Delete(ctx, chunkKey))
+	}
+
+	response := &domain.AppendUploadChunkResponse{Offset: utils.ValueOrZero(upload.Offset)}
+	response.Completed = response.Offset == size
+
+	return response, nil
+}
+
+// TerminateUpload прерывает незавершённую возобновляемую загрузку и удаляет уже принятые части.
+func (s *UploadsService) TerminateUpload(ctx context.Context, request domain.DeleteUploadRequest) error {
+	err := request.Validate()
+	if err != nil {
+		return err
+	}
+
+	upload, err := s.resumableUpload(ctx, *request.ID)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repository.DeleteUpload(ctx, request); err != nil {
+		return err
+	}
+
+	return s.deleteUploadChunks(ctx, *upload.ID)
+}
+
+// resumableUpload незавершённая возобновляемая загрузка, которую продолжает или прерывает её владелец (или
+// администратор).
+func (s *UploadsService) resumableUpload(ctx context.Context, id uuid.UUID) (*domain.Upload, error) {
+	response, err := s.GetUpload(ctx, domain.GetUploadRequest{ID: &id})
+	if err != nil {
+		return nil, err
+	}
+
+	if utils.ValueOrZero(response.Upload.Status) != domain.UploadStatusUploading {
+		return nil, fmt.Errorf("%w: upload %s is %s", domain.ErrUploadNotResumable, id, utils.ValueOrZero(response.Upload.Status))
+	}
+
+	return response.Upload, nil
+}
+
+// completeResumableUpload сохраняет склеенные части взятой в работу загрузки как её содержимое и удаляет их. Если
+// склейка прервалась, части остаются, и загрузка, взятая повторно, склеится заново.
+func (s *UploadsService) completeResumableUpload(ctx context.Context, upload *domain.Upload) error {
+	chunks, err := s.blobStore.List(ctx, uploadChunkPrefix(*upload.ID))
+	if err != nil {
+		return fmt.Errorf("list upload chunks: %w", err)
+	}
+
+	sort.Slice(chunks, func(i, j int) bool { return chunks[i].Key < chunks[j].Key })
+
+	var expected int64
+
+	for _, chunk := range chunks {
+		offset, err := strconv.ParseInt(path.Base(chunk.Key), 10, 64)
+		if err != nil || offset != expected {
+			return fmt.Errorf("upload %s: chunk %s does not continue offset %d", upload.ID, chunk.Key, expected)
+		}
+
+		expected += chunk.Size
+	}
+
+	if expected != utils.ValueOrZero(upload.Size) {
+		return fmt.Errorf("upload %s: chunks cover %d of %d bytes", upload.ID, expected, utils.ValueOrZero(upload.Size))
+	}
+
+	body := &chunksReader{ctx: ctx, blobStore: s.blobStore, chunks: chunks}
+	defer body.Close()
+
+	blob, err := s.content.store(ctx, body, utils.ValueOrZero(upload.Mime), nil)
+	if err != nil {
+		return fmt.Errorf("assemble upload: %w", err)
+	}
+
+	err = s.repository.CompleteResumableUpload(ctx, domain.CompleteResumableUploadRequest{
+		ID:       upload.ID,
+		S3Key:    blob.S3Key,
+		Checksum: blob.Checksum,
+	})
+	if err != nil {
+		return err
+	}
+
+	upload.S3Key, upload.Checksum = blob.S3Key, blob.Checksum
+
+	// Содержимое уже привязано к загрузке, поэтому неудавшееся удаление частей обработку не прерывает.
+	_ = s.deleteUploadChunks(ctx, *upload.ID)
+
+	return nil
+}
+
+func (s *UploadsService) deleteUploadChunks(ctx context.Context, id uuid.UUID) error {
+	chunks, err := s.blobStore.List(ctx, uploadChunkPrefix(id))
+	if err != nil {
+		return fmt.Errorf("list upload chunks: %w", err)
+	}
+
+	var errs []error
+
+	for _, chunk := range chunks {
+		errs = append(errs, s.blobStore.Delete(ctx, chunk.Key))
+	}
+
+	return errors.Join(errs...)
+}
+
+func uploadChunkPrefix(id uuid.UUID) string {
+	return "tus/" + id.String() + "/"
+}
+
+// uploadChunkKey смещение дополняется нулями, чтобы лексикографический порядок ключей совпадал с порядком частей.
+func uploadChunkKey(id uuid.UUID, offset int64) string {
+	return fmt.Sprintf("%s%020d", uploadChunkPrefix(id), offset)
+}
+
+// spoolChunk сохраняет не больше limit байт из r во временный файл. Ошибка чтения возвращается вместе с уже
+// записанными байтами, а всё, что идёт после limit, отбрасывается.
+func spoolChunk(r io.Reader, limit int64) (*os.File, int64, error) {
+	tmp, err := os.CreateTemp("", "upload-chunk-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("create temp chunk: %w", err)
+	}
+
+	written, readErr := io.Copy(tmp, io.LimitReader(r, limit))
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		return tmp, 0, fmt.Errorf("rewind temp chunk: %w", err)
+	}
+
+	return tmp, written, readErr
+}
+
+// chunksReader последовательно читает части загрузки, открывая каждую только когда до неё дошла очередь.
+type chunksReader struct {
+	ctx       context.Context
+	blobStore storage.BlobStore
+	chunks    []*storage.Object
+	current   io.ReadCloser
+}
+
+func (r *chunksReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.chunks) == 0 {
+				return 0, io.EOF
+			}
+
+			body, err := r.blobStore.Get(r.ctx, r.chunks[0].Key, 0, 0)
+			if err != nil {
+				return 0, err
+			}
+
+			r.current, r.chunks = body, r.chunks[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if errors.Is(err, io.EOF) {
+			_ = r.current.Close()
+			r.current = nil
+
+			if n == 0 {
+				continue
+			}
+
+			err = nil
+		}
+
+		return n, err
+	}
+}
+
+func (r *chunksReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+
+	return r.current.Close()
+}
@@ -14,21 +14,27 @@ func NewUsersService(repository Users) *UsersService {
 	return &UsersService{repository: repository}
 }
 
+// CreateUser хеширует пароль и создаёт пользователя с любой ролью; доступно только администратору. Остальные
+// регистрируются через AuthService.Register. Занятый email — domain.ErrEmailTaken.
 func (s *UsersService) CreateUser(ctx context.Context, request domain.CreateUserRequest) (*domain.CreateUserResponse, error) {
-	err := request.Validate()
-	if err != nil {
+	if _, err := requireAdmin(ctx); err != nil {
 		return nil, err
 	}
 
-	return s.repository.CreateUser(ctx, request)
+	return createUser(ctx, s.repository, request)
 }
 
+// GetUser профиль с email виден только самому пользователю и администратору.
 func (s *UsersService) GetUser(ctx context.Context, request domain.GetUserRequest) (*domain.GetUserResponse, error) {
 	err := request.Validate()
 	if err != nil {
 		return nil, err
 	}
 
+	if _, err = requireOwnerOrAdmin(ctx, request.ID); err != nil {
+		return nil, err
+	}
+
 	return s.repository.GetUser(ctx, request)
 }
 
@@ -38,15 +44,30 @@ func (s *UsersService) ListUsers(ctx context.Context, request domain.ListUsersRe
 		return nil, err
 	}
 
+	if _, err = requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+
 	return s.repository.ListUsers(ctx, request)
 }
 
+// UpdateUser своё имя пользователь меняет сам, а роль меняет только администратор и только чужую: так он не
+// лишит себя прав по ошибке, оставив сервис без администраторов.
 func (s *UsersService) UpdateUser(ctx context.Context, request domain.UpdateUserRequest) error {
 	err := request.Validate()
 	if err != nil {
 		return err
 	}
 
+	principal, err := requireOwnerOrAdmin(ctx, request.ID)
+	if err != nil {
+		return err
+	}
+
+	if request.Role != nil && (!principal.IsAdmin() || *request.ID == principal.UserID) {
+		return domain.ErrPermissionDenied
+	}
+
 	return s.repository.UpdateUser(ctx, request)
 }
 
@@ -56,5 +77,9 @@ func (s *UsersService) DeleteUser(ctx context.Context, request domain.DeleteUser
 		return err
 	}
 
+	if _, err = requireAdmin(ctx); err != nil {
+		return err
+	}
+
 	return s.repository.DeleteUser(ctx, request)
 }
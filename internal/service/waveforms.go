@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+type WaveformsService struct {
+	repository Waveforms
+	tracks     Tracks
+	blobStore  storage.BlobStore
+}
+
+func NewWaveformsService(repository Waveforms, tracks Tracks, blobStore storage.BlobStore) *WaveformsService {
+	return &WaveformsService{
+		repository: repository,
+		tracks:     tracks,
+		blobStore:  blobStore,
+	}
+}
+
+// ProcessNextWaveform считает обзор формы волны для следующего файла без него. Возвращает false, если таких файлов
+// нет. Ошибка расчёта сохраняется в track_file_waveforms.error, и файл больше не берётся; если же расчёт прерван
+// отменой ctx, файл будет взят повторно через staleAfter.
+func (s *WaveformsService) ProcessNextWaveform(ctx context.Context, staleAfter time.Duration) (bool, error) {
+	trackFile, err := s.repository.ClaimWaveform(ctx, domain.ClaimWaveformRequest{StaleAfter: &staleAfter})
+	if errors.Is(err, postgres.ErrNotFound) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("claim waveform: %w", err)
+	}
+
+	peaks, computeErr := s.computeWaveform(ctx, trackFile)
+	if ctx.Err() != nil {
+		return true, ctx.Err()
+	}
+
+	request := domain.SaveWaveformRequest{
+		TrackFileID: trackFile.ID,
+		Peaks:       peaks,
+	}
+
+	if computeErr != nil {
+		request.Error = utils.Ptr(computeErr.Error())
+	}
+
+	err = s.repository.SaveWaveform(context.WithoutCancel(ctx), request)
+	if err != nil {
+		return true, fmt.Errorf("save waveform of track file %s: %w", trackFile.ID, err)
+	}
+
+	return true, nil
+}
+
+// computeWaveform декодирует файл прямо из хранилища и возвращает обзор в двоичном виде.
+func (s *WaveformsService) computeWaveform(ctx context.Context, trackFile *domain.TrackFile) ([]byte, error) {
+	if trackFile.S3Key == nil || trackFile.Format == nil {
+		return nil, errors.New("track file has no stored object")
+	}
+
+	body, err := s.blobStore.Get(ctx, *trackFile.S3Key, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open track file object: %w", err)
+	}
+
+	defer body.Close()
+
+	decoder, err := decodeTrackFile(body, trackFile)
+	if err != nil {
+		return nil, err
+	}
+
+	waveform, err := audio.ComputeWaveform(decoder)
+	if err != nil {
+		return nil, err
+	}
+
+	return waveform.MarshalBinary()
+}
+
+// GetWaveform возвращает обзор формы волны трека на запрошенное число точек, если вызывающему виден трек.
+func (s *WaveformsService) GetWaveform(ctx context.Context, request domain.GetWaveformRequest) (*domain.GetWaveformResponse, error) {
+	err := request.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = viewTrack(ctx, s.tracks, request.TrackID); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repository.GetTrackWaveform(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var waveform audio.Waveform
+	if err = waveform.UnmarshalBinary(stored.Peaks); err != nil {
+		return nil, fmt.Errorf("decode waveform of track file %s: %w", stored.TrackFileID, err)
+	}
+
+	points := domain.WaveformDefaultPoints
+	if request.Points != nil {
+		points = *request.Points
+	}
+
+	samplesPerPoint, peaks := waveform.Resample(points)
+
+	return &domain.GetWaveformResponse{
+		TrackFileID:     stored.TrackFileID,
+		SampleRate:      waveform.SampleRate,
+		SamplesPerPixel: samplesPerPoint,
+		Bits:            16,
+		Length:          len(peaks) / 2,
+		Data:            peaks,
+	}, nil
+}
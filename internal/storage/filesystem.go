@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// FileSystem хранит объекты как обычные файлы внутри каталога root. Подходит для локальной разработки и
+// однонодовых инсталляций.
+type FileSystem struct {
+	root string
+}
+
+func NewFileSystem(root string) (*FileSystem, error) {
+	if root == "" {
+		return nil, errors.New("filesystem storage: empty root path")
+	}
+
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("filesystem storage: resolve root: %w", err)
+	}
+
+	if err = os.MkdirAll(absRoot, 0o750); err != nil {
+		return nil, fmt.Errorf("filesystem storage: create root: %w", err)
+	}
+
+	return &FileSystem{root: absRoot}, nil
+}
+
+func (s *FileSystem) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filePath), 0o750); err != nil {
+		return nil, fmt.Errorf("create object directory: %w", err)
+	}
+
+	// Пишем во временный файл рядом с целевым и переименовываем в конце, чтобы читатели никогда не увидели
+	// недописанный объект.
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp object: %w", err)
+	}
+
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	written, err := io.Copy(tmp, contextReader{ctx: ctx, r: r})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("write object: %w", err)
+	}
+
+	if size >= 0 && written != size {
+		return nil, fmt.Errorf("write object: size mismatch: expected %d, got %d", size, written)
+	}
+
+	if err = os.Rename(tmp.Name(), filePath); err != nil {
+		return nil, fmt.Errorf("commit object: %w", err)
+	}
+
+	object, err := s.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType != "" {
+		object.ContentType = contentType
+	}
+
+	return object, nil
+}
+
+func (s *FileSystem) Get(_ context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(filePath) //nolint:gosec // путь проверен в s.path и не выходит за пределы root
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("open object: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("stat object: %w", err)
+	}
+
+	if offset < 0 || (offset > 0 && offset >= fi.Size()) {
+		_ = f.Close()
+		return nil, ErrInvalidRange
+	}
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("seek object: %w", err)
+	}
+
+	if length <= 0 {
+		return f, nil
+	}
+
+	return readCloser{Reader: io.LimitReader(f, length), Closer: f}, nil
+}
+
+func (s *FileSystem) Stat(_ context.Context, key string) (*Object, error) {
+	filePath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("stat object: %w", err)
+	}
+
+	if fi.IsDir() {
+		return nil, ErrNotFound
+	}
+
+	return s.object(key, fi), nil
+}
+
+func (s *FileSystem) Delete(_ context.Context, key string) error {
+	filePath, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("delete object: %w", err)
+	}
+
+	return nil
+}
+
+func (s *FileSystem) List(ctx context.Context, prefix string) ([]*Object, error) {
+	// Обходим только ближайший каталог, который может содержать ключи с данным префиксом.
+	dir := s.root
+	if i := strings.LastIndexByte(prefix, '/'); i >= 0 {
+		var err error
+
+		dir, err = s.path(prefix[:i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []*Object
+
+	err := filepath.WalkDir(dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+
+			return err
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		objects = append(objects, s.object(key, fi))
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+func (s *FileSystem) path(key string) (string, error) {
+	// Ключ должен уже быть в нормальной форме: без "..", "./", двойных и ведущих слешей.
+	if key == "" || path.Clean("/" + key)[1:] != key || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *FileSystem) object(key string, fi fs.FileInfo) *Object {
+	return &Object{
+		Key:         key,
+		Size:        fi.Size(),
+		ContentType: mime.TypeByExtension(path.Ext(key)),
+		ETag:        fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size()),
+		ModifiedAt:  fi.ModTime(),
+	}
+}
+
+// contextReader прерывает копирование, как только отменяется контекст.
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.r.Read(p)
+}
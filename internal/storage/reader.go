@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// readAtBlockSize ReadAt читает объект блоками такого размера и держит последний блок в памяти: парсеры
+// контейнеров делают много мелких чтений заголовков, и без этого каждое стало бы отдельным запросом к S3.
+const readAtBlockSize = 256 << 10
+
+// ObjectReader даёт io.ReadSeeker и io.ReaderAt поверх объекта хранилища, чтобы его можно было отдавать через
+// http.ServeContent и разбирать парсерами без временного файла. Тело объекта для Read открывается лениво с текущей
+// позиции, а Seek только закрывает открытое тело: следующий Read откроет объект заново с нового смещения (для S3
+// это запрос с заголовком Range).
+type ObjectReader struct {
+	ctx   context.Context
+	store BlobStore
+	key   string
+	size  int64
+
+	pos  int64
+	body io.ReadCloser
+
+	mu          sync.Mutex
+	block       []byte
+	blockOffset int64
+}
+
+func NewObjectReader(ctx context.Context, store BlobStore, key string, size int64) *ObjectReader {
+	return &ObjectReader{
+		ctx:   ctx,
+		store: store,
+		key:   key,
+		size:  size,
+	}
+}
+
+func (r *ObjectReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+
+	if r.body == nil {
+		body, err := r.store.Get(r.ctx, r.key, r.pos, 0)
+		if err != nil {
+			return 0, err
+		}
+
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+
+	if errors.Is(err, io.EOF) && r.pos < r.size {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, err
+}
+
+func (r *ObjectReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.size + offset
+	default:
+		return 0, fmt.Errorf("seek object: invalid whence %d", whence)
+	}
+
+	if pos < 0 {
+		return 0, fmt.Errorf("seek object: %w", ErrInvalidRange)
+	}
+
+	if pos != r.pos {
+		if err := r.Close(); err != nil {
+			return 0, err
+		}
+
+		r.pos = pos
+	}
+
+	return pos, nil
+}
+
+// ReadAt читает независимо от позиции Read/Seek и безопасен для параллельного использования.
+func (r *ObjectReader) ReadAt(p []byte, offset int64) (int, error) {
+	if offset < 0 {
+		return 0, fmt.Errorf("read object: %w", ErrInvalidRange)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int
+
+	for n < len(p) {
+		pos := offset + int64(n)
+		if pos >= r.size {
+			return n, io.EOF
+		}
+
+		if r.block == nil || pos < r.blockOffset || pos >= r.blockOffset+int64(len(r.block)) {
+			if err := r.loadBlock(pos - pos%readAtBlockSize); err != nil {
+				return n, err
+			}
+		}
+
+		n += copy(p[n:], r.block[pos-r.blockOffset:])
+	}
+
+	return n, nil
+}
+
+func (r *ObjectReader) loadBlock(offset int64) error {
+	length := min(int64(readAtBlockSize), r.size-offset)
+
+	body, err := r.store.Get(r.ctx, r.key, offset, length)
+	if err != nil {
+		return err
+	}
+
+	defer body.Close()
+
+	block := make([]byte, length)
+	if _, err = io.ReadFull(body, block); err != nil {
+		return fmt.Errorf("read object block: %w", err)
+	}
+
+	r.block, r.blockOffset = block, offset
+
+	return nil
+}
+
+func (r *ObjectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+
+	err := r.body.Close()
+	r.body = nil
+
+	return err
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("object not found")
+	ErrInvalidKey   = errors.New("invalid object key")
+	ErrInvalidRange = errors.New("invalid object range")
+)
+
+const (
+	DriverFileSystem = "filesystem"
+	DriverS3         = "s3"
+)
+
+// Object описание объекта в хранилище без его содержимого.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string
+	ETag        string
+	ModifiedAt  time.Time
+}
+
+// BlobStore абстракция над объектным хранилищем. Ключи всегда разделяются символом "/" независимо от бэкенда.
+type BlobStore interface {
+	// Put записывает содержимое r под ключом key. Если size < 0, размер заранее неизвестен и бэкенд сам
+	// определяет его по мере чтения.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) (*Object, error)
+	// Get открывает объект на чтение, начиная с offset. Если length <= 0, читается всё до конца объекта.
+	Get(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Stat возвращает описание объекта либо ErrNotFound.
+	Stat(ctx context.Context, key string) (*Object, error)
+	// Delete удаляет объект. Удаление несуществующего объекта не считается ошибкой.
+	Delete(ctx context.Context, key string) error
+	// List возвращает все объекты, ключ которых начинается с prefix.
+	List(ctx context.Context, prefix string) ([]*Object, error)
+}
+
+type Configuration struct {
+	Driver string
+	// Path корневой каталог для DriverFileSystem.
+	Path string
+	S3   S3Configuration
+}
+
+type S3Configuration struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// UsePathStyle адресация вида endpoint/bucket/key вместо bucket.endpoint/key. Нужна для MinIO и локальных
+	// S3-совместимых заглушек.
+	UsePathStyle bool
+	Timeout      time.Duration
+}
+
+func New(configuration Configuration) (BlobStore, error) {
+	switch configuration.Driver {
+	case "", DriverFileSystem:
+		return NewFileSystem(configuration.Path)
+	case DriverS3:
+		return NewS3(configuration.S3)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %q", configuration.Driver)
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,140 @@
+package transcode
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+// externalStderrLimit сколько байт stderr кодировщика попадает в текст ошибки.
+const externalStderrLimit = 2048
+
+var errEncoderExited = errors.New("encoder exited before reading all input")
+
+// externalPreset как получить кодек внешним кодировщиком (ffmpeg).
+type externalPreset struct {
+	encoder string
+	muxer   string
+	// maxSampleRate и maxChannels ограничения кодека: исходник выше них понижается; 0 — без ограничений.
+	maxSampleRate int
+	maxChannels   int
+	// sampleRate фиксированная частота кодека (Opus работает только на 48 кГц); 0 — как у исходника.
+	sampleRate int
+}
+
+var externalPresets = map[domain.Codec]externalPreset{
+	domain.CodecMP3:    {encoder: "libmp3lame", muxer: "mp3", maxSampleRate: 48000, maxChannels: 2},
+	domain.CodecAAC:    {encoder: "aac", muxer: "adts", maxSampleRate: 96000},
+	domain.CodecVORBIS: {encoder: "libvorbis", muxer: "ogg"},
+	domain.CodecOPUS:   {encoder: "libopus", muxer: "ogg", sampleRate: 48000},
+}
+
+// ExternalTranscoder адаптер к внешнему кодировщику для lossy-копий. Декодированный исходник подаётся ffmpeg на
+// stdin в виде WAV (как в PCMTranscoder), результат читается из stdout — ни исходник, ни копия не попадают на диск.
+type ExternalTranscoder struct {
+	path string
+}
+
+// NewExternalTranscoder path — исполняемый файл ffmpeg (имя ищется в PATH).
+func NewExternalTranscoder(path string) *ExternalTranscoder {
+	return &ExternalTranscoder{path: path}
+}
+
+func (t *ExternalTranscoder) Supports(rendition domain.Rendition) bool {
+	_, ok := externalPresets[rendition.Codec]
+
+	return ok && codecFormats[rendition.Codec] == rendition.Format && rendition.Bitrate > 0
+}
+
+func (t *ExternalTranscoder) Transcode(ctx context.Context, decoder audio.PCMDecoder, rendition domain.Rendition, w io.Writer) (*Result, error) {
+	if !t.Supports(rendition) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, rendition)
+	}
+
+	preset := externalPresets[rendition.Codec]
+	sampleRate, channels := decoder.SampleRate(), decoder.Channels()
+
+	switch {
+	case preset.sampleRate > 0:
+		sampleRate = preset.sampleRate
+	case preset.maxSampleRate > 0 && sampleRate > preset.maxSampleRate:
+		sampleRate = preset.maxSampleRate
+	}
+
+	if preset.maxChannels > 0 {
+		channels = min(channels, preset.maxChannels)
+	}
+
+	args := []string{
+		"-hide_banner", "-nostdin", "-loglevel", "error",
+		"-f", "wav", "-i", "pipe:0",
+		"-vn", "-map_metadata", "-1",
+		"-c:a", preset.encoder,
+		"-b:a", strconv.Itoa(rendition.Bitrate) + "k",
+		"-ar", strconv.Itoa(sampleRate),
+		"-ac", strconv.Itoa(channels),
+		"-f", preset.muxer,
+		"pipe:1",
+	}
+
+	input, pipe := io.Pipe()
+	stderr := &bytes.Buffer{}
+
+	cmd := exec.CommandContext(ctx, t.path, args...)
+	cmd.Stdin = input
+	cmd.Stdout = w
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", t.path, err)
+	}
+
+	type decoded struct {
+		result *Result
+		err    error
+	}
+
+	done := make(chan decoded, 1)
+
+	go func() {
+		result, err := encodeWav(ctx, decoder, pipe)
+		pipe.CloseWithError(err)
+		done <- decoded{result: result, err: err}
+	}()
+
+	waitErr := cmd.Wait()
+	// Если кодировщик завершился, не дочитав вход, декодер иначе так и остался бы ждать на записи в pipe.
+	input.CloseWithError(errEncoderExited)
+
+	source := <-done
+
+	// Ошибка декодирования первична: из-за неё же Wait вернёт ошибку копирования stdin. Но если декодер
+	// остановился лишь потому, что кодировщик упал, интересна ошибка кодировщика.
+	if source.err != nil && (waitErr == nil || !errors.Is(source.err, errEncoderExited)) {
+		return nil, source.err
+	}
+
+	if waitErr != nil {
+		message := strings.TrimSpace(stderr.String())
+		if len(message) > externalStderrLimit {
+			message = message[len(message)-externalStderrLimit:]
+		}
+
+		return nil, fmt.Errorf("%s %s: %w: %s", t.path, rendition, waitErr, message)
+	}
+
+	return &Result{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Bitrate:    rendition.Bitrate,
+		Duration:   source.result.Duration,
+	}, nil
+}
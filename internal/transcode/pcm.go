@@ -0,0 +1,83 @@
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+// PCMTranscoder транскодер на чистом Go: пишет декодированный FLAC/WAV в WAV без потерь. Его кодировщик служит
+// и входом ExternalTranscoder.
+type PCMTranscoder struct{}
+
+func NewPCMTranscoder() *PCMTranscoder {
+	return &PCMTranscoder{}
+}
+
+func (t *PCMTranscoder) Supports(rendition domain.Rendition) bool {
+	return rendition.Format == domain.FormatWAV && rendition.Codec == domain.CodecWAV
+}
+
+func (t *PCMTranscoder) Transcode(ctx context.Context, decoder audio.PCMDecoder, rendition domain.Rendition, w io.Writer) (*Result, error) {
+	if !t.Supports(rendition) {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, rendition)
+	}
+
+	return encodeWav(ctx, decoder, w)
+}
+
+// encodeWav декодирует исходник до конца и пишет его в w как WAV той же частоты, числа каналов и разрядности.
+// Длина берётся из заголовка исходника, поэтому файлы без неё (FLAC с нулевым total samples) не поддерживаются.
+func encodeWav(ctx context.Context, decoder audio.PCMDecoder, w io.Writer) (*Result, error) {
+	total := decoder.TotalSamples()
+	if total == 0 {
+		return nil, fmt.Errorf("%w: source length is unknown", ErrUnsupported)
+	}
+
+	encoder, err := audio.NewWavEncoder(w, decoder.SampleRate(), decoder.Channels(), decoder.BitsPerSample(), total)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		frame, err := decoder.ReadFrame()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if err = encoder.Write(frame); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	// Ячейка сэмпла в WAV кратна байту, поэтому и битрейт считаем по ней, а не по значащим битам.
+	width := (decoder.BitsPerSample() + 7) / 8 * 8
+
+	return &Result{
+		SampleRate: decoder.SampleRate(),
+		Channels:   decoder.Channels(),
+		Bitrate:    decoder.SampleRate() * decoder.Channels() * width / 1000,
+		Duration:   samplesDuration(total, decoder.SampleRate()),
+	}, nil
+}
+
+func samplesDuration(samples uint64, sampleRate int) time.Duration {
+	return time.Duration(float64(samples) / float64(sampleRate) * float64(time.Second))
+}
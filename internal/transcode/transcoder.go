@@ -0,0 +1,127 @@
+package transcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/audio"
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+var ErrUnsupported = errors.New("unsupported rendition")
+
+// Result параметры получившейся копии, которые нужны для записи в track_files. Размер и контрольную сумму считает
+// вызывающий по тому, что было записано в w.
+type Result struct {
+	SampleRate int
+	Channels   int
+	// Bitrate битрейт копии в кбит/с: для lossy — заказанный, для PCM — фактический.
+	Bitrate  int
+	Duration time.Duration
+}
+
+// Transcoder получает из исходного файла копию в другом формате.
+type Transcoder interface {
+	// Supports сообщает, умеет ли транскодер получать rendition.
+	Supports(rendition domain.Rendition) bool
+	// Transcode декодирует исходник до конца и пишет rendition в w. Декодер открывает вызывающий: так транскодеру
+	// всё равно, целый это файл или трек внутри образа диска (см. audio.NewPCMRange).
+	Transcode(ctx context.Context, source audio.PCMDecoder, rendition domain.Rendition, w io.Writer) (*Result, error)
+}
+
+// Chain перебирает транскодеры по порядку и отдаёт rendition первому, который её поддерживает.
+type Chain []Transcoder
+
+func (c Chain) Supports(rendition domain.Rendition) bool {
+	return c.pick(rendition) != nil
+}
+
+func (c Chain) Transcode(ctx context.Context, source audio.PCMDecoder, rendition domain.Rendition, w io.Writer) (*Result, error) {
+	transcoder := c.pick(rendition)
+	if transcoder == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, rendition)
+	}
+
+	return transcoder.Transcode(ctx, source, rendition, w)
+}
+
+func (c Chain) pick(rendition domain.Rendition) Transcoder {
+	for _, transcoder := range c {
+		if transcoder.Supports(rendition) {
+			return transcoder
+		}
+	}
+
+	return nil
+}
+
+// ParseRenditions разбирает список копий вида "mp3:320,mp3:128,opus:96,wav". Первая часть — кодек, вторая —
+// битрейт в кбит/с (для PCM не указывается). Формат контейнера выводится из кодека.
+func ParseRenditions(s string) ([]domain.Rendition, error) {
+	var renditions []domain.Rendition
+
+	for item := range strings.SplitSeq(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		name, bitrate, _ := strings.Cut(item, ":")
+
+		codec := domain.Codec(strings.ToLower(name))
+
+		format, ok := codecFormats[codec]
+		if !ok {
+			return nil, fmt.Errorf("rendition %q: %w: codec %q", item, ErrUnsupported, name)
+		}
+
+		rendition := domain.Rendition{Format: format, Codec: codec}
+
+		if bitrate != "" {
+			kbps, err := strconv.Atoi(bitrate)
+			if err != nil || kbps <= 0 {
+				return nil, fmt.Errorf("rendition %q: invalid bitrate %q", item, bitrate)
+			}
+
+			rendition.Bitrate = kbps
+		}
+
+		if err := rendition.Validate(); err != nil {
+			return nil, fmt.Errorf("rendition %q: %w", item, err)
+		}
+
+		renditions = append(renditions, rendition)
+	}
+
+	return renditions, nil
+}
+
+// codecFormats в каком контейнере хранится копия с данным кодеком.
+var codecFormats = map[domain.Codec]domain.Format{
+	domain.CodecWAV:    domain.FormatWAV,
+	domain.CodecMP3:    domain.FormatMP3,
+	domain.CodecAAC:    domain.FormatAAC,
+	domain.CodecVORBIS: domain.FormatOGG,
+	domain.CodecOPUS:   domain.FormatOGG,
+}
+
+// FileType расширение имени файла и MIME-тип копии.
+func FileType(rendition domain.Rendition) (extension, mime string) {
+	switch rendition.Codec {
+	case domain.CodecMP3:
+		return ".mp3", audio.MimeMP3
+	case domain.CodecAAC:
+		return ".aac", "audio/aac"
+	case domain.CodecVORBIS:
+		return ".ogg", audio.MimeOGG
+	case domain.CodecOPUS:
+		return ".opus", audio.MimeOGG
+	default:
+		return ".wav", audio.MimeWAV
+	}
+}
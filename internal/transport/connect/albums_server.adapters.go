@@ -20,5 +20,11 @@ func toProtoAlbum(album *domain.Album) *protov1.Album {
 		ReleaseDate: utils.TimeToTimestamppb(album.ReleaseDate),
 		CreatedAt:   utils.TimeToTimestamppb(album.CreatedAt),
 		UpdatedAt:   utils.TimeToTimestamppb(album.UpdatedAt),
+
+		IntegratedLoudness:  album.IntegratedLoudness,
+		LoudnessRange:       album.LoudnessRange,
+		TruePeak:            album.TruePeak,
+		ReplaygainAlbumGain: album.ReplayGainAlbumGain,
+		ReplaygainAlbumPeak: album.ReplayGainAlbumPeak,
 	}
 }
@@ -21,7 +21,6 @@ func NewAlbumsServer(albumsService *service.AlbumsService) *AlbumsServer {
 
 func (s *AlbumsServer) CreateAlbum(ctx context.Context, request *connect.Request[protov1.CreateAlbumRequest]) (*connect.Response[protov1.CreateAlbumResponse], error) {
 	response, err := s.albumsService.CreateAlbum(ctx, domain.CreateAlbumRequest{
-		OwnerID:     utils.StringToUUIDPtr(request.Msg.OwnerId),
 		Title:       utils.Ptr(request.Msg.Title),
 		Description: utils.Ptr(request.Msg.Description),
 		ReleaseDate: utils.TimestamppbToTime(request.Msg.ReleaseDate),
@@ -6,7 +6,9 @@ import (
 	"connectrpc.com/connect"
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 
+	"github.com/untea/bottom_babruysk/internal/domain"
 	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
 )
 
 func toConnectErr(err error) error {
@@ -14,10 +16,23 @@ func toConnectErr(err error) error {
 		return nil
 	}
 
-	if errors.Is(err, postgres.ErrNotFound) {
+	if errors.Is(err, postgres.ErrNotFound) || errors.Is(err, storage.ErrNotFound) || errors.Is(err, domain.ErrNotFound) {
 		return connect.NewError(connect.CodeNotFound, err)
 	}
 
+	if errors.Is(err, domain.ErrEmailTaken) {
+		return connect.NewError(connect.CodeAlreadyExists, err)
+	}
+
+	if errors.Is(err, domain.ErrInvalidCredentials) || errors.Is(err, domain.ErrUnauthenticated) ||
+		errors.Is(err, domain.ErrInvalidToken) || errors.Is(err, domain.ErrRefreshTokenReused) {
+		return connect.NewError(connect.CodeUnauthenticated, err)
+	}
+
+	if errors.Is(err, domain.ErrPermissionDenied) {
+		return connect.NewError(connect.CodePermissionDenied, err)
+	}
+
 	var verr validation.Errors
 
 	if errors.As(err, &verr) {
@@ -21,7 +21,6 @@ func NewPlaylistsServer(playlistsService *service.PlaylistsService) *PlaylistsSe
 
 func (s *PlaylistsServer) CreatePlaylist(ctx context.Context, request *connect.Request[protov1.CreatePlaylistRequest]) (*connect.Response[protov1.CreatePlaylistResponse], error) {
 	response, err := s.playlistsService.CreatePlaylist(ctx, domain.CreatePlaylistRequest{
-		OwnerID:     utils.StringToUUIDPtr(request.Msg.OwnerId),
 		Title:       utils.Ptr(request.Msg.Title),
 		Description: utils.Ptr(request.Msg.Description),
 		Visibility:  FromProtoVisibility(request.Msg.Visibility),
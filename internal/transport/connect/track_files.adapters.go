@@ -12,22 +12,27 @@ func toProtoTrackFile(trackFile *domain.TrackFile) *protov1.TrackFile {
 	}
 
 	return &protov1.TrackFile{
-		Id:         trackFile.ID.String(),
-		TrackId:    trackFile.TrackID.String(),
-		Filename:   utils.ValueOrZero(trackFile.Filename),
-		S3Key:      trackFile.S3Key,
-		Mime:       utils.ValueOrZero(trackFile.Mime),
-		Format:     ToProtoFormat(trackFile.Format),
-		Codec:      ToProtoCodec(trackFile.Codec),
-		Bitrate:    utils.ValueOrZero(utils.IntToInt32(trackFile.Bitrate)),
-		SampleRate: utils.ValueOrZero(utils.IntToInt32(trackFile.SampleRate)),
-		Channels:   utils.ValueOrZero(utils.IntToInt32(trackFile.Channels)),
-		Size:       utils.ValueOrZero(trackFile.Size),
-		Duration:   utils.DurationToDurationpb(trackFile.Duration),
-		Checksum:   utils.ValueOrZero(trackFile.Checksum),
-		CreatedAt:  utils.TimeToTimestamppb(trackFile.CreatedAt),
-		UpdatedAt:  utils.TimeToTimestamppb(trackFile.UpdatedAt),
-		UploadedAt: utils.TimeToTimestamppb(trackFile.UploadedAt),
+		Id:             trackFile.ID.String(),
+		TrackId:        trackFile.TrackID.String(),
+		Filename:       utils.ValueOrZero(trackFile.Filename),
+		S3Key:          trackFile.S3Key,
+		Mime:           utils.ValueOrZero(trackFile.Mime),
+		Format:         ToProtoFormat(trackFile.Format),
+		Codec:          ToProtoCodec(trackFile.Codec),
+		Bitrate:        utils.ValueOrZero(utils.IntToInt32(trackFile.Bitrate)),
+		SampleRate:     utils.ValueOrZero(utils.IntToInt32(trackFile.SampleRate)),
+		Channels:       utils.ValueOrZero(utils.IntToInt32(trackFile.Channels)),
+		Size:           utils.ValueOrZero(trackFile.Size),
+		Duration:       utils.DurationToDurationpb(trackFile.Duration),
+		Checksum:       utils.ValueOrZero(trackFile.Checksum),
+		CreatedAt:      utils.TimeToTimestamppb(trackFile.CreatedAt),
+		UpdatedAt:      utils.TimeToTimestamppb(trackFile.UpdatedAt),
+		UploadedAt:     utils.TimeToTimestamppb(trackFile.UploadedAt),
+		Healthy:        trackFile.Healthy,
+		IntegrityError: trackFile.IntegrityError,
+		SourceId:       utils.PtrIfNonZero(utils.UUIDPtrToString(trackFile.SourceID)),
+		StartSample:    trackFile.StartSample,
+		EndSample:      trackFile.EndSample,
 	}
 }
 
@@ -53,6 +58,8 @@ func ToProtoFormat(format *domain.Format) protov1.Format {
 		return protov1.Format_FORMAT_WEBM
 	case domain.FormatAAC:
 		return protov1.Format_FORMAT_AAC
+	case domain.FormatAIFF:
+		return protov1.Format_FORMAT_AIFF
 	default:
 		return protov1.Format_FORMAT_UNSPECIFIED
 	}
@@ -84,6 +91,9 @@ func FromProtoFormat(format protov1.Format) *domain.Format {
 	case protov1.Format_FORMAT_AAC:
 		x := domain.FormatAAC
 		return &x
+	case protov1.Format_FORMAT_AIFF:
+		x := domain.FormatAIFF
+		return &x
 	default:
 		x := domain.FormatUnspecified
 		return &x
@@ -97,7 +107,7 @@ func ToProtoCodec(codec *domain.Codec) protov1.Codec {
 
 	switch *codec {
 	case domain.CodecWAV:
-		return protov1.Codec_CODEC_WMA
+		return protov1.Codec_CODEC_WAV
 	case domain.CodecFLAC:
 		return protov1.Codec_CODEC_FLAC
 	case domain.CodecALAC:
@@ -116,6 +126,8 @@ func ToProtoCodec(codec *domain.Codec) protov1.Codec {
 		return protov1.Codec_CODEC_VORBIS
 	case domain.CodecWMA:
 		return protov1.Codec_CODEC_WMA
+	case domain.CodecPCM:
+		return protov1.Codec_CODEC_PCM
 	default:
 		return protov1.Codec_CODEC_UNSPECIFIED
 	}
@@ -153,6 +165,9 @@ func FromProtoCodec(codec protov1.Codec) *domain.Codec {
 	case protov1.Codec_CODEC_WMA:
 		x := domain.CodecWMA
 		return &x
+	case protov1.Codec_CODEC_PCM:
+		x := domain.CodecPCM
+		return &x
 	default:
 		x := domain.CodecUnspecified
 		return &x
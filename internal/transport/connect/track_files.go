@@ -57,16 +57,13 @@ func (s *TrackFilesServer) UpdateTrackFile(ctx context.Context, request *connect
 		ID:         utils.StringToUUIDPtr(request.Msg.Id),
 		TrackID:    utils.StringToUUIDPtr(request.Msg.TrackId),
 		Filename:   request.Msg.Filename,
-		S3Key:      request.Msg.S3Key,
 		Mime:       request.Msg.Mime,
 		Format:     FromProtoFormat(*request.Msg.Format),
 		Codec:      FromProtoCodec(*request.Msg.Codec),
 		Bitrate:    utils.Int32ToInt(request.Msg.Bitrate),
 		SampleRate: utils.Int32ToInt(request.Msg.SampleRate),
 		Channels:   utils.Int32ToInt(request.Msg.Channels),
-		Size:       request.Msg.Size,
 		Duration:   utils.DurationpbToDuration(request.Msg.Duration),
-		Checksum:   request.Msg.Checksum,
 	})
 	if err != nil {
 		return nil, toConnectErr(err)
@@ -23,6 +23,12 @@ func toProtoTrack(track *domain.Track) *protov1.Track {
 		CreatedAt:   utils.TimeToTimestamppb(track.CreatedAt),
 		UpdatedAt:   utils.TimeToTimestamppb(track.UpdatedAt),
 		UploadedAt:  utils.TimeToTimestamppb(track.UploadedAt),
+
+		IntegratedLoudness:  track.IntegratedLoudness,
+		LoudnessRange:       track.LoudnessRange,
+		TruePeak:            track.TruePeak,
+		ReplaygainTrackGain: track.ReplayGainTrackGain,
+		ReplaygainTrackPeak: track.ReplayGainTrackPeak,
 	}
 }
 
@@ -24,7 +24,7 @@ func (s *TracksServer) GetTrack(ctx context.Context, request *connect.Request[pr
 		ID: utils.StringToUUIDPtr(request.Msg.Id),
 	})
 	if err != nil {
-		return nil, connect.NewError(connect.CodeNotFound, err)
+		return nil, toConnectErr(err)
 	}
 
 	return connect.NewResponse(&protov1.GetTrackResponse{Track: toProtoTrack(response.Track)}), nil
@@ -40,7 +40,7 @@ func (s *TracksServer) ListTracks(ctx context.Context, request *connect.Request[
 		SortOrder:  utils.Ptr(request.Msg.SortOrder),
 	})
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		return nil, toConnectErr(err)
 	}
 
 	result := &protov1.ListTracksResponse{Tracks: make([]*protov1.Track, 0, len(response.Tracks))}
@@ -61,7 +61,7 @@ func (s *TracksServer) UpdateTrack(ctx context.Context, request *connect.Request
 		Visibility:  FromProtoVisibility(*request.Msg.Visibility),
 	})
 	if err != nil {
-		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		return nil, toConnectErr(err)
 	}
 
 	return connect.NewResponse(&protov1.UpdateTrackResponse{}), nil
@@ -71,7 +71,7 @@ func (s *TracksServer) DeleteTrack(ctx context.Context, request *connect.Request
 	if err := s.tracksService.DeleteTrack(ctx, domain.DeleteTrackRequest{
 		ID: utils.StringToUUIDPtr(request.Msg.Id),
 	}); err != nil {
-		return nil, connect.NewError(connect.CodeNotFound, err)
+		return nil, toConnectErr(err)
 	}
 
 	return connect.NewResponse(&protov1.DeleteTrackResponse{}), nil
@@ -13,13 +13,12 @@ func toProtoUser(user *domain.User) *protov1.User {
 	}
 
 	return &protov1.User{
-		Id:           user.ID.String(),
-		Email:        utils.ValueOrZero(user.Email),
-		PasswordHash: utils.ValueOrZero(user.PasswordHash),
-		DisplayName:  utils.ValueOrZero(user.DisplayName),
-		Role:         ToProtoRole(user.Role),
-		CreatedAt:    utils.TimeToTimestamppb(user.CreatedAt),
-		UpdatedAt:    utils.TimeToTimestamppb(user.UpdatedAt),
+		Id:          user.ID.String(),
+		Email:       utils.ValueOrZero(user.Email),
+		DisplayName: utils.ValueOrZero(user.DisplayName),
+		Role:        ToProtoRole(user.Role),
+		CreatedAt:   utils.TimeToTimestamppb(user.CreatedAt),
+		UpdatedAt:   utils.TimeToTimestamppb(user.UpdatedAt),
 	}
 }
 
@@ -38,6 +37,16 @@ func ToProtoRole(role *domain.Role) protov1.Role {
 	}
 }
 
+// FromProtoOptionalRole роль из необязательного поля запроса на изменение: не переданная и ROLE_UNSPECIFIED значат
+// «не менять».
+func FromProtoOptionalRole(role *protov1.Role) *domain.Role {
+	if role == nil || *role == protov1.Role_ROLE_UNSPECIFIED {
+		return nil
+	}
+
+	return FromProtoRole(*role)
+}
+
 func FromProtoRole(role protov1.Role) *domain.Role {
 	switch role {
 	case protov1.Role_ROLE_USER:
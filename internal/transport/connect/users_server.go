@@ -21,10 +21,10 @@ func NewUsersServer(usersService *service.UsersService) *UsersServer {
 
 func (s *UsersServer) CreateUser(ctx context.Context, request *connect.Request[protov1.CreateUserRequest]) (*connect.Response[protov1.CreateUserResponse], error) {
 	response, err := s.usersService.CreateUser(ctx, domain.CreateUserRequest{
-		Email:        utils.Ptr(request.Msg.Email),
-		PasswordHash: utils.Ptr(request.Msg.PasswordHash),
-		DisplayName:  utils.Ptr(request.Msg.DisplayName),
-		Role:         FromProtoRole(request.Msg.Role),
+		Email:       utils.Ptr(request.Msg.Email),
+		Password:    utils.Ptr(request.Msg.Password),
+		DisplayName: utils.Ptr(request.Msg.DisplayName),
+		Role:        FromProtoRole(request.Msg.Role),
 	})
 	if err != nil {
 		return nil, toConnectErr(err)
@@ -71,7 +71,7 @@ func (s *UsersServer) UpdateUser(ctx context.Context, request *connect.Request[p
 	err := s.usersService.UpdateUser(ctx, domain.UpdateUserRequest{
 		ID:          utils.StringToUUIDPtr(request.Msg.Id),
 		DisplayName: request.Msg.DisplayName,
-		Role:        FromProtoRole(*request.Msg.Role),
+		Role:        FromProtoOptionalRole(request.Msg.Role),
 	})
 	if err != nil {
 		return nil, toConnectErr(err)
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/textproto"
 	"net/url"
 	"reflect"
 	"strconv"
@@ -18,16 +19,30 @@ import (
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 	"github.com/google/uuid"
 
+	"github.com/untea/bottom_babruysk/internal/domain"
 	"github.com/untea/bottom_babruysk/internal/repository/postgres"
+	"github.com/untea/bottom_babruysk/internal/storage"
 )
 
 type Empty struct{}
 
 func (h *Handler) toHTTPStatus(err error) int {
 	switch {
-	case errors.Is(err, postgres.ErrNotFound):
+	case errors.Is(err, postgres.ErrNotFound), errors.Is(err, storage.ErrNotFound), errors.Is(err, domain.ErrNotFound):
 		return http.StatusNotFound
-	case isValidationErr(err):
+	case errors.Is(err, domain.ErrNotAcceptable):
+		return http.StatusNotAcceptable
+	case errors.Is(err, storage.ErrInvalidRange):
+		return http.StatusRequestedRangeNotSatisfiable
+	case errors.Is(err, domain.ErrUploadOffsetMismatch), errors.Is(err, domain.ErrUploadNotResumable),
+		errors.Is(err, domain.ErrEmailTaken):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrInvalidCredentials), errors.Is(err, domain.ErrUnauthenticated),
+		errors.Is(err, domain.ErrInvalidToken), errors.Is(err, domain.ErrRefreshTokenReused):
+		return http.StatusUnauthorized
+	case errors.Is(err, domain.ErrPermissionDenied):
+		return http.StatusForbidden
+	case errors.Is(err, domain.ErrChecksumMismatch), isValidationErr(err):
 		return http.StatusBadRequest
 	default:
 		return http.StatusInternalServerError
@@ -110,6 +125,11 @@ func Decode[T any](r *http.Request) (T, error) {
 		}
 	}
 
+	err = fillFrom(reflect.ValueOf(&out).Elem(), url.Values(r.Header), headerName, "header")
+	if err != nil {
+		return out, fmt.Errorf("decode header: %w", err)
+	}
+
 	return out, nil
 }
 
@@ -199,6 +219,16 @@ func pathName(sf reflect.StructField) (string, bool) {
 	return "", false
 }
 
+// headerName в отличие от query и path, заголовки читаются только в поля с явным тегом header.
+func headerName(sf reflect.StructField) (string, bool) {
+	tag, ok, skip := pickTag(sf, "header")
+	if !ok || skip {
+		return "", true
+	}
+
+	return textproto.CanonicalMIMEHeaderKey(tag), false
+}
+
 func pickTag(sf reflect.StructField, key string) (name string, has bool, skip bool) {
 	tag := sf.Tag.Get(key)
 	if tag == "" {
@@ -304,6 +334,17 @@ func setValue(dst reflect.Value, str string) error { //nolint // TODO деком
 			return nil
 		}
 
+		under := dst.Type()
+		if under.Kind() == reflect.String {
+			dst.SetString(str)
+
+			return nil
+		}
+
+		return fmt.Errorf("unsupported struct type %str", dst.Type())
+
+	// uuid.UUID — массив [16]byte, а не структура.
+	case reflect.Array:
 		if dst.Type().PkgPath() == "github.com/google/uuid" && dst.Type().Name() == "UUID" {
 			if err := uuid.Validate(str); err != nil {
 				return err
@@ -315,14 +356,7 @@ func setValue(dst reflect.Value, str string) error { //nolint // TODO деком
 			return nil
 		}
 
-		under := dst.Type()
-		if under.Kind() == reflect.String {
-			dst.SetString(str)
-
-			return nil
-		}
-
-		return fmt.Errorf("unsupported struct type %str", dst.Type())
+		return fmt.Errorf("unsupported array type %s", dst.Type())
 
 	default:
 		under := dst.Type()
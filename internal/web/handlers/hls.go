@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+// HandleHLS как Handle, но отдаёт плейлист или сегмент как есть, с его Content-Type. Ответы неизменны, пока не
+// изменились файлы трека, поэтому их можно недолго кэшировать.
+func HandleHLS[R any](h *Handler, action func(ctx context.Context, request R) (*domain.GetHLSResponse, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		request, err := Decode[R](r)
+		if err != nil {
+			h.httpError(w, err, http.StatusBadRequest)
+			return
+		}
+
+		response, err := action(r.Context(), request)
+		if err != nil {
+			h.httpError(w, err, h.toHTTPStatus(err))
+			return
+		}
+
+		header := w.Header()
+		header.Set("Content-Type", response.ContentType)
+		header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+		header.Set("Cache-Control", "private, max-age=60")
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(response.Body)
+	}
+}
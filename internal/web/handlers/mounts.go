@@ -2,6 +2,17 @@ package handlers
 
 import "github.com/go-chi/chi/v5"
 
+func (h *Handler) MountAuth(r chi.Router) {
+	r.Route("/auth", func(r chi.Router) {
+		r.Post("/register", Handle(h, h.Services.AuthService.Register))
+		r.Post("/login", Handle(h, h.Services.AuthService.Login))
+		r.Post("/refresh", Handle(h, h.Services.AuthService.Refresh))
+		r.Post("/logout", Handle(h, Lift(h.Services.AuthService.Logout)))
+		r.Get("/sessions", Handle(h, h.Services.AuthService.ListSessions))
+		r.Delete("/sessions/{id}", Handle(h, Lift(h.Services.AuthService.RevokeSession)))
+	})
+}
+
 func (h *Handler) MountUsers(r chi.Router) {
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/", Handle(h, h.Services.UsersServices.CreateUser))
@@ -26,6 +37,14 @@ func (h *Handler) MountTracks(r chi.Router) {
 	r.Route("/tracks", func(r chi.Router) {
 		r.Get("/", Handle(h, h.Services.TacksServices.ListTracks))
 		r.Get("/{id}", Handle(h, h.Services.TacksServices.GetTrack))
+		r.Get("/{id}/stream", h.StreamTrack)
+		r.Get("/{id}/waveform", Handle(h, h.Services.WaveformsService.GetWaveform))
+		r.Get("/{id}/duplicates", Handle(h, h.Services.FingerprintsService.ListDuplicates))
+		r.Route("/{id}/hls", func(r chi.Router) {
+			r.Get("/master.m3u8", HandleHLS(h, h.Services.HLSService.GetMasterPlaylist))
+			r.Get("/{file_id}/playlist.m3u8", HandleHLS(h, h.Services.HLSService.GetMediaPlaylist))
+			r.Get("/{file_id}/{segment:[0-9]+}.ts", HandleHLS(h, h.Services.HLSService.GetSegment))
+		})
 		r.Patch("/{id}", Handle(h, Lift(h.Services.TacksServices.UpdateTrack)))
 		r.Delete("/{id}", Handle(h, Lift(h.Services.TacksServices.DeleteTrack)))
 	})
@@ -53,9 +72,41 @@ func (h *Handler) MountArtists(r chi.Router) {
 
 func (h *Handler) MountTrackFiles(r chi.Router) {
 	r.Route("/trackfiles", func(r chi.Router) {
+		r.Post("/", h.UploadTrackFile)
 		r.Get("/", Handle(h, h.Services.TrackFilesService.ListTrackFiles))
 		r.Get("/{id}", Handle(h, h.Services.TrackFilesService.GetTrackFile))
 		r.Patch("/{id}", Handle(h, Lift(h.Services.TrackFilesService.UpdateTrackFile)))
 		r.Delete("/{id}", Handle(h, Lift(h.Services.TrackFilesService.DeleteTrackFile)))
 	})
 }
+
+func (h *Handler) MountUploads(r chi.Router) {
+	r.Route("/uploads", func(r chi.Router) {
+		r.Post("/", h.CreateUpload)
+		r.Get("/", Handle(h, h.Services.UploadsService.ListUploads))
+		r.Route("/tus", func(r chi.Router) {
+			r.Use(TusResumable)
+			r.Options("/", h.TusOptions)
+			r.Post("/", h.TusCreateUpload)
+			r.Head("/{id}", h.TusUploadOffset)
+			r.Patch("/{id}", h.TusAppendChunk)
+			r.Delete("/{id}", h.TusTerminateUpload)
+		})
+		r.Get("/{id}", Handle(h, h.Services.UploadsService.GetUpload))
+	})
+}
+
+// MountBlobs проверка по SHA-256, есть ли уже содержимое у вызывающего: HEAD отвечает 200 или 404 без тела.
+func (h *Handler) MountBlobs(r chi.Router) {
+	r.Route("/blobs", func(r chi.Router) {
+		r.Get("/{checksum}", Handle(h, h.Services.BlobsService.GetBlob))
+		r.Head("/{checksum}", Handle(h, h.Services.BlobsService.GetBlob))
+	})
+}
+
+// MountAdmin административные операции над каталогом.
+func (h *Handler) MountAdmin(r chi.Router) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Post("/tracks/{id}/merge", Handle(h, h.Services.FingerprintsService.MergeTracks))
+	})
+}
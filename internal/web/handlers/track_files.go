@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// UploadTrackFile принимает содержимое файла трека в теле запроса как есть, а метаданные (track_id, filename,
+// format, codec и т.д.) в query-параметрах. Если mime не передан, берётся из Content-Type. Запрос с пустым телом и
+// checksum (SHA-256) создаёт файл из уже сохранённого содержимого.
+func (h *Handler) UploadTrackFile(w http.ResponseWriter, r *http.Request) {
+	var request domain.UploadTrackFileRequest
+
+	err := fillFrom(reflect.ValueOf(&request).Elem(), r.URL.Query(), queryName, "query")
+	if err != nil {
+		h.httpError(w, fmt.Errorf("decode query: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	if request.Mime == nil && r.Header.Get("Content-Type") != "" {
+		request.Mime = utils.Ptr(r.Header.Get("Content-Type"))
+	}
+
+	if r.ContentLength != 0 {
+		request.Body = r.Body
+	}
+
+	extendUploadDeadlines(w)
+
+	response, err := h.Services.TrackFilesService.UploadTrackFile(context.WithoutCancel(r.Context()), request)
+	if err != nil {
+		h.httpError(w, err, h.toHTTPStatus(err))
+		return
+	}
+
+	h.writeJson(w, response, http.StatusCreated)
+}
+
+// streamWriteTimeout ограничивает отдачу одного ответа /stream. Общий WriteTimeout сервера рассчитан на JSON и
+// оборвал бы проигрывание длинного трека на медленном соединении.
+const streamWriteTimeout = 30 * time.Minute
+
+// StreamTrack отдаёт содержимое файла трека с поддержкой Range/206, ETag (по checksum) и If-Range, чтобы
+// HTML-плеер мог перематывать. Сами заголовки Range разбирает http.ServeContent.
+func (h *Handler) StreamTrack(w http.ResponseWriter, r *http.Request) {
+	request, err := Decode[domain.StreamTrackRequest](r)
+	if err != nil {
+		h.httpError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	request.Accept = r.Header.Get("Accept")
+
+	// Контекст запроса отменяется таймаутом роутера, а отдача может идти дольше; обрыв соединения всё равно
+	// прервёт ServeContent на записи.
+	body, trackFile, err := h.Services.TrackFilesService.StreamTrack(context.WithoutCancel(r.Context()), request)
+	if err != nil {
+		h.httpError(w, err, h.toHTTPStatus(err))
+		return
+	}
+
+	defer body.Close()
+
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+
+	header := w.Header()
+	header.Set("Vary", "Accept")
+	header.Set("Content-Type", utils.ValueOrZero(trackFile.Mime))
+
+	if trackFile.Checksum != nil {
+		header.Set("ETag", `"`+*trackFile.Checksum+`"`)
+	}
+
+	modifiedAt := utils.ValueOrZero(trackFile.UploadedAt)
+	if trackFile.UpdatedAt != nil && trackFile.UpdatedAt.After(modifiedAt) {
+		modifiedAt = *trackFile.UpdatedAt
+	}
+
+	http.ServeContent(w, r, "", modifiedAt, body)
+}
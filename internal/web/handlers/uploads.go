@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+const (
+	uploadFormField = "file"
+	// uploadBodyTimeout ограничивает приём тела одного запроса с файлом. Общие ReadTimeout и WriteTimeout
+	// сервера рассчитаны на JSON и оборвали бы большой файл; ещё большие файлы стоит передавать через tus.
+	uploadBodyTimeout = 30 * time.Minute
+)
+
+// CreateUpload принимает аудиофайл в поле "file" формы multipart/form-data. Файл читается потоком, не буферизуясь
+// в памяти, но должен прийти за uploadBodyTimeout: файлы, которые не успеют, передаются через tus. Владельцем
+// загрузки становится вызывающий пользователь. Запрос с пустым телом и query-параметром checksum (SHA-256) создаёт
+// загрузку из уже сохранённого содержимого, а если его нет, получает 404.
+func (h *Handler) CreateUpload(w http.ResponseWriter, r *http.Request) {
+	var request domain.CreateUploadRequest
+
+	extendUploadDeadlines(w)
+
+	err := fillFrom(reflect.ValueOf(&request).Elem(), r.URL.Query(), queryName, "query")
+	if err != nil {
+		h.httpError(w, fmt.Errorf("decode query: %w", err), http.StatusBadRequest)
+		return
+	}
+
+	if request.Checksum != nil && r.ContentLength == 0 {
+		if request.Mime == nil {
+			request.Mime = utils.Ptr(partMime("", utils.ValueOrZero(request.Filename)))
+		}
+
+		h.createUpload(w, r, request)
+		return
+	}
+
+	part, err := uploadPart(r)
+	if err != nil {
+		h.httpError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	defer part.Close()
+
+	if request.Filename == nil && part.FileName() != "" {
+		request.Filename = utils.Ptr(part.FileName())
+	}
+
+	if request.Mime == nil {
+		request.Mime = utils.Ptr(partMime(part.Header.Get("Content-Type"), utils.ValueOrZero(request.Filename)))
+	}
+
+	request.Body = part
+
+	h.createUpload(w, r, request)
+}
+
+func (h *Handler) createUpload(w http.ResponseWriter, r *http.Request, request domain.CreateUploadRequest) {
+	// Контекст запроса отменяется таймаутом роутера, а приём файла может идти дольше; обрыв соединения всё равно
+	// прервёт чтение тела.
+	response, err := h.Services.UploadsService.CreateUpload(context.WithoutCancel(r.Context()), request)
+	if err != nil {
+		h.httpError(w, err, h.toHTTPStatus(err))
+		return
+	}
+
+	h.writeJson(w, response, http.StatusAccepted)
+}
+
+// extendUploadDeadlines продлевает сроки соединения на время приёма файла.
+func extendUploadDeadlines(w http.ResponseWriter) {
+	controller := http.NewResponseController(w)
+	_ = controller.SetReadDeadline(time.Now().Add(uploadBodyTimeout))
+	_ = controller.SetWriteDeadline(time.Now().Add(uploadBodyTimeout))
+}
+
+type multipartFile struct {
+	io.ReadCloser
+	fileName string
+	Header   http.Header
+}
+
+func (f multipartFile) FileName() string {
+	return f.fileName
+}
+
+func uploadPart(r *http.Request) (*multipartFile, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("read multipart body: %w", err)
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("multipart field %q is missing", uploadFormField)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("read multipart body: %w", err)
+		}
+
+		if part.FormName() != uploadFormField {
+			_ = part.Close()
+			continue
+		}
+
+		return &multipartFile{
+			ReadCloser: part,
+			fileName:   part.FileName(),
+			Header:     http.Header(part.Header),
+		}, nil
+	}
+}
+
+func partMime(contentType, filename string) string {
+	if contentType != "" && !strings.HasPrefix(contentType, "application/octet-stream") {
+		return contentType
+	}
+
+	if byExt := mime.TypeByExtension(strings.ToLower(path.Ext(filename))); byExt != "" {
+		return byExt
+	}
+
+	return "application/octet-stream"
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+
+	"github.com/untea/bottom_babruysk/internal/domain"
+	"github.com/untea/bottom_babruysk/utils"
+)
+
+// Реализация протокола возобновляемых загрузок tus 1.0.0 (https://tus.io/protocols/resumable-upload) с
+// расширениями creation и termination. Состояние загрузки хранится в таблице uploads, а завершённая загрузка
+// попадает в ту же очередь обработки, что и POST /uploads.
+
+const (
+	tusVersion      = "1.0.0"
+	tusExtensions   = "creation,termination"
+	tusContentType  = "application/offset+octet-stream"
+	tusChunkTimeout = 30 * time.Minute
+)
+
+// TusResumable проверяет заголовок Tus-Resumable у всех запросов, кроме OPTIONS, и проставляет его в ответы.
+func TusResumable(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			w.WriteHeader(http.StatusPreconditionFailed)
+
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) TusOptions(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", tusExtensions)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusCreateUpload расширение creation: Upload-Length обязателен, имя и тип файла берутся из Upload-Metadata
+// (ключи filename/name и filetype/type).
+func (h *Handler) TusCreateUpload(w http.ResponseWriter, r *http.Request) {
+	size, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		h.httpError(w, errors.New("invalid Upload-Length header"), http.StatusBadRequest)
+		return
+	}
+
+	metadata, err := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		h.httpError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	filename := firstNonEmpty(metadata["filename"], metadata["name"])
+	mime := partMime(firstNonEmpty(metadata["filetype"], metadata["type"]), filename)
+
+	response, err := h.Services.UploadsService.CreateResumableUpload(r.Context(), domain.CreateResumableUploadRequest{
+		Filename: utils.PtrIfNonZero(filename),
+		Mime:     utils.Ptr(mime),
+		Size:     utils.Ptr(size),
+	})
+	if err != nil {
+		h.httpError(w, err, h.toHTTPStatus(err))
+		return
+	}
+
+	w.Header().Set("Location", strings.TrimRight(r.URL.Path, "/")+"/"+response.ID.String())
+	w.Header().Set("Upload-Offset", "0")
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) TusUploadOffset(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	response, err := h.Services.UploadsService.GetUpload(r.Context(), domain.GetUploadRequest{ID: &id})
+	if err != nil {
+		w.WriteHeader(h.toHTTPStatus(err))
+		return
+	}
+
+	upload := response.Upload
+
+	// Загрузка, уже переданная целиком, отдаёт смещение, равное размеру, чтобы клиент не начинал её заново.
+	offset := utils.ValueOrZero(upload.Offset)
+	if utils.ValueOrZero(upload.Status) != domain.UploadStatusUploading {
+		offset = utils.ValueOrZero(upload.Size)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(utils.ValueOrZero(upload.Size), 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) TusAppendChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != tusContentType {
+		h.httpError(w, fmt.Errorf("content type must be %s", tusContentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil {
+		h.httpError(w, errors.New("invalid Upload-Offset header"), http.StatusBadRequest)
+		return
+	}
+
+	// Часть может идти дольше общих ReadTimeout и WriteTimeout сервера и таймаута роутера. Сроки соединения
+	// продлеваются, иначе ответ с новым Upload-Offset потерялся бы уже после того, как байты сохранены. Таймаут
+	// роутера отменяет только контекст запроса, а принятые байты должны быть зафиксированы, даже если клиент
+	// отвалился, поэтому сервис получает контекст без отмены.
+	controller := http.NewResponseController(w)
+	_ = controller.SetReadDeadline(time.Now().Add(tusChunkTimeout))
+	_ = controller.SetWriteDeadline(time.Now().Add(tusChunkTimeout))
+
+	response, err := h.Services.UploadsService.AppendUploadChunk(context.WithoutCancel(r.Context()), domain.AppendUploadChunkRequest{
+		ID:     &id,
+		Offset: &offset,
+		Body:   r.Body,
+	})
+	if err != nil {
+		h.httpError(w, err, h.toHTTPStatus(err))
+		return
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(response.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// TusTerminateUpload расширение termination.
+func (h *Handler) TusTerminateUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = h.Services.UploadsService.TerminateUpload(r.Context(), domain.DeleteUploadRequest{ID: &id})
+	if err != nil {
+		h.httpError(w, err, h.toHTTPStatus(err))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseTusMetadata разбирает Upload-Metadata: пары "ключ base64(значение)", разделённые запятыми.
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+
+		value, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Upload-Metadata value for %q: %w", key, err)
+		}
+
+		metadata[key] = string(value)
+	}
+
+	return metadata, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
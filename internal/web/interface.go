@@ -2,6 +2,10 @@ package web
 
 import "github.com/go-chi/chi/v5"
 
+type AuthHTTP interface {
+	MountAuth(r chi.Router)
+}
+
 type UsersHTTP interface {
 	MountUsers(r chi.Router)
 }
@@ -26,11 +30,27 @@ type TrackFilesHTTP interface {
 	MountTrackFiles(r chi.Router)
 }
 
+type UploadsHTTP interface {
+	MountUploads(r chi.Router)
+}
+
+type BlobsHTTP interface {
+	MountBlobs(r chi.Router)
+}
+
+type AdminHTTP interface {
+	MountAdmin(r chi.Router)
+}
+
 type HandlerHTTP interface {
+	AuthHTTP
 	UsersHTTP
 	AlbumsHTTP
 	TracksHTTP
 	PlaylistsHTTP
 	ArtistsHTTP
 	TrackFilesHTTP
+	UploadsHTTP
+	BlobsHTTP
+	AdminHTTP
 }
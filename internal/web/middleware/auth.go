@@ -0,0 +1,72 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+
+	"github.com/untea/bottom_babruysk/internal/auth"
+	"github.com/untea/bottom_babruysk/internal/domain"
+)
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*domain.Principal, error)
+}
+
+// Authenticate проверяет access-токен из заголовка Authorization: Bearer и кладёт principal в контекст запроса,
+// откуда его берут сервисы: и через REST-хендлеры, и через Connect. Запрос без заголовка проходит анонимным, а с
+// недействительным токеном отклоняется с 401, чтобы клиент знал, что пора обменять refresh-токен. На anonymousPaths
+// (вход, регистрация, обмен refresh-токена) заголовок не смотрится вовсе: клиент, по привычке приложивший
+// истёкший токен, иначе не смог бы его обновить.
+func Authenticate(authenticator Authenticator, anonymousPaths ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" || slices.Contains(anonymousPaths, strings.TrimSuffix(r.URL.Path, "/")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scheme, token, ok := strings.Cut(header, " ")
+			if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+				unauthorized(w, domain.ErrInvalidToken)
+				return
+			}
+
+			principal, err := authenticator.Authenticate(r.Context(), token)
+			if errors.Is(err, domain.ErrInvalidToken) {
+				unauthorized(w, err)
+				return
+			}
+
+			if err != nil {
+				ctxzap.Extract(r.Context()).Error("failed to authenticate request", zap.Error(err))
+				writeError(w, errors.New("internal error"), http.StatusInternalServerError)
+
+				return
+			}
+
+			ctxzap.AddFields(r.Context(), zap.Any("user id", principal.UserID))
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, err error) {
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	writeError(w, err, http.StatusUnauthorized)
+}
+
+func writeError(w http.ResponseWriter, err error, code int) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+
+	_ = json.NewEncoder(w).Encode(domain.ErrorResponse{Error: err.Error()})
+}
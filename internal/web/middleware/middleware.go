@@ -40,6 +40,12 @@ func (w *statusRW) Flush() {
 	}
 }
 
+// Unwrap нужен http.ResponseController, чтобы добраться до исходного ResponseWriter (например, для продления
+// дедлайнов чтения на долгих загрузках).
+func (w *statusRW) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // RequestLogger кладёт request-scoped zap.Logger в контекст + логирует запрос/ответ.
 func RequestLogger(baseLogger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -31,10 +31,16 @@ func New(dependencies Dependencies) *chi.Mux {
 
 	if dependencies.EnableCORS {
 		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins:   []string{"https://*", "http://*"},
-			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-			ExposedHeaders:   []string{"Link"},
+			AllowedOrigins: []string{"https://*", "http://*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+			AllowedHeaders: []string{
+				"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "Range", "If-Range",
+				"Tus-Resumable", "Upload-Length", "Upload-Offset", "Upload-Metadata",
+			},
+			ExposedHeaders: []string{
+				"Link", "Location", "ETag", "Accept-Ranges", "Content-Range",
+				"Tus-Resumable", "Tus-Version", "Tus-Extension", "Upload-Length", "Upload-Offset",
+			},
 			AllowCredentials: true,
 			MaxAge:           300,
 		}))
@@ -46,6 +52,11 @@ func New(dependencies Dependencies) *chi.Mux {
 		chiMiddleware.Recoverer,
 		chiMiddleware.Timeout(60*time.Second),
 		webMiddleware.RequestLogger(dependencies.Logger),
+		webMiddleware.Authenticate(dependencies.Services.AuthService,
+			"/api/v1/auth/register",
+			"/api/v1/auth/login",
+			"/api/v1/auth/refresh",
+		),
 	)
 
 	r.Get("/healthz", func(w http.ResponseWriter, _ *http.Request) {
@@ -54,12 +65,16 @@ func New(dependencies Dependencies) *chi.Mux {
 
 	// REST
 	r.Route("/api/v1", func(api chi.Router) {
+		dependencies.Handlers.MountAuth(api)
 		dependencies.Handlers.MountUsers(api)
 		dependencies.Handlers.MountAlbums(api)
 		dependencies.Handlers.MountTracks(api)
 		dependencies.Handlers.MountPlaylists(api)
 		dependencies.Handlers.MountArtists(api)
 		dependencies.Handlers.MountTrackFiles(api)
+		dependencies.Handlers.MountUploads(api)
+		dependencies.Handlers.MountBlobs(api)
+		dependencies.Handlers.MountAdmin(api)
 	})
 
 	// CONNECT RPC
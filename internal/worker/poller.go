@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// defaultInterval пауза между опросами, если Interval не задан.
+const defaultInterval = 10 * time.Second
+
+// Poller фоновый обработчик очереди работы в БД. Пока работа есть, Process вызывается подряд без пауз; когда она
+// закончилась или Process вернул ошибку, воркер засыпает на Interval.
+type Poller struct {
+	// Name имя воркера в логах.
+	Name string
+	// Interval пауза между опросами, когда работы нет.
+	Interval time.Duration
+	// Process берёт и выполняет одну единицу работы. Возвращает false, если работы нет. Прерванную отменой ctx
+	// работу он должен оставить так, чтобы её взяли повторно.
+	Process func(ctx context.Context) (bool, error)
+}
+
+// Run блокируется до отмены ctx.
+func (p Poller) Run(ctx context.Context, logger *zap.Logger) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+
+	logger = logger.With(zap.String("worker", p.Name))
+	logger.Info("worker start")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("worker stop")
+			return
+		case <-timer.C:
+		}
+
+		processed, err := p.Process(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Error("failed to process", zap.Error(err))
+		}
+
+		if processed && err == nil {
+			timer.Reset(0)
+			continue
+		}
+
+		timer.Reset(interval)
+	}
+}
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+alter table uploads
+    add column error text default null;
+
+comment on column uploads.error is 'Причина ошибки обработки загрузки (заполняется при статусе failed).';
+
+alter table uploads
+    add column track_id uuid default null references tracks (id) on delete set null;
+
+comment on column uploads.track_id is 'Трек, созданный в результате обработки загрузки.';
+
+create index uploads_status_created_at_idx on uploads (status, created_at);
+comment on index uploads_status_created_at_idx is 'Индекс для выборки очереди загрузок, ожидающих обработки.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop index uploads_status_created_at_idx;
+
+alter table uploads
+    drop column track_id;
+
+alter table uploads
+    drop column error;
+
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+alter type upload_status add value if not exists 'uploading' before 'pending';
+
+comment on type upload_status is 'Тип статуса загрузки: uploading, pending, processing, done, failed';
+
+alter table uploads
+    add column upload_offset bigint default 0 not null;
+
+comment on column uploads.upload_offset is 'Количество байт, уже принятых сервером для возобновляемой (tus) загрузки.';
+
+-- +goose Down
+alter table uploads
+    drop column upload_offset;
+
+comment on type upload_status is 'Тип статуса загрузки: pending, processing, done, failed';
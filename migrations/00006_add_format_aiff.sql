@@ -0,0 +1,10 @@
+-- +goose NO TRANSACTION
+
+-- +goose Up
+alter type format add value if not exists 'aiff';
+
+comment on type format is 'Идентификатор формата файла (.mp3, .mp4, .aiff)';
+
+-- +goose Down
+-- Значение из enum в Postgres удалить нельзя, поэтому откат оставляет 'aiff' на месте.
+comment on type format is 'Идентификатор формата файла (.mp3, .mp4)';
@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+
+alter table track_files
+    add column healthy boolean default null;
+
+comment on column track_files.healthy is 'Результат проверки целостности файла при загрузке (для FLAC — CRC фреймов и MD5 PCM из STREAMINFO). NULL, если формат не проверяется.';
+
+alter table track_files
+    add column integrity_error text default null;
+
+comment on column track_files.integrity_error is 'Причина, по которой файл признан повреждённым (заполняется при healthy = false).';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+alter table track_files
+    drop column integrity_error;
+
+alter table track_files
+    drop column healthy;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+create table track_file_waveforms
+(
+    track_file_id uuid primary key references track_files (id) on delete cascade,
+    peaks         bytea       default null,
+    error         text        default null,
+    created_at    timestamptz default now() not null,
+    updated_at    timestamptz default now() not null
+);
+
+comment on table track_file_waveforms is 'Обзоры формы волны файлов треков для плеера. Строка без peaks и error — расчёт ещё идёт.';
+
+comment on column track_file_waveforms.track_file_id is 'Файл трека, по которому посчитаны пики.';
+comment on column track_file_waveforms.peaks is 'Пики min/max на нескольких уровнях детализации в двоичном формате BBWF (см. audio.Waveform).';
+comment on column track_file_waveforms.error is 'Причина, по которой посчитать пики не удалось.';
+comment on column track_file_waveforms.created_at is 'Время, когда файл впервые взят в расчёт.';
+comment on column track_file_waveforms.updated_at is 'Время последнего взятия в расчёт или его завершения.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table track_file_waveforms;
+
+-- +goose StatementEnd
@@ -0,0 +1,58 @@
+-- +goose Up
+-- +goose StatementBegin
+
+create table track_file_loudness
+(
+    track_file_id         uuid primary key references track_files (id) on delete cascade,
+    integrated_loudness   double precision default null,
+    loudness_range        double precision default null,
+    true_peak             double precision default null,
+    replaygain_track_gain double precision default null,
+    replaygain_track_peak double precision default null,
+    histogram             bytea            default null,
+    error                 text             default null,
+    created_at            timestamptz      default now() not null,
+    updated_at            timestamptz      default now() not null
+);
+
+comment on table track_file_loudness is 'Громкость файлов треков по ITU-R BS.1770 / EBU R128 и ReplayGain. Строка без histogram и error — анализ ещё идёт.';
+
+comment on column track_file_loudness.track_file_id is 'Проанализированный файл трека.';
+comment on column track_file_loudness.integrated_loudness is 'Интегральная громкость, LUFS. NULL, если файл тише абсолютного порога -70 LUFS.';
+comment on column track_file_loudness.loudness_range is 'Диапазон громкости (LRA), LU.';
+comment on column track_file_loudness.true_peak is 'Истинный пик, dBTP. NULL для полной тишины.';
+comment on column track_file_loudness.replaygain_track_gain is 'Усиление ReplayGain 2.0 до -18 LUFS, дБ.';
+comment on column track_file_loudness.replaygain_track_peak is 'Пик для ReplayGain в долях полной шкалы (истинный пик).';
+comment on column track_file_loudness.histogram is 'Гистограммы громкости 400-мс и 3-с блоков в двоичном формате BBLH (см. audio.LoudnessHistogram); по ним считается громкость альбома.';
+comment on column track_file_loudness.error is 'Причина, по которой проанализировать файл не удалось.';
+comment on column track_file_loudness.created_at is 'Время, когда файл впервые взят в анализ.';
+comment on column track_file_loudness.updated_at is 'Время последнего взятия в анализ или его завершения.';
+
+alter table albums
+    add column integrated_loudness double precision default null,
+    add column loudness_range double precision default null,
+    add column true_peak double precision default null,
+    add column replaygain_album_gain double precision default null,
+    add column replaygain_album_peak double precision default null;
+
+comment on column albums.integrated_loudness is 'Интегральная громкость альбома по всем проанализированным трекам, LUFS.';
+comment on column albums.loudness_range is 'Диапазон громкости альбома (LRA), LU.';
+comment on column albums.true_peak is 'Наибольший истинный пик среди треков альбома, dBTP.';
+comment on column albums.replaygain_album_gain is 'Усиление ReplayGain 2.0 для альбома до -18 LUFS, дБ.';
+comment on column albums.replaygain_album_peak is 'Пик альбома для ReplayGain в долях полной шкалы.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+alter table albums
+    drop column replaygain_album_peak,
+    drop column replaygain_album_gain,
+    drop column true_peak,
+    drop column loudness_range,
+    drop column integrated_loudness;
+
+drop table track_file_loudness;
+
+-- +goose StatementEnd
@@ -0,0 +1,65 @@
+-- +goose Up
+-- +goose StatementBegin
+
+alter table track_files
+    add column source_id uuid default null references track_files (id) on delete cascade;
+
+comment on column track_files.source_id is 'Оригинал, из которого сервер транскодировал этот файл. NULL у загруженных файлов.';
+
+-- Один оригинал на формат остаётся правилом, а производных копий одного формата может быть несколько (128 и 320
+-- кбит/с), поэтому они ограничены отдельным индексом.
+drop index track_files_track_format_unique_index;
+
+create unique index track_files_track_format_unique_index on track_files (track_id, format) where source_id is null;
+comment on index track_files_track_format_unique_index is 'Уникальный индекс для таблицы track_files по полям (track_id, format) среди загруженных файлов, гарантирующий, что у трека нет двух оригиналов одинакового формата.';
+
+create unique index track_files_rendition_unique_index on track_files (track_id, format, codec, bitrate) where source_id is not null;
+comment on index track_files_rendition_unique_index is 'Уникальный индекс производных копий: у трека не больше одной копии с данными форматом, кодеком и битрейтом.';
+
+create index track_files_source_id_idx on track_files (source_id) where source_id is not null;
+comment on index track_files_source_id_idx is 'Индекс для поиска копий оригинала (и каскадного удаления вместе с ним).';
+
+create table track_file_transcodes
+(
+    source_id     uuid        not null references track_files (id) on delete cascade,
+    format        format      not null,
+    codec         codec       not null,
+    bitrate       integer     not null,
+    track_file_id uuid        default null references track_files (id) on delete cascade,
+    error         text        default null,
+    created_at    timestamptz default now() not null,
+    updated_at    timestamptz default now() not null,
+    primary key (source_id, format, codec, bitrate)
+);
+
+comment on table track_file_transcodes is 'Задания на транскодирование оригиналов в настроенные копии. Строка без track_file_id и error — транскодирование ещё идёт.';
+
+comment on column track_file_transcodes.source_id is 'Оригинал (FLAC/WAV), из которого получается копия.';
+comment on column track_file_transcodes.format is 'Формат копии.';
+comment on column track_file_transcodes.codec is 'Кодек копии.';
+comment on column track_file_transcodes.bitrate is 'Заказанный битрейт копии в кбит/с; 0 для PCM.';
+comment on column track_file_transcodes.track_file_id is 'Получившаяся копия. При её удалении задание удаляется, и копия будет получена заново.';
+comment on column track_file_transcodes.error is 'Причина, по которой получить копию не удалось.';
+comment on column track_file_transcodes.created_at is 'Время, когда задание впервые взято в работу.';
+comment on column track_file_transcodes.updated_at is 'Время последнего взятия в работу или его завершения.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table track_file_transcodes;
+
+delete from track_files where source_id is not null;
+
+drop index track_files_source_id_idx;
+drop index track_files_rendition_unique_index;
+drop index track_files_track_format_unique_index;
+
+create unique index track_files_track_format_unique_index on track_files (track_id, format);
+comment on index track_files_track_format_unique_index is 'Уникальный индекс для таблицы track_files по полям (track_id, format), гарантирующий, что один трек не имеет двух файлов одинакового формата.';
+
+alter table track_files
+    drop column source_id;
+
+-- +goose StatementEnd
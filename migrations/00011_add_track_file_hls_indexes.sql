@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+create table track_file_hls_indexes
+(
+    track_file_id uuid primary key references track_files (id) on delete cascade,
+    segments      bytea       default null,
+    error         text        default null,
+    created_at    timestamptz default now() not null,
+    updated_at    timestamptz default now() not null
+);
+
+comment on table track_file_hls_indexes is 'Разбиение сжатых файлов треков (MP3, AAC) на сегменты HLS. Строка без segments и error — разбиение ещё идёт.';
+
+comment on column track_file_hls_indexes.track_file_id is 'Файл трека, из которого нарезаются сегменты.';
+comment on column track_file_hls_indexes.segments is 'Параметры потока и диапазоны байтов сегментов в двоичном формате BBHI (см. hls.Index).';
+comment on column track_file_hls_indexes.error is 'Причина, по которой разбить файл не удалось.';
+comment on column track_file_hls_indexes.created_at is 'Время, когда файл впервые взят в разбиение.';
+comment on column track_file_hls_indexes.updated_at is 'Время последнего взятия в разбиение или его завершения.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table track_file_hls_indexes;
+
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+
+alter table track_files
+    add column start_sample bigint default null,
+    add column end_sample   bigint default null,
+    add constraint track_files_sample_range_check
+        check ((start_sample is null and end_sample is null)
+            or (start_sample >= 0 and end_sample > start_sample));
+
+comment on column track_files.start_sample is 'Первый сэмпл трека в объекте, если объект — образ диска, размеченный cue. NULL, если файл целиком принадлежит треку.';
+comment on column track_files.end_sample is 'Сэмпл, следующий за последним сэмплом трека в образе диска. NULL, если файл целиком принадлежит треку.';
+comment on constraint track_files_sample_range_check on track_files is 'Границы трека в образе задаются обе сразу и не пусты.';
+
+create index track_files_s3_key_idx on track_files (s3_key);
+comment on index track_files_s3_key_idx is 'Индекс для поиска файлов, разделяющих один объект хранилища (треки образа диска).';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop index track_files_s3_key_idx;
+
+alter table track_files
+    drop constraint track_files_sample_range_check,
+    drop column end_sample,
+    drop column start_sample;
+
+-- +goose StatementEnd
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+
+create table track_file_fingerprints
+(
+    track_file_id uuid primary key references track_files (id) on delete cascade,
+    fingerprint   bytea       default null,
+    error         text        default null,
+    created_at    timestamptz default now() not null,
+    updated_at    timestamptz default now() not null
+);
+
+comment on table track_file_fingerprints is 'Акустические отпечатки исходных файлов треков для поиска дубликатов. Строка без fingerprint и error — расчёт ещё идёт.';
+
+comment on column track_file_fingerprints.track_file_id is 'Файл трека, по которому посчитан отпечаток.';
+comment on column track_file_fingerprints.fingerprint is 'Отпечаток начала записи в двоичном формате BBFP (см. audio.Fingerprint).';
+comment on column track_file_fingerprints.error is 'Причина, по которой посчитать отпечаток не удалось.';
+comment on column track_file_fingerprints.created_at is 'Время, когда файл впервые взят в расчёт.';
+comment on column track_file_fingerprints.updated_at is 'Время последнего взятия в расчёт или его завершения.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table track_file_fingerprints;
+
+-- +goose StatementEnd
@@ -0,0 +1,115 @@
+-- +goose Up
+-- +goose StatementBegin
+
+create table blobs
+(
+    s3_key     text primary key,
+    checksum   text        default null unique check (checksum ~ '^[0-9a-f]{64}$'),
+    size       bigint                    not null check (size >= 0),
+    mime       text                      not null,
+    ref_count  integer     default 0     not null check (ref_count >= 0),
+    stored_at  timestamptz default null,
+    created_at timestamptz default now() not null,
+    updated_at timestamptz default now() not null
+);
+
+comment on table blobs is 'Объекты хранилища с содержимым файлов и число ссылок на них из track_files и uploads. Одинаковое содержимое хранится одним объектом.';
+
+comment on column blobs.s3_key is 'Ключ объекта в object storage.';
+comment on column blobs.checksum is 'SHA-256 содержимого (64 строчные hex-цифры). NULL у объектов, сохранённых до появления дедупликации: они в ней не участвуют.';
+comment on column blobs.size is 'Размер содержимого в байтах.';
+comment on column blobs.mime is 'MIME-тип, с которым объект записан в хранилище.';
+comment on column blobs.ref_count is 'Число строк track_files и uploads с этим s3_key; поддерживается триггерами.';
+comment on column blobs.stored_at is 'Время, когда объект полностью записан в хранилище. NULL — запись ещё идёт или не удалась.';
+comment on column blobs.created_at is 'Время регистрации содержимого.';
+comment on column blobs.updated_at is 'Время последнего изменения счётчика ссылок или повторного обращения к содержимому; объект без ссылок удаляется не раньше, чем через период ожидания после него.';
+
+create index blobs_unreferenced_idx on blobs (updated_at) where ref_count = 0;
+comment on index blobs_unreferenced_idx is 'Индекс для сборки объектов, на которые больше никто не ссылается.';
+
+-- Контрольные суммы приводятся к одному виду. Старые суммы другого алгоритма остаются как есть (ограничение
+-- NOT VALID их не проверяет), новые обязаны быть SHA-256.
+update track_files set checksum = lower(btrim(checksum)) where checksum <> lower(btrim(checksum));
+
+alter table track_files
+    add constraint track_files_checksum_sha256_check check (checksum ~ '^[0-9a-f]{64}$') not valid;
+
+comment on column track_files.checksum is 'SHA-256 содержимого файла (64 строчные hex-цифры), считается сервером при сохранении.';
+
+alter table uploads
+    add column checksum text default null check (checksum ~ '^[0-9a-f]{64}$');
+
+comment on column uploads.checksum is 'SHA-256 загруженного файла; NULL, пока возобновляемая загрузка не получена целиком.';
+
+-- У возобновляемой загрузки объекта нет, пока не получены все части: ключ определяется содержимым.
+alter table uploads
+    alter column s3_key drop not null;
+
+update uploads set s3_key = null where status = 'uploading'::upload_status;
+
+insert into blobs (s3_key, size, mime, ref_count, stored_at)
+select s3_key, max(size), min(mime), count(*), now()
+from (select s3_key, size, mime
+      from track_files
+      where s3_key is not null
+      union all
+      select s3_key, size, mime
+      from uploads
+      where s3_key is not null) as refs
+group by s3_key;
+
+create function blobs_count_references() returns trigger
+    language plpgsql as
+$$
+begin
+    if tg_op in ('UPDATE', 'DELETE') and old.s3_key is not null then
+        update blobs set ref_count = ref_count - 1, updated_at = now() where s3_key = old.s3_key;
+    end if;
+
+    if tg_op in ('INSERT', 'UPDATE') and new.s3_key is not null then
+        update blobs set ref_count = ref_count + 1, updated_at = now() where s3_key = new.s3_key;
+    end if;
+
+    return null;
+end;
+$$;
+
+comment on function blobs_count_references() is 'Пересчитывает blobs.ref_count при появлении, удалении и смене s3_key у строк track_files и uploads.';
+
+create trigger track_files_count_blob_references
+    after insert or delete or update of s3_key
+    on track_files
+    for each row
+execute function blobs_count_references();
+
+create trigger uploads_count_blob_references
+    after insert or delete or update of s3_key
+    on uploads
+    for each row
+execute function blobs_count_references();
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop trigger uploads_count_blob_references on uploads;
+drop trigger track_files_count_blob_references on track_files;
+drop function blobs_count_references();
+
+delete from uploads where s3_key is null;
+
+alter table uploads
+    alter column s3_key set not null;
+
+alter table uploads
+    drop column checksum;
+
+alter table track_files
+    drop constraint track_files_checksum_sha256_check;
+
+comment on column track_files.checksum is 'Контрольная сумма файла (md5/sha256 и т.п.).';
+
+drop table blobs;
+
+-- +goose StatementEnd
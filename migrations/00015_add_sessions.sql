@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+
+create table sessions
+(
+    id                 uuid primary key     default gen_random_uuid(),
+    user_id            uuid        not null references users (id) on delete cascade,
+    refresh_token_hash text        not null unique,
+    user_agent         text                 default null,
+    created_at         timestamptz not null default now(),
+    refreshed_at       timestamptz          default null,
+    expires_at         timestamptz not null,
+    revoked_at         timestamptz          default null
+);
+
+comment on table sessions is 'Сессии пользователей: вход с одного устройства, продлеваемый refresh-токеном.';
+
+comment on column sessions.id is 'Идентификатор сессии; записывается в access-токены, выданные в ней.';
+comment on column sessions.user_id is 'Пользователь, вошедший в сессии.';
+comment on column sessions.refresh_token_hash is 'SHA-256 действующего refresh-токена (hex). Сам токен не хранится.';
+comment on column sessions.user_agent is 'User-Agent клиента при входе, чтобы пользователь мог узнать устройство в списке сессий.';
+comment on column sessions.created_at is 'Время входа.';
+comment on column sessions.refreshed_at is 'Время последней смены refresh-токена.';
+comment on column sessions.expires_at is 'Время, после которого refresh-токен сессии не принимается; сдвигается при каждой смене токена.';
+comment on column sessions.revoked_at is 'Время выхода или отзыва сессии; отозванная сессия не продлевается, а её access-токены не принимаются.';
+
+create index sessions_user_id_active_idx on sessions (user_id) where revoked_at is null;
+comment on index sessions_user_id_active_idx is 'Индекс для списка действующих сессий пользователя.';
+
+create table session_rotated_tokens
+(
+    token_hash text primary key,
+    session_id uuid        not null references sessions (id) on delete cascade,
+    rotated_at timestamptz not null default now()
+);
+
+comment on table session_rotated_tokens is 'Уже заменённые refresh-токены сессий. Повторное предъявление такого токена означает, что он утёк, и сессия отзывается.';
+
+comment on column session_rotated_tokens.token_hash is 'SHA-256 заменённого refresh-токена (hex).';
+comment on column session_rotated_tokens.session_id is 'Сессия, которой принадлежал токен.';
+comment on column session_rotated_tokens.rotated_at is 'Время замены токена.';
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop table session_rotated_tokens;
+drop table sessions;
+
+-- +goose StatementEnd
@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+
+alter table track_file_fingerprints
+    add column lookup_keys integer[] default null;
+
+comment on column track_file_fingerprints.lookup_keys is 'Ключи поиска похожих отпечатков: различные старшие 28 бит субфингерпринтов (см. audio.Fingerprint.LookupKeys).';
+
+-- Ключи уже посчитанных отпечатков: субфингерпринты идут little-endian после 9 байт заголовка BBFP.
+update track_file_fingerprints as f
+set lookup_keys = (select array_agg(distinct keys.key order by keys.key)
+                   from (select ((get_byte(f.fingerprint, 9 + 4 * i)::bigint
+                                  | (get_byte(f.fingerprint, 10 + 4 * i)::bigint << 8)
+                                  | (get_byte(f.fingerprint, 11 + 4 * i)::bigint << 16)
+                                  | (get_byte(f.fingerprint, 12 + 4 * i)::bigint << 24)) >> 4)::integer as key
+                         from generate_series(0, (length(f.fingerprint) - 9) / 4 - 1) as i) as keys
+                   where keys.key <> 0)
+where f.fingerprint is not null;
+
+create index track_file_fingerprints_lookup_keys_idx on track_file_fingerprints using gin (lookup_keys);
+
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+
+drop index track_file_fingerprints_lookup_keys_idx;
+
+alter table track_file_fingerprints
+    drop column lookup_keys;
+
+-- +goose StatementEnd